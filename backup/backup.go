@@ -3,10 +3,12 @@ package backup
 import (
 	"archive/tar"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path/filepath"
@@ -20,9 +22,11 @@ type Format string
 var _ models.Backup = &BackupMetadata{}
 
 const (
-	FormatTarGz Format = "tar.gz"
-	FormatTar   Format = "tar"
-	FormatZip   Format = "zip"
+	FormatTarGz  Format = "tar.gz"
+	FormatTarZst Format = "tar.zst"
+	FormatTarXz  Format = "tar.xz"
+	FormatTar    Format = "tar"
+	FormatZip    Format = "zip"
 )
 
 type Checksum struct {
@@ -37,6 +41,10 @@ type BackupMetadata struct {
 	Stores     []string  `json:"stores,omitempty"`
 	Checksum   Checksum  `json:"checksum,omitempty"`
 	Size       int64     `json:"size,omitempty"`
+	// Base is the checksum of the backup that an incremental backup builds on, empty for a full backup.
+	Base string `json:"base,omitempty"`
+	// KeyID is the ID of the key the archive is encrypted with, empty if it isn't encrypted. See [WithKeys].
+	KeyID string `json:"key_id,omitempty"`
 }
 
 func (bm BackupMetadata) MarshalJSON() ([]byte, error) {
@@ -50,6 +58,12 @@ func (bm BackupMetadata) MarshalJSON() ([]byte, error) {
 	if bm.Size > 0 {
 		mdat["size"] = bm.Size
 	}
+	if bm.Base != "" {
+		mdat["base"] = bm.Base
+	}
+	if bm.KeyID != "" {
+		mdat["key_id"] = bm.KeyID
+	}
 	return json.Marshal(mdat)
 }
 
@@ -93,154 +107,347 @@ func (tgz *TarGzBackup) Metadata() BackupMetadata {
 	}
 }
 
+// ctxWriter fails writes once its context is done, which stops a copy part way through.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw ctxWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
+
 func NewTarGzBackup(inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
-	stat, err := os.Stat(inPath)
+	return NewTarGzBackupContext(context.Background(), inPath, outPath, stores, extraData...)
+}
+
+// NewTarGzBackupContext is like [NewTarGzBackup], but stops writing the archive once ctx is done.
+// A partially written archive is removed.
+func NewTarGzBackupContext(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return newCompressedTarBackup(ctx, gzipCodec{}, options{}, inPath, outPath, stores, extraData...)
+}
+
+// newCompressedTarBackup writes a tar archive of inPath compressed with c to outPath, at the level set in o
+// with [WithLevel]. Extra data goes in the gzip header for gzip, which has always kept it there,
+// and in a PAX global header at the start of the tar archive for other codecs.
+func newCompressedTarBackup(ctx context.Context, c Codec, o options, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
 	nilBackup := BackupMetadata{}
+	format := c.Format()
+	outPath, err := prepare(ctx, inPath, outPath, format)
+	if err != nil {
+		return nilBackup, err
+	}
+	src, err := collect(ctx, inPath, o)
+	if err != nil {
+		return nilBackup, err
+	}
+	out, err := createOutput(outPath, o.keys)
+	if err != nil {
+		return nilBackup, err
+	}
+	defer out.abort()
+
+	tarComment := comment(extraData)
+	if format == FormatTarGz {
+		tarComment = ""
+	}
+	tmpF, err := tarToTemp(ctx, src, outPath, stores, tarComment)
+	if err != nil {
+		return nilBackup, err
+	}
+	defer func() {
+		_ = tmpF.Close()
+		_ = os.Remove(tmpF.Name())
+	}()
+
+	cw, err := c.NewWriter(ctxWriter{ctx: ctx, w: out}, o.level)
+	if err != nil {
+		return nilBackup, fmt.Errorf("error creating %s writer: %w", format, err)
+	}
+	if gz, ok := cw.(*gzip.Writer); ok {
+		gz.Comment = comment(extraData)
+	}
+	if _, err = io.CopyBuffer(cw, tmpF, make([]byte, copyBufferSize)); err != nil {
+		_ = cw.Close()
+		return nilBackup, fmt.Errorf("error writing to final %s file: %w", format, err)
+	}
+	if err = cw.Close(); err != nil {
+		return nilBackup, fmt.Errorf("error closing final %s file: %w", format, err)
+	}
+	if err = tmpF.Close(); err != nil {
+		return nilBackup, fmt.Errorf("error closing temporary tar file: %w", err)
+	}
+	return out.commit(format, stores, src)
+}
+
+// comment joins the extra data stored in an archive's comment.
+func comment(extraData [][]byte) string {
+	c := "github.com/tcp-direct/database backup archive"
+	for _, data := range extraData {
+		c += "\n" + string(data)
+	}
+	return c
+}
+
+// prepare checks that inPath is a directory to back up and returns the path of the archive to write,
+// named after inPath with the format's extension if outPath is a directory.
+func prepare(ctx context.Context, inPath string, outPath string, format Format) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	stat, err := os.Stat(inPath)
 	if err != nil {
-		return nilBackup, fmt.Errorf("error collecting files to backup: %w", err)
+		return "", fmt.Errorf("error collecting files to backup: %w", err)
 	}
 	if !stat.IsDir() {
-		return nilBackup, fmt.Errorf("error collecting files to backup, not a directory: %s", stat.Name())
+		return "", fmt.Errorf("error collecting files to backup, not a directory: %s", stat.Name())
 	}
 	stat, err = os.Stat(outPath)
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return nilBackup, fmt.Errorf("error checking backup path: %w", err)
+		return "", fmt.Errorf("error checking backup path: %w", err)
 	}
 	if stat != nil && stat.IsDir() {
-		outPath = filepath.Join(outPath, filepath.Base(inPath)+".tar.gz")
+		outPath = filepath.Join(outPath, filepath.Base(inPath)+format.Ext())
 	}
+	return outPath, nil
+}
 
-	tmpTar := outPath + ".tar.tmp"
-	tmpF, ferr := os.Create(tmpTar)
-	if ferr != nil {
-		return nilBackup, fmt.Errorf("error creating temporary tar file: %w", ferr)
+// tarToTemp writes the manifest and files of src to a temporary tar file next to outPath, checking that every store
+// made it in to the archive. The returned file is positioned at its start, the caller must close and remove it.
+// If comment isn't empty, it is recorded in a PAX global header at the start of the archive.
+func tarToTemp(ctx context.Context, src source, outPath string, stores []string, comment string) (*os.File, error) {
+	tmpF, err := os.Create(outPath + ".tar.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary tar file: %w", err)
 	}
-
-	defer func() {
+	fail := func(err error) (*os.File, error) {
+		_ = tmpF.Close()
 		_ = os.Remove(tmpF.Name())
-	}()
+		return nil, err
+	}
 
-	tf := tar.NewWriter(tmpF)
-	if err = tf.AddFS(os.DirFS(inPath)); err != nil {
-		return nilBackup, fmt.Errorf("error adding files to backup: %w", err)
+	tf := tar.NewWriter(ctxWriter{ctx: ctx, w: tmpF})
+	if comment != "" {
+		hdr := &tar.Header{Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": comment}}
+		if err = tf.WriteHeader(hdr); err != nil {
+			return fail(fmt.Errorf("error writing backup tar header: %w", err))
+		}
+	}
+	hdr := &tar.Header{Name: ManifestName, Mode: 0600, Size: int64(len(src.encoded)), ModTime: time.Now()}
+	if err = tf.WriteHeader(hdr); err != nil {
+		return fail(fmt.Errorf("error writing backup manifest: %w", err))
+	}
+	if _, err = tf.Write(src.encoded); err != nil {
+		return fail(fmt.Errorf("error writing backup manifest: %w", err))
+	}
+	if err = tf.AddFS(src.fsys); err != nil {
+		return fail(fmt.Errorf("error adding files to backup: %w", err))
 	}
 	if err = tf.Close(); err != nil {
-		return nilBackup, fmt.Errorf("error closing backup tar file: %w", err)
+		return fail(fmt.Errorf("error closing backup tar file: %w", err))
 	}
 	if err = tmpF.Sync(); err != nil {
-		return nilBackup, fmt.Errorf("error syncing backup tar file: %w", err)
+		return fail(fmt.Errorf("error syncing backup tar file: %w", err))
 	}
 	if _, err = tmpF.Seek(0, 0); err != nil {
-		return nilBackup, fmt.Errorf("error seeking to beginning of tar file: %w", err)
+		return fail(fmt.Errorf("error seeking to beginning of tar file: %w", err))
 	}
 
+	var names []string
 	tfr := tar.NewReader(tmpF)
 	var entry *tar.Header
+	for entry, err = tfr.Next(); err == nil; entry, err = tfr.Next() {
+		names = append(names, entry.Name)
+	}
+	if !errors.Is(err, io.EOF) {
+		return fail(fmt.Errorf("error verifying backup tar file: %w", err))
+	}
+	if err = src.checkStores(names, stores); err != nil {
+		return fail(err)
+	}
+	if _, err = tmpF.Seek(0, 0); err != nil {
+		return fail(fmt.Errorf("error seeking to beginning of tar file: %w", err))
+	}
+	return tmpF, nil
+}
 
+// checkStores returns an error if any of the stores has no entries directly within it among the archive's names.
+// Incremental backups only hold the stores that changed, so they aren't checked.
+func (src source) checkStores(names []string, stores []string) error {
+	if src.manifest.Base != "" {
+		return nil
+	}
 	var seen = make(map[string]bool)
 	for _, storeName := range stores {
 		seen[storeName] = false
 	}
-
-	for entry, err = tfr.Next(); err == nil; entry, err = tfr.Next() {
-		if s, ok := seen[filepath.Dir(entry.Name)]; ok {
-			if s {
-				continue
-			}
-			seen[filepath.Dir(entry.Name)] = true
+	for _, name := range names {
+		if _, ok := seen[filepath.Dir(name)]; ok {
+			seen[filepath.Dir(name)] = true
 		}
 	}
-
-	if !errors.Is(err, io.EOF) {
-		return nilBackup, fmt.Errorf("error verifying backup tar file: %w", err)
-	}
-
 	for _, storeName := range stores {
 		if !seen[storeName] {
-			return nilBackup, fmt.Errorf("store %s not found in backup", storeName)
+			return fmt.Errorf("store %s not found in backup", storeName)
 		}
 	}
+	return nil
+}
 
-	buffer := make([]byte, 1024)
-
-	var finalFile *os.File
+// output is an archive being written to a temporary file next to its path, which it is only moved to once it is
+// complete. If the backup is encrypted, everything written to the file is encrypted first, so that the archive's
+// plaintext is never written there.
+type output struct {
+	io.Writer
+	path   string
+	f      *os.File
+	sum    hash.Hash
+	enc    *encrypter
+	keyID  string
+	closed bool
+	done   bool
+}
 
-	if finalFile, err = os.Create(outPath); err != nil {
-		return nilBackup, fmt.Errorf("error opening final tar.gz file before writing: %w", err)
+// createOutput starts writing the archive at path, encrypted with the current key of keys if it isn't nil.
+func createOutput(path string, keys KeyProvider) (*output, error) {
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("error creating backup file: %w", err)
 	}
-
-	gz := gzip.NewWriter(finalFile)
-	gz.Comment = "github.com/tcp-direct/database backup archive"
-	if len(extraData) > 0 {
-		for _, data := range extraData {
-			gz.Comment += "\n" + string(data)
-		}
+	out := &output{path: path, f: f, sum: sha256.New()}
+	out.Writer = io.MultiWriter(f, out.sum)
+	if keys == nil {
+		return out, nil
 	}
-	if _, err = tmpF.Seek(0, 0); err != nil {
-		return nilBackup, fmt.Errorf("error seeking to beginning of tar file: %w", err)
+	keyID, key, err := keys.EncryptionKey()
+	if err != nil {
+		out.abort()
+		return nil, fmt.Errorf("error getting backup encryption key: %w", err)
 	}
-	if _, err = io.CopyBuffer(gz, tmpF, buffer); err != nil {
-		return nilBackup, fmt.Errorf("error writing to final tar.gz file: %w", err)
+	if out.enc, err = newEncrypter(out.Writer, keyID, key); err != nil {
+		out.abort()
+		return nil, fmt.Errorf("error encrypting backup: %w", err)
 	}
-	if err = gz.Close(); err != nil {
-		return nilBackup, fmt.Errorf("error closing final tar.gz file: %w", err)
+	out.Writer, out.keyID = out.enc, keyID
+	return out, nil
+}
+
+// close finishes writing the archive to the temporary file, which is left in place.
+func (out *output) close() error {
+	if out.closed {
+		return nil
 	}
-	if err = tmpF.Close(); err != nil {
-		return nilBackup, fmt.Errorf("error closing temporary tar file: %w", err)
+	out.closed = true
+	if out.enc != nil {
+		if err := out.enc.Close(); err != nil {
+			return fmt.Errorf("error encrypting backup: %w", err)
+		}
 	}
-	_ = finalFile.Sync()
-	if _, err = finalFile.Seek(0, 0); err != nil {
-		return nilBackup, fmt.Errorf("error seeking to beginning of final tar.gz file: %w", err)
+	if err := out.f.Sync(); err != nil {
+		return fmt.Errorf("error syncing backup file: %w", err)
 	}
+	if err := out.f.Close(); err != nil {
+		return fmt.Errorf("error closing backup file: %w", err)
+	}
+	return nil
+}
 
-	summah := sha256.New()
-	if _, err = io.CopyBuffer(summah, finalFile, buffer); err != nil {
-		return nilBackup, fmt.Errorf("error calculating checksum: %w", err)
+// commit moves the complete archive to its path and returns its metadata.
+func (out *output) commit(format Format, stores []string, src source) (BackupMetadata, error) {
+	if err := out.close(); err != nil {
+		return BackupMetadata{}, err
 	}
-	if err = finalFile.Close(); err != nil {
-		return nilBackup, fmt.Errorf("error closing final tar.gz file: %w", err)
+	stat, err := os.Stat(out.f.Name())
+	if err != nil {
+		return BackupMetadata{}, fmt.Errorf("error checking backup file: %w", err)
 	}
-	checksum := Checksum{
-		Type:  "sha256",
-		Value: fmt.Sprintf("%x", summah.Sum(nil)),
+	if err = os.Rename(out.f.Name(), out.path); err != nil {
+		return BackupMetadata{}, fmt.Errorf("error moving backup file in to place: %w", err)
 	}
+	out.done = true
+	return BackupMetadata{
+		FileFormat: string(format),
+		FilePath:   out.path,
+		Stores:     stores,
+		Checksum:   Checksum{Type: "sha256", Value: fmt.Sprintf("%x", out.sum.Sum(nil))},
+		Size:       stat.Size(),
+		Date:       time.Now(),
+		Base:       src.manifest.Base,
+		KeyID:      out.keyID,
+	}, nil
+}
 
-	tgz := &TarGzBackup{
-		path:      outPath,
-		stores:    stores,
-		timestamp: time.Now(),
-		checksum:  checksum,
+// abort removes the archive unless it was committed.
+func (out *output) abort() {
+	if out.done {
+		return
 	}
-
-	return tgz.Metadata(), nil
+	_ = out.f.Close()
+	_ = os.Remove(out.f.Name())
 }
 
 func RestoreTarGzBackup(inPath string, outPath string) error {
-	stat, err := os.Stat(inPath)
+	return restoreCompressedTar(gzipCodec{}, nil, inPath, outPath)
+}
+
+// restoreCompressedTar extracts a tar archive compressed with c in to outPath, decrypting it with keys
+// if it is encrypted.
+func restoreCompressedTar(c Codec, keys KeyProvider, inPath string, outPath string) error {
+	f, err := openArchive(inPath, keys)
 	if err != nil {
-		return fmt.Errorf("error checking backup file: %w", err)
+		return err
 	}
-	if stat.IsDir() {
-		return fmt.Errorf("error checking backup file, not a file: %s", stat.Name())
-	}
-	f, ferr := os.Open(inPath)
-
 	defer func() {
 		_ = f.Close()
 	}()
+	cr, err := c.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("error creating %s reader: %w", c.Format(), err)
+	}
+	defer func() {
+		_ = cr.Close()
+	}()
+	return extractTar(tar.NewReader(cr), outPath)
+}
 
-	if ferr != nil {
-		return fmt.Errorf("error opening backup file: %w", ferr)
+// openArchive opens the backup file at inPath, which must not be a directory, decrypting it with keys
+// if it is encrypted.
+func openArchive(inPath string, keys KeyProvider) (*archive, error) {
+	stat, err := os.Stat(inPath)
+	if err != nil {
+		return nil, fmt.Errorf("error checking backup file: %w", err)
 	}
-	gz, gerr := gzip.NewReader(f)
-	if gerr != nil {
-		return fmt.Errorf("error creating gzip reader: %w", gerr)
+	if stat.IsDir() {
+		return nil, fmt.Errorf("error checking backup file, not a file: %s", stat.Name())
 	}
+	f, err := os.Open(inPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening backup file: %w", err)
+	}
+	_, hdr, err := readEncHeader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if hdr == nil {
+		return &archive{Reader: f, f: f, size: -1}, nil
+	}
+	d, err := newDecrypter(f, hdr, keys)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &archive{Reader: d, f: f, size: -1}, nil
+}
 
-	buf := make([]byte, 1024)
-
-	tfr := tar.NewReader(gz)
+// extractTar writes every entry of a tar archive below outPath.
+func extractTar(tfr *tar.Reader, outPath string) error {
+	buf := make([]byte, copyBufferSize)
 	var entry *tar.Header
+	var err error
 
 	for {
 		entry, err = tfr.Next()
@@ -253,6 +460,9 @@ func RestoreTarGzBackup(inPath string, outPath string) error {
 		if entry == nil {
 			break
 		}
+		if entry.Typeflag == tar.TypeXGlobalHeader || entry.Name == ManifestName {
+			continue
+		}
 		if !filepath.IsLocal(entry.Name) {
 			return fmt.Errorf("tar file contains invalid path: %s", entry.Name)
 		}
@@ -262,36 +472,8 @@ func RestoreTarGzBackup(inPath string, outPath string) error {
 				return fmt.Errorf("error creating directory: %w", err)
 			}
 		case tar.TypeReg:
-			var file *os.File
-			dirStat, dirErr := os.Stat(filepath.Dir(filepath.Join(outPath, filepath.Dir(entry.Name))))
-			if errors.Is(dirErr, os.ErrNotExist) {
-				if err = os.MkdirAll(filepath.Join(outPath, filepath.Dir(entry.Name)), 0755); err != nil {
-					return fmt.Errorf("error creating directory: %w", err)
-				}
-			}
-			if !errors.Is(dirErr, os.ErrNotExist) && dirErr != nil {
-				return fmt.Errorf("error checking output directory: %w", dirErr)
-			}
-			if dirStat != nil && !dirStat.IsDir() {
-				return fmt.Errorf("directory in backup exists in outpath as a file: %s", filepath.Dir(entry.Name))
-			}
-			if file, err = os.Create(filepath.Join(outPath, entry.Name)); err != nil {
-				if !errors.Is(err, os.ErrNotExist) {
-					return fmt.Errorf("error creating file %s: %w", entry.Name, err)
-				}
-				if err = os.MkdirAll(filepath.Dir(filepath.Join(outPath, entry.Name)), 0755); err != nil {
-					return fmt.Errorf("error creating directory: %w", err)
-				}
-				if file, err = os.Create(filepath.Join(outPath, entry.Name)); err != nil {
-					return fmt.Errorf("error creating file %s: %w", entry.Name, err)
-				}
-			}
-			if _, err = io.CopyBuffer(file, tfr, buf); err != nil {
-				_ = file.Close()
-				return fmt.Errorf("error writing file: %w", err)
-			}
-			if err = file.Close(); err != nil {
-				return fmt.Errorf("error closing file (%s): %w", file.Name(), err)
+			if err = extractFile(tfr, outPath, entry.Name, buf); err != nil {
+				return err
 			}
 		default:
 			return fmt.Errorf("unsupported tar file type: %c", entry.Typeflag)
@@ -300,3 +482,40 @@ func RestoreTarGzBackup(inPath string, outPath string) error {
 
 	return nil
 }
+
+// extractFile writes the contents of r to the file name below outPath, creating its directory if needed.
+func extractFile(r io.Reader, outPath string, name string, buf []byte) error {
+	var file *os.File
+	var err error
+	dirStat, dirErr := os.Stat(filepath.Dir(filepath.Join(outPath, filepath.Dir(name))))
+	if errors.Is(dirErr, os.ErrNotExist) {
+		if err = os.MkdirAll(filepath.Join(outPath, filepath.Dir(name)), 0755); err != nil {
+			return fmt.Errorf("error creating directory: %w", err)
+		}
+	}
+	if !errors.Is(dirErr, os.ErrNotExist) && dirErr != nil {
+		return fmt.Errorf("error checking output directory: %w", dirErr)
+	}
+	if dirStat != nil && !dirStat.IsDir() {
+		return fmt.Errorf("directory in backup exists in outpath as a file: %s", filepath.Dir(name))
+	}
+	if file, err = os.Create(filepath.Join(outPath, name)); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error creating file %s: %w", name, err)
+		}
+		if err = os.MkdirAll(filepath.Dir(filepath.Join(outPath, name)), 0755); err != nil {
+			return fmt.Errorf("error creating directory: %w", err)
+		}
+		if file, err = os.Create(filepath.Join(outPath, name)); err != nil {
+			return fmt.Errorf("error creating file %s: %w", name, err)
+		}
+	}
+	if _, err = io.CopyBuffer(file, r, buf); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("error closing file (%s): %w", file.Name(), err)
+	}
+	return nil
+}
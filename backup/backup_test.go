@@ -1,10 +1,15 @@
 package backup
 
 import (
+	"bytes"
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
@@ -231,3 +236,622 @@ func TestFailures(t *testing.T) {
 		t.Error("expected error, got nil")
 	}
 }
+
+// countdownCtx reports itself as cancelled once Err has been called n times,
+// which cancels a backup part way through writing the archive.
+type countdownCtx struct {
+	context.Context
+	n int
+}
+
+func (c *countdownCtx) Err() error {
+	if c.n <= 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+func TestNewTarGzBackupContext(t *testing.T) {
+	inDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(inDir, "yeet"), 0755); err != nil {
+		t.Fatalf("error creating sample directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "yeet", "sample.txt"), []byte("yeets"), 0644); err != nil {
+		t.Fatalf("error creating sample file: %v", err)
+	}
+
+	for _, n := range []int{0, 1, 5} {
+		t.Run(fmt.Sprintf("cancel_after_%d", n), func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out.tar.gz")
+			ctx := &countdownCtx{Context: context.Background(), n: n}
+			if _, err := NewTarGzBackupContext(ctx, inDir, outPath, []string{"yeet"}); !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected context.Canceled, got %v", err)
+			}
+			entries, err := os.ReadDir(filepath.Dir(outPath))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("expected partial archive to be removed, found %v", entries)
+			}
+		})
+	}
+}
+
+func TestBackuper(t *testing.T) {
+	inDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(inDir, "yeet", "nested"), 0755); err != nil {
+		t.Fatalf("error creating sample directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "yeet", "sample.txt"), []byte("yeets"), 0644); err != nil {
+		t.Fatalf("error creating sample file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "yeet", "nested", "deep.txt"), []byte("deep yeets"), 0644); err != nil {
+		t.Fatalf("error creating sample file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "meta.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("error creating sample file: %v", err)
+	}
+
+	for _, format := range []Format{FormatTarGz, FormatTarZst, FormatTarXz, FormatTar, FormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			outPath := filepath.Join(t.TempDir(), "out"+format.Ext())
+			if got := FormatFromPath(outPath); got != format {
+				t.Errorf("expected %s from the path, got %s", format, got)
+			}
+			b, err := NewBackuper(format)
+			if err != nil || b.Format() != format {
+				t.Fatalf("expected a %s Backuper, got %v (%v)", format, b, err)
+			}
+			bu, err := NewBackup(inDir, outPath, []string{"yeet"}, []byte("extra"))
+			if err != nil {
+				t.Fatalf("error creating backup: %v", err)
+			}
+			if bu.Format() != string(format) || bu.Path() != outPath || bu.Size == 0 {
+				t.Errorf("expected %s backup metadata for %s, got %+v", format, outPath, bu)
+			}
+			if got, detectErr := DetectFormat(outPath); detectErr != nil || got != format {
+				t.Errorf("expected to detect %s, got %s (%v)", format, got, detectErr)
+			}
+			if err = VerifyBackup(bu); err != nil {
+				t.Fatalf("error verifying backup: %v", err)
+			}
+
+			outDir := t.TempDir()
+			if err = RestoreBackup(outPath, outDir); err != nil {
+				t.Fatalf("error restoring backup: %v", err)
+			}
+			for name, want := range map[string]string{
+				filepath.Join("yeet", "sample.txt"):         "yeets",
+				filepath.Join("yeet", "nested", "deep.txt"): "deep yeets",
+				"meta.json": "{}",
+			} {
+				if got, readErr := os.ReadFile(filepath.Join(outDir, name)); readErr != nil || string(got) != want {
+					t.Errorf("%s: expected %q, got %q (%v)", name, want, got, readErr)
+				}
+			}
+
+			if _, err = b.Backup(context.Background(), inDir, t.TempDir(), []string{"missing"}); err == nil {
+				t.Error("expected an error for a store missing from the archive")
+			}
+
+			data, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = data[:len(data)-16]
+			if err = os.WriteFile(outPath, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err = VerifyBackup(bu); err == nil {
+				t.Error("expected a corrupted archive to fail verification")
+			}
+			bu.Checksum.Value = fmt.Sprintf("%x", sha256.Sum256(data))
+			if format != FormatTar {
+				// the checksum now matches, but the archive's own checks still catch the corruption.
+				if err = VerifyBackup(bu); err == nil {
+					t.Error("expected a corrupted archive to fail to read")
+				}
+			}
+		})
+	}
+
+	if _, err := NewBackuper("rar"); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if err := VerifyBackup(BackupMetadata{FileFormat: "rar"}); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+	if got := FormatFromPath("backup.TGZ"); got != FormatTarGz {
+		t.Errorf("expected .tgz to be tar.gz, got %s", got)
+	}
+	notArchive := filepath.Join(t.TempDir(), "plain.txt")
+	if err := os.WriteFile(notArchive, []byte("not an archive"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DetectFormat(notArchive); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func TestNewBackupContext_Cancel(t *testing.T) {
+	inDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(inDir, "yeet"), 0755); err != nil {
+		t.Fatalf("error creating sample directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(inDir, "yeet", "sample.txt"), []byte("yeets"), 0644); err != nil {
+		t.Fatalf("error creating sample file: %v", err)
+	}
+	for _, newBackup := range []func(context.Context, string, string, []string, ...[]byte) (BackupMetadata, error){
+		NewTarBackupContext, NewZipBackupContext,
+	} {
+		outPath := filepath.Join(t.TempDir(), "out")
+		ctx := &countdownCtx{Context: context.Background(), n: 1}
+		if _, err := newBackup(ctx, inDir, outPath, []string{"yeet"}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		entries, err := os.ReadDir(filepath.Dir(outPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("expected partial archive to be removed, found %v", entries)
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	inDir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(inDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("yeet/000.data", "first data file")
+	write("yeet/001.data", "second data file")
+	write("yote/000.data", "another store")
+	write("meta.json", "{}")
+
+	full, err := NewBackup(inDir, filepath.Join(t.TempDir(), "full.tar.gz"), []string{"yeet", "yote"})
+	if err != nil {
+		t.Fatalf("error creating full backup: %v", err)
+	}
+	man, err := ReadManifest(full.Path())
+	if err != nil || man.Base != "" || len(man.Files) != 4 {
+		t.Fatalf("expected a full manifest of 4 files, got %+v (%v)", man, err)
+	}
+
+	write("yeet/001.data", "second data file, appended to")
+	write("yeet/002.data", "third data file")
+	if err = os.Remove(filepath.Join(inDir, "yote", "000.data")); err != nil {
+		t.Fatal(err)
+	}
+	// rewritten with the same contents, so it isn't archived again.
+	write("yeet/000.data", "first data file")
+
+	b, err := NewBackuper(FormatZip, WithBase(full))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inc, err := b.Backup(context.Background(), inDir, filepath.Join(t.TempDir(), "inc.zip"), []string{"yeet", "yote"})
+	if err != nil {
+		t.Fatalf("error creating incremental backup: %v", err)
+	}
+	if inc.Base != full.Checksum.Value {
+		t.Errorf("expected the incremental backup to record its base, got %q", inc.Base)
+	}
+	if err = VerifyBackup(inc); err != nil {
+		t.Fatalf("error verifying incremental backup: %v", err)
+	}
+
+	alone := t.TempDir()
+	if err = RestoreZipBackup(inc.Path(), alone); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"yeet/000.data": false, "yeet/001.data": true, "yeet/002.data": true, "meta.json": false} {
+		if _, statErr := os.Stat(filepath.Join(alone, filepath.FromSlash(name))); (statErr == nil) != want {
+			t.Errorf("%s: expected archived %t, got %v", name, want, statErr)
+		}
+	}
+
+	if b, err = NewBackuper(FormatTar, WithBase(inc)); err != nil {
+		t.Fatal(err)
+	}
+	inc2, err := b.Backup(context.Background(), inDir, filepath.Join(t.TempDir(), "inc2.tar"), nil)
+	if err != nil {
+		t.Fatalf("error creating second incremental backup: %v", err)
+	}
+	chain, err := ResolveChain(inc2.Path(), WithKnown(inc, full))
+	if err != nil || len(chain) != 3 || chain[0] != full.Path() || chain[2] != inc2.Path() {
+		t.Fatalf("expected a chain of 3 backups, got %v (%v)", chain, err)
+	}
+
+	outDir := t.TempDir()
+	if err = os.MkdirAll(filepath.Join(outDir, "yote"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = RestoreBackup(inc2.Path(), outDir, WithKnown(full, inc)); err != nil {
+		t.Fatalf("error restoring backup chain: %v", err)
+	}
+	for name, want := range map[string]string{
+		"yeet/000.data": "first data file",
+		"yeet/001.data": "second data file, appended to",
+		"yeet/002.data": "third data file",
+		"meta.json":     "{}",
+	} {
+		if got, readErr := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name))); readErr != nil || string(got) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", name, want, got, readErr)
+		}
+	}
+	if _, err = os.Stat(filepath.Join(outDir, "yote", "000.data")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a file deleted since the full backup to be removed, got %v", err)
+	}
+
+	if err = RestoreChain([]string{full.Path(), inc2.Path()}, t.TempDir()); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain, got %v", err)
+	}
+	if err = RestoreChain([]string{inc.Path()}, t.TempDir()); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("expected ErrBrokenChain for a chain without a full backup, got %v", err)
+	}
+	moved := filepath.Join(t.TempDir(), "moved.tar.gz")
+	if err = os.Rename(full.Path(), moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ResolveChain(inc.Path()); !errors.Is(err, ErrBaseNotFound) {
+		t.Errorf("expected ErrBaseNotFound, got %v", err)
+	}
+	full.FilePath = moved
+	if chain, err = ResolveChain(inc.Path(), WithKnown(full)); err != nil || chain[0] != moved {
+		t.Errorf("expected the base to be found by checksum, got %v (%v)", chain, err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	key := make([]byte, KeySize)
+	copy(key, "yeet yeet yeet yeet yeet yeet!!!")
+	keys := WithKeys(StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": key}})
+
+	inDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(inDir, "yeet"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	secret := bytes.Repeat([]byte("very secret value "), 10000)
+	if err := os.WriteFile(filepath.Join(inDir, "yeet", "000.data"), secret, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := t.TempDir()
+	backups := make(map[Format]BackupMetadata)
+	for _, format := range []Format{FormatTarGz, FormatTar, FormatZip} {
+		t.Run(string(format), func(t *testing.T) {
+			b, err := NewBackuper(format, keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bu, err := b.Backup(context.Background(), inDir, filepath.Join(outDir, "backup"+format.Ext()), []string{"yeet"})
+			if err != nil {
+				t.Fatalf("error creating encrypted backup: %v", err)
+			}
+			backups[format] = bu
+			if bu.KeyID != "k1" {
+				t.Errorf("expected key ID k1 in metadata, got %q", bu.KeyID)
+			}
+			if id, idErr := KeyID(bu.Path()); id != "k1" || idErr != nil {
+				t.Errorf("expected key ID k1 in archive, got %q (%v)", id, idErr)
+			}
+			raw, err := os.ReadFile(bu.Path())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(raw, []byte("very secret value")) || bytes.Contains(raw, key) {
+				t.Fatal("archive contains plaintext")
+			}
+			if got, detectErr := DetectFormat(bu.Path(), keys); got != format || detectErr != nil {
+				t.Errorf("expected format %s, got %s (%v)", format, got, detectErr)
+			}
+			if err = VerifyBackup(bu, keys); err != nil {
+				t.Fatalf("error verifying encrypted backup: %v", err)
+			}
+			restoreDir := t.TempDir()
+			if err = RestoreBackup(bu.Path(), restoreDir, keys); err != nil {
+				t.Fatalf("error restoring encrypted backup: %v", err)
+			}
+			restored, err := os.ReadFile(filepath.Join(restoreDir, "yeet", "000.data"))
+			if err != nil || !bytes.Equal(restored, secret) {
+				t.Fatalf("restored file doesn't match (%v)", err)
+			}
+		})
+	}
+	if entries, _ := os.ReadDir(outDir); len(entries) != len(backups) {
+		t.Errorf("expected only the %d archives to be left, got %d files", len(backups), len(entries))
+	}
+
+	t.Run("plaintext", func(t *testing.T) {
+		a, err := openArchive(backups[FormatZip].Path(), newOptions([]Option{keys}).keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = a.Size(); err != nil {
+			t.Fatalf("error decrypting zip archive: %v", err)
+		}
+		if filepath.Dir(a.tmp.Name()) != outDir {
+			t.Errorf("expected the zip archive to be decrypted next to it, got %s", a.tmp.Name())
+		}
+		if stat, statErr := a.tmp.Stat(); statErr != nil || stat.Mode().Perm() != 0600 {
+			t.Errorf("expected the decrypted archive to only be readable by its owner, got %v (%v)", stat.Mode(), statErr)
+		}
+		if err = a.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if leftover, _ := filepath.Glob(filepath.Join(outDir, ".*.dec")); len(leftover) > 0 {
+			t.Errorf("expected the decrypted archive to be removed, got %q", leftover)
+		}
+	})
+
+	t.Run("rotated", func(t *testing.T) {
+		rotated := WithKeys(StaticKeys{Current: "k2", Keys: map[string][]byte{"k1": key, "k2": bytes.Repeat([]byte{2}, KeySize)}})
+		b, err := NewBackuper(FormatTarGz, WithBase(backups[FormatTarGz]), rotated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inc, err := b.Backup(context.Background(), inDir, filepath.Join(t.TempDir(), "inc.tar.gz"), nil)
+		if err != nil {
+			t.Fatalf("error creating incremental backup on an encrypted base: %v", err)
+		}
+		if inc.KeyID != "k2" {
+			t.Errorf("expected key ID k2, got %q", inc.KeyID)
+		}
+		if err = RestoreBackup(inc.Path(), t.TempDir(), rotated, WithKnown(backups[FormatTarGz])); err != nil {
+			t.Fatalf("error restoring backups encrypted with rotated keys: %v", err)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		// backups taken at the same time with different keys each use their own.
+		var wg sync.WaitGroup
+		for _, id := range []string{"a", "b", "c", "d"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b, err := NewBackuper(FormatTarGz, WithKeys(StaticKeys{Current: id, Keys: map[string][]byte{id: key}}))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				bu, err := b.Backup(context.Background(), inDir, filepath.Join(t.TempDir(), id+".tar.gz"), nil)
+				if err != nil || bu.KeyID != id {
+					t.Errorf("expected a backup encrypted with key %s, got %q (%v)", id, bu.KeyID, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("failures", func(t *testing.T) {
+		bu := backups[FormatZip]
+		if err := RestoreBackup(bu.Path(), t.TempDir()); !errors.Is(err, ErrNoKeyProvider) {
+			t.Errorf("expected ErrNoKeyProvider, got %v", err)
+		}
+		wrong := WithKeys(StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{1}, KeySize)}})
+		if err := VerifyBackup(bu, wrong); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("expected ErrDecrypt with the wrong key, got %v", err)
+		}
+		missing := WithKeys(StaticKeys{Current: "k3", Keys: map[string][]byte{}})
+		if err := RestoreBackup(bu.Path(), t.TempDir(), missing); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("expected ErrKeyNotFound, got %v", err)
+		}
+		failed := t.TempDir()
+		b, err := NewBackuper(FormatTar, missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = b.Backup(context.Background(), inDir, filepath.Join(failed, "nokey.tar"), nil); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("expected ErrKeyNotFound backing up without the current key, got %v", err)
+		}
+		short := WithKeys(StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": key[:16]}})
+		if b, err = NewBackuper(FormatZip, short); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = b.Backup(context.Background(), inDir, filepath.Join(failed, "short.zip"), nil); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+		if entries, _ := os.ReadDir(failed); len(entries) > 0 {
+			t.Errorf("expected failed backups to leave nothing behind, got %v", entries)
+		}
+		raw, err := os.ReadFile(bu.Path())
+		if err != nil {
+			t.Fatal(err)
+		}
+		truncated := filepath.Join(t.TempDir(), "truncated.zip")
+		if err = os.WriteFile(truncated, raw[:len(raw)-1], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err = RestoreBackup(truncated, t.TempDir(), keys); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("expected ErrDecrypt for a truncated archive, got %v", err)
+		}
+	})
+}
+
+func TestEncrypt_Chunks(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, KeySize)
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize} {
+		plain := bytes.Repeat([]byte{'y'}, size)
+		var enc bytes.Buffer
+		e, err := newEncrypter(&enc, "k", key)
+		if err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		// written in uneven pieces, so that chunks are filled across writes.
+		for rest := plain; len(rest) > 0; {
+			n := min(len(rest), 1000)
+			if _, err = e.Write(rest[:n]); err != nil {
+				t.Fatalf("%d: %v", size, err)
+			}
+			rest = rest[n:]
+		}
+		if err = e.Close(); err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		r := bytes.NewReader(enc.Bytes())
+		_, hdr, err := readEncHeader(r)
+		if err != nil || hdr == nil {
+			t.Fatalf("%d: expected an encryption header, got %v", size, err)
+		}
+		d, err := newDecrypter(r, hdr, StaticKeys{Keys: map[string][]byte{"k": key}})
+		if err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		got, err := io.ReadAll(d)
+		if err != nil || !bytes.Equal(got, plain) {
+			t.Fatalf("%d: round trip failed (%v)", size, err)
+		}
+		if size < chunkSize {
+			continue
+		}
+		// dropping whole chunks from the end must not go unnoticed.
+		short := enc.Bytes()[:len(hdr)+chunkSize+d.aead.Overhead()]
+		r = bytes.NewReader(short)
+		_, hdr, _ = readEncHeader(r)
+		d, _ = newDecrypter(r, hdr, StaticKeys{Keys: map[string][]byte{"k": key}})
+		if _, err = io.ReadAll(d); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("%d: expected ErrDecrypt for a truncated stream, got %v", size, err)
+		}
+	}
+}
+
+func TestKeyFromPassphrase(t *testing.T) {
+	k1, err := KeyFromPassphrase("ops", "correct horse battery staple")
+	if err != nil || len(k1) != KeySize {
+		t.Fatalf("expected a %d byte key, got %d (%v)", KeySize, len(k1), err)
+	}
+	k2, _ := KeyFromPassphrase("ops", "correct horse battery staple")
+	k3, _ := KeyFromPassphrase("dev", "correct horse battery staple")
+	if !bytes.Equal(k1, k2) || bytes.Equal(k1, k3) {
+		t.Error("expected keys to depend on the passphrase and the ID only")
+	}
+	if _, err = KeyFromPassphrase("", "yeet"); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey for an empty ID, got %v", err)
+	}
+}
+
+// prefixCodec "compresses" by writing a magic prefix, to test registering codecs.
+type prefixCodec struct{}
+
+func (prefixCodec) Format() Format {
+	return "tar.yeet"
+}
+
+func (prefixCodec) Magic() []byte {
+	return []byte("YEET")
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func (c prefixCodec) NewWriter(w io.Writer, _ CompressionLevel) (io.WriteCloser, error) {
+	_, err := w.Write(c.Magic())
+	return nopWriteCloser{w}, err
+}
+
+func (c prefixCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	magic := make([]byte, len(c.Magic()))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, c.Magic()) {
+		return nil, fmt.Errorf("bad magic: %q (%v)", magic, err)
+	}
+	return io.NopCloser(r), nil
+}
+
+func TestCompression(t *testing.T) {
+	inDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(inDir, "yeet"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("compressible yeets "), 50000)
+	if err := os.WriteFile(filepath.Join(inDir, "yeet", "000.data"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, format := range []Format{FormatTarGz, FormatTarZst, FormatTarXz} {
+		t.Run(string(format), func(t *testing.T) {
+			for _, level := range []CompressionLevel{DefaultCompression, BestSpeed, 5, BestCompression} {
+				b, err := NewBackuper(format, WithLevel(level))
+				if err != nil {
+					t.Fatalf("level %d: %v", level, err)
+				}
+				bu, err := b.Backup(context.Background(), inDir, filepath.Join(t.TempDir(), "out"+format.Ext()), []string{"yeet"}, []byte("extra"))
+				if err != nil {
+					t.Fatalf("level %d: %v", level, err)
+				}
+				if bu.Format() != string(format) {
+					t.Errorf("level %d: expected format %s in metadata, got %s", level, format, bu.Format())
+				}
+				if bu.Size >= int64(len(data)) {
+					t.Errorf("level %d: expected the archive to be compressed, got %d bytes", level, bu.Size)
+				}
+				if err = VerifyBackup(bu); err != nil {
+					t.Fatalf("level %d: %v", level, err)
+				}
+				outDir := t.TempDir()
+				if err = RestoreBackup(bu.Path(), outDir); err != nil {
+					t.Fatalf("level %d: %v", level, err)
+				}
+				if restored, readErr := os.ReadFile(filepath.Join(outDir, "yeet", "000.data")); !bytes.Equal(restored, data) {
+					t.Fatalf("level %d: restored file doesn't match (%v)", level, readErr)
+				}
+			}
+			if _, err := NewBackuper(format, WithLevel(BestCompression+1)); !errors.Is(err, ErrInvalidCompressionLevel) {
+				t.Errorf("expected ErrInvalidCompressionLevel, got %v", err)
+			}
+		})
+	}
+
+	t.Run("register", func(t *testing.T) {
+		if err := RegisterCodec(prefixCodec{}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			codecsMu.Lock()
+			delete(codecs, prefixCodec{}.Format())
+			codecsMu.Unlock()
+		})
+		outPath := filepath.Join(t.TempDir(), "out.tar.yeet")
+		if got := FormatFromPath(outPath); got != "tar.yeet" {
+			t.Fatalf("expected tar.yeet from the path, got %s", got)
+		}
+		bu, err := NewBackup(inDir, outPath, []string{"yeet"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, detectErr := DetectFormat(bu.Path()); got != "tar.yeet" || detectErr != nil {
+			t.Errorf("expected tar.yeet from the contents, got %s (%v)", got, detectErr)
+		}
+		if err = RestoreBackup(bu.Path(), t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+		for _, format := range []Format{FormatTar, FormatZip, ""} {
+			if err = RegisterCodec(badFormatCodec{prefixCodec{}, format}); !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("%q: expected ErrUnsupportedFormat, got %v", format, err)
+			}
+		}
+	})
+}
+
+type badFormatCodec struct {
+	prefixCodec
+	format Format
+}
+
+func (c badFormatCodec) Format() Format {
+	return c.format
+}
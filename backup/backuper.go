@@ -0,0 +1,240 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned for backup formats other than those declared by [Format].
+var ErrUnsupportedFormat = errors.New("unsupported backup format")
+
+// Backuper creates, restores and verifies backup archives of a single [Format].
+type Backuper interface {
+	// Format returns the format of the archives the Backuper handles.
+	Format() Format
+	// Backup writes an archive of the directory inPath to outPath, checking that each of the stores made it in to it.
+	// If outPath is a directory, the archive is written in to it, named after inPath.
+	Backup(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error)
+	// Restore extracts the archive at inPath in to the directory outPath.
+	Restore(inPath string, outPath string) error
+	// Verify checks the archive described by metadata against its checksum, and that it can be read in full.
+	Verify(metadata BackupMetadata) error
+}
+
+// compressedTarBackuper handles tar archives compressed with a [Codec].
+type compressedTarBackuper struct {
+	codec Codec
+	opts  options
+}
+
+func (b compressedTarBackuper) Format() Format {
+	return b.codec.Format()
+}
+
+func (b compressedTarBackuper) Backup(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return newCompressedTarBackup(ctx, b.codec, b.opts, inPath, outPath, stores, extraData...)
+}
+
+func (b compressedTarBackuper) Restore(inPath string, outPath string) error {
+	return restoreCompressedTar(b.codec, b.opts.keys, inPath, outPath)
+}
+
+func (b compressedTarBackuper) Verify(metadata BackupMetadata) error {
+	return verify(metadata, b.opts.keys, func(a *archive) error {
+		cr, err := b.codec.NewReader(a)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = cr.Close()
+		}()
+		if err = readTar(cr); err != nil {
+			return err
+		}
+		// the tar archive ends before the codec's own trailer and checksums, which are only checked at EOF.
+		_, err = io.Copy(io.Discard, cr)
+		return err
+	})
+}
+
+type tarBackuper struct {
+	opts options
+}
+
+func (tarBackuper) Format() Format {
+	return FormatTar
+}
+
+func (b tarBackuper) Backup(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return newTarBackup(ctx, b.opts, inPath, outPath, stores, extraData...)
+}
+
+func (b tarBackuper) Restore(inPath string, outPath string) error {
+	return restoreTar(b.opts.keys, inPath, outPath)
+}
+
+func (b tarBackuper) Verify(metadata BackupMetadata) error {
+	return verify(metadata, b.opts.keys, func(a *archive) error {
+		return readTar(a)
+	})
+}
+
+type zipBackuper struct {
+	opts options
+}
+
+func (zipBackuper) Format() Format {
+	return FormatZip
+}
+
+func (b zipBackuper) Backup(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return newZipBackup(ctx, b.opts, inPath, outPath, stores, extraData...)
+}
+
+func (b zipBackuper) Restore(inPath string, outPath string) error {
+	return restoreZip(b.opts.keys, inPath, outPath)
+}
+
+func (b zipBackuper) Verify(metadata BackupMetadata) error {
+	return verify(metadata, b.opts.keys, func(a *archive) error {
+		size, err := a.Size()
+		if err != nil {
+			return err
+		}
+		return readZip(a, size)
+	})
+}
+
+// Ext returns the file extension of archives in the format, including the leading dot.
+func (f Format) Ext() string {
+	return "." + string(f)
+}
+
+// NewBackuper returns the [Backuper] for the given format, which is either uncompressed or has a [Codec]
+// registered for it. The archives it writes and reads are configured with opts, and a level set with
+// [WithLevel] outside of [DefaultCompression] to [BestCompression] returns [ErrInvalidCompressionLevel].
+func NewBackuper(format Format, opts ...Option) (Backuper, error) {
+	o := newOptions(opts)
+	if err := checkLevel(o.level); err != nil {
+		return nil, err
+	}
+	return newBackuper(format, o)
+}
+
+func newBackuper(format Format, o options) (Backuper, error) {
+	switch format {
+	case FormatTar:
+		return tarBackuper{opts: o}, nil
+	case FormatZip:
+		return zipBackuper{opts: o}, nil
+	}
+	if c, ok := getCodec(format); ok {
+		return compressedTarBackuper{codec: c, opts: o}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// FormatFromPath returns the format of an archive named path, going by its extension.
+// Paths ending in .tgz are [FormatTarGz], and paths without a known extension default to [FormatTarGz].
+func FormatFromPath(path string) Format {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".tgz") {
+		return FormatTarGz
+	}
+	for _, c := range allCodecs() {
+		if strings.HasSuffix(lower, c.Format().Ext()) {
+			return c.Format()
+		}
+	}
+	switch {
+	case strings.HasSuffix(lower, FormatTar.Ext()):
+		return FormatTar
+	case strings.HasSuffix(lower, FormatZip.Ext()):
+		return FormatZip
+	default:
+		return FormatTarGz
+	}
+}
+
+// DetectFormat returns the format of the archive at path, going by its contents.
+// An encrypted archive is decrypted with the keys in opts.
+func DetectFormat(path string, opts ...Option) (Format, error) {
+	return detectFormat(path, newOptions(opts).keys)
+}
+
+func detectFormat(path string, keys KeyProvider) (Format, error) {
+	f, err := openArchive(path, keys)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("error reading backup file: %w", err)
+	}
+	head = head[:n]
+	for _, c := range allCodecs() {
+		if magic := c.Magic(); len(magic) > 0 && bytes.HasPrefix(head, magic) {
+			return c.Format(), nil
+		}
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return FormatZip, nil
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return FormatTar, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, path)
+	}
+}
+
+// NewBackup writes an archive of inPath to outPath, in the format given by the extension of outPath.
+// See [FormatFromPath].
+func NewBackup(inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return NewBackupContext(context.Background(), inPath, outPath, stores, extraData...)
+}
+
+// NewBackupContext is like [NewBackup], but stops writing the archive once ctx is done.
+func NewBackupContext(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	b, err := NewBackuper(FormatFromPath(outPath))
+	if err != nil {
+		return BackupMetadata{}, err
+	}
+	return b.Backup(ctx, inPath, outPath, stores, extraData...)
+}
+
+// RestoreBackup extracts the archive at inPath in to outPath, in whichever format it was written in.
+// See [DetectFormat]. If it is an incremental backup, the chain of backups it builds on is restored first,
+// see [ResolveChain] for how they are found. Encrypted archives are decrypted with the keys in opts.
+func RestoreBackup(inPath string, outPath string, opts ...Option) error {
+	chain, err := ResolveChain(inPath, opts...)
+	if err != nil {
+		return err
+	}
+	return RestoreChain(chain, outPath, opts...)
+}
+
+// readTar reads every entry of a tar archive.
+func readTar(r io.Reader) error {
+	tfr := tar.NewReader(r)
+	for {
+		_, err := tfr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(io.Discard, tfr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// ErrInvalidCompressionLevel is returned for compression levels outside of [DefaultCompression] to
+// [BestCompression].
+var ErrInvalidCompressionLevel = errors.New("invalid compression level")
+
+// CompressionLevel trades the speed of compressing a backup for its size, from [BestSpeed] to [BestCompression].
+// Each [Codec] maps the levels on to its own.
+type CompressionLevel int
+
+const (
+	// DefaultCompression uses the default level of the [Codec].
+	DefaultCompression CompressionLevel = 0
+	BestSpeed          CompressionLevel = 1
+	BestCompression    CompressionLevel = 9
+)
+
+// copyBufferSize is the size of the buffers used to compress, checksum and extract archives.
+const copyBufferSize = 256 * 1024
+
+// Codec compresses the tar stream of a backup archive.
+type Codec interface {
+	// Format returns the format of tar archives compressed with the Codec, which is also their file extension.
+	Format() Format
+	// Magic returns the bytes that streams compressed with the Codec start with, used to detect their format.
+	Magic() []byte
+	// NewWriter returns a writer compressing in to w at the given level.
+	NewWriter(w io.Writer, level CompressionLevel) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecs = map[Format]Codec{
+		FormatTarGz:  gzipCodec{},
+		FormatTarZst: zstdCodec{},
+		FormatTarXz:  xzCodec{},
+	}
+	codecsMu sync.RWMutex
+)
+
+// RegisterCodec makes archives in the format of c readable and writable by [NewBackup], [RestoreBackup] and
+// [VerifyBackup], replacing any Codec registered for the same format.
+// The uncompressed formats, [FormatTar] and [FormatZip], can't be registered.
+func RegisterCodec(c Codec) error {
+	switch c.Format() {
+	case "", FormatTar, FormatZip:
+		return fmt.Errorf("%w: can't register a codec for %q", ErrUnsupportedFormat, c.Format())
+	}
+	codecsMu.Lock()
+	codecs[c.Format()] = c
+	codecsMu.Unlock()
+	return nil
+}
+
+func getCodec(format Format) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[format]
+	return c, ok
+}
+
+// allCodecs returns the registered codecs, those with the longest extensions first so that they match first.
+func allCodecs() []Codec {
+	codecsMu.RLock()
+	all := make([]Codec, 0, len(codecs))
+	for _, c := range codecs {
+		all = append(all, c)
+	}
+	codecsMu.RUnlock()
+	slices.SortFunc(all, func(a, b Codec) int {
+		if n := len(b.Format()) - len(a.Format()); n != 0 {
+			return n
+		}
+		return strings.Compare(string(a.Format()), string(b.Format()))
+	})
+	return all
+}
+
+// WithLevel makes the compressed backups written with it use level. Uncompressed formats ignore it.
+func WithLevel(level CompressionLevel) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+func checkLevel(level CompressionLevel) error {
+	if level < DefaultCompression || level > BestCompression {
+		return fmt.Errorf("%w: %d", ErrInvalidCompressionLevel, level)
+	}
+	return nil
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Format() Format {
+	return FormatTarGz
+}
+
+func (gzipCodec) Magic() []byte {
+	return []byte{0x1f, 0x8b}
+}
+
+func (gzipCodec) NewWriter(w io.Writer, level CompressionLevel) (io.WriteCloser, error) {
+	if err := checkLevel(level); err != nil {
+		return nil, err
+	}
+	if level == DefaultCompression {
+		return gzip.NewWriter(w), nil
+	}
+	return gzip.NewWriterLevel(w, int(level))
+}
+
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Format() Format {
+	return FormatTarZst
+}
+
+func (zstdCodec) Magic() []byte {
+	return []byte{0x28, 0xb5, 0x2f, 0xfd}
+}
+
+func (zstdCodec) NewWriter(w io.Writer, level CompressionLevel) (io.WriteCloser, error) {
+	if err := checkLevel(level); err != nil {
+		return nil, err
+	}
+	speed := zstd.SpeedDefault
+	switch {
+	case level == DefaultCompression:
+	case level <= 2:
+		speed = zstd.SpeedFastest
+	case level <= 5:
+		speed = zstd.SpeedDefault
+	case level <= 7:
+		speed = zstd.SpeedBetterCompression
+	default:
+		speed = zstd.SpeedBestCompression
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(speed))
+}
+
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+type xzCodec struct{}
+
+func (xzCodec) Format() Format {
+	return FormatTarXz
+}
+
+func (xzCodec) Magic() []byte {
+	return []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+}
+
+// xzDictCaps are the dictionary sizes of the xz presets, which is what their levels mostly come down to.
+var xzDictCaps = [...]int{0, 1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+func (xzCodec) NewWriter(w io.Writer, level CompressionLevel) (io.WriteCloser, error) {
+	if err := checkLevel(level); err != nil {
+		return nil, err
+	}
+	if level == DefaultCompression {
+		return xz.NewWriter(w)
+	}
+	return xz.WriterConfig{DictCap: xzDictCaps[level]}.NewWriter(w)
+}
+
+func (xzCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	xr, err := xz.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(xr), nil
+}
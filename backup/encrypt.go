@@ -0,0 +1,354 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// ErrNoKeyProvider is returned when reading an encrypted archive without a [KeyProvider], see [WithKeys].
+	ErrNoKeyProvider = errors.New("backup is encrypted but no key provider was given")
+	// ErrKeyNotFound is returned by a [KeyProvider] that doesn't have the key it is asked for.
+	ErrKeyNotFound = errors.New("backup encryption key not found")
+	// ErrInvalidKey is returned for encryption keys that aren't 32 bytes long, or whose ID is empty or too long.
+	ErrInvalidKey = errors.New("invalid backup encryption key")
+	// ErrDecrypt is returned when an encrypted archive can't be decrypted, because the key is wrong or
+	// the archive was truncated or tampered with.
+	ErrDecrypt = errors.New("error decrypting backup")
+)
+
+const (
+	// KeySize is the size of the keys that backups are encrypted with.
+	KeySize = 32
+
+	encMagic    = "TCPDBENC\x01"
+	encInfo     = "github.com/tcp-direct/database backup"
+	saltSize    = 16
+	chunkSize   = 64 * 1024
+	maxKeyIDLen = 255
+)
+
+// KeyProvider supplies the keys that backups are encrypted with. Only the ID of a key is stored
+// in the archives and their [BackupMetadata], the key itself never is.
+type KeyProvider interface {
+	// EncryptionKey returns the ID of the key that new backups are encrypted with, and the key itself.
+	EncryptionKey() (id string, key []byte, err error)
+	// DecryptionKey returns the key with the given ID, or [ErrKeyNotFound].
+	DecryptionKey(id string) ([]byte, error)
+}
+
+// StaticKeys is a [KeyProvider] holding its keys in memory. New backups are encrypted with the key named by Current,
+// the other keys are kept to decrypt older backups.
+type StaticKeys struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+func (sk StaticKeys) EncryptionKey() (string, []byte, error) {
+	key, err := sk.DecryptionKey(sk.Current)
+	return sk.Current, key, err
+}
+
+func (sk StaticKeys) DecryptionKey(id string) ([]byte, error) {
+	key, ok := sk.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// KeyFromPassphrase derives a key for a [KeyProvider] from a passphrase with scrypt, salted with the key's ID.
+// The same ID and passphrase always derive the same key.
+func KeyFromPassphrase(id string, passphrase string) ([]byte, error) {
+	if id == "" || len(id) > maxKeyIDLen {
+		return nil, fmt.Errorf("%w: bad key ID %q", ErrInvalidKey, id)
+	}
+	return scrypt.Key([]byte(passphrase), []byte(encInfo+" key "+id), 1<<15, 8, 1, KeySize)
+}
+
+// WithKeys makes the backups written with it encrypted with the current key of p, and lets encrypted archives
+// be restored, verified and used as the base of incremental backups transparently. Encrypted archives can't be
+// read without it.
+func WithKeys(p KeyProvider) Option {
+	return func(o *options) {
+		o.keys = p
+	}
+}
+
+// KeyID returns the ID of the key the archive at path is encrypted with, or an empty string if it isn't encrypted.
+func KeyID(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening backup file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	id, _, err := readEncHeader(f)
+	return id, err
+}
+
+// encHeader returns the header of an encrypted archive, which is also authenticated with every chunk of it.
+func encHeader(keyID string, salt []byte) []byte {
+	hdr := make([]byte, 0, len(encMagic)+1+len(keyID)+len(salt))
+	hdr = append(hdr, encMagic...)
+	hdr = append(hdr, byte(len(keyID)))
+	hdr = append(hdr, keyID...)
+	return append(hdr, salt...)
+}
+
+// readEncHeader reads the header of an encrypted archive from r, returning an empty key ID and a nil header
+// with r rewound to its start if it isn't encrypted.
+func readEncHeader(r io.ReadSeeker) (string, []byte, error) {
+	magic := make([]byte, len(encMagic)+1)
+	n, err := io.ReadFull(r, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", nil, fmt.Errorf("error reading backup file: %w", err)
+	}
+	if n < len(magic) || !bytes.HasPrefix(magic, []byte(encMagic)) {
+		_, err = r.Seek(0, io.SeekStart)
+		return "", nil, err
+	}
+	rest := make([]byte, int(magic[len(encMagic)])+saltSize)
+	if _, err = io.ReadFull(r, rest); err != nil {
+		return "", nil, fmt.Errorf("%w: header is truncated", ErrDecrypt)
+	}
+	keyID := string(rest[:len(rest)-saltSize])
+	return keyID, append(magic, rest...), nil
+}
+
+// newAEAD derives the key for a single archive from key and the archive's salt.
+func newAEAD(key []byte, salt []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("%w: key is %d bytes, not %d", ErrInvalidKey, len(key), KeySize)
+	}
+	fileKey := make([]byte, KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, salt, []byte(encInfo)), fileKey); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce numbers the chunks of an archive, marking the last one so that a truncated archive can't be
+// mistaken for a complete one.
+func chunkNonce(nonce []byte, counter uint64, last bool) []byte {
+	clear(nonce)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-9:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// encrypter writes what is written to it to w encrypted with a key, in chunks sealed with AES-GCM. The last chunk,
+// written by Close, is always shorter than a full one, so that readers can tell it apart.
+type encrypter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	hdr     []byte
+	nonce   []byte
+	buf     []byte
+	counter uint64
+}
+
+func newEncrypter(w io.Writer, keyID string, key []byte) (*encrypter, error) {
+	if keyID == "" || len(keyID) > maxKeyIDLen {
+		return nil, fmt.Errorf("%w: bad key ID %q", ErrInvalidKey, keyID)
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	hdr := encHeader(keyID, salt)
+	if _, err = w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &encrypter{
+		w:     w,
+		aead:  aead,
+		hdr:   hdr,
+		nonce: make([]byte, aead.NonceSize()),
+		buf:   make([]byte, 0, chunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (e *encrypter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+		// a full chunk is sealed straight away, so that the chunk left for Close is always shorter.
+		if len(e.buf) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (e *encrypter) seal(last bool) error {
+	_, err := e.w.Write(e.aead.Seal(e.buf[:0], chunkNonce(e.nonce, e.counter, last), e.buf, e.hdr))
+	e.counter++
+	e.buf = e.buf[:0]
+	return err
+}
+
+// Close writes the last chunk. It doesn't close w.
+func (e *encrypter) Close() error {
+	return e.seal(true)
+}
+
+// decrypter reads the plaintext of an encrypted archive, one chunk at a time.
+type decrypter struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	hdr     []byte
+	nonce   []byte
+	buf     []byte
+	plain   []byte
+	counter uint64
+	done    bool
+}
+
+func newDecrypter(r io.Reader, hdr []byte, p KeyProvider) (*decrypter, error) {
+	keyID := string(hdr[len(encMagic)+1 : len(hdr)-saltSize])
+	if p == nil {
+		return nil, fmt.Errorf("%w: key %s", ErrNoKeyProvider, keyID)
+	}
+	key, err := p.DecryptionKey(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting backup decryption key: %w", err)
+	}
+	aead, err := newAEAD(key, hdr[len(hdr)-saltSize:])
+	if err != nil {
+		return nil, err
+	}
+	return &decrypter{
+		r:     r,
+		aead:  aead,
+		hdr:   hdr,
+		nonce: make([]byte, aead.NonceSize()),
+		buf:   make([]byte, chunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (d *decrypter) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// next decrypts the next chunk, which is the last one if it is shorter than a full chunk.
+func (d *decrypter) next() error {
+	n, err := io.ReadFull(d.r, d.buf)
+	last := errors.Is(err, io.ErrUnexpectedEOF)
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: archive is truncated", ErrDecrypt)
+	case err != nil && !last:
+		return fmt.Errorf("error reading backup file: %w", err)
+	}
+	plain, err := d.aead.Open(d.buf[:0], chunkNonce(d.nonce, d.counter, last), d.buf[:n], d.hdr)
+	if err != nil {
+		return ErrDecrypt
+	}
+	d.counter++
+	d.plain = plain
+	d.done = last
+	return nil
+}
+
+// archive is an opened backup file, read through a decrypter if it is encrypted.
+type archive struct {
+	io.Reader
+	f    *os.File
+	tmp  *os.File
+	size int64
+}
+
+// ReadAt makes the plaintext of an archive readable at random, which zip files need. An encrypted archive is
+// decrypted in to a temporary file next to it the first time, so that the plaintext stays on the same filesystem
+// as the archive, readable only by its owner, and is removed on Close. Reading from it after ReadAt was called
+// returns nothing.
+func (a *archive) ReadAt(p []byte, off int64) (int, error) {
+	if err := a.plaintextFile(); err != nil {
+		return 0, err
+	}
+	if a.tmp != nil {
+		return a.tmp.ReadAt(p, off)
+	}
+	return a.f.ReadAt(p, off)
+}
+
+// Size returns the size of the archive's plaintext.
+func (a *archive) Size() (int64, error) {
+	if err := a.plaintextFile(); err != nil {
+		return 0, err
+	}
+	return a.size, nil
+}
+
+func (a *archive) plaintextFile() error {
+	if a.size >= 0 {
+		return nil
+	}
+	if _, ok := a.Reader.(*decrypter); !ok {
+		stat, err := a.f.Stat()
+		if err != nil {
+			return fmt.Errorf("error checking backup file: %w", err)
+		}
+		a.size = stat.Size()
+		return nil
+	}
+	// CreateTemp opens the file with 0600 permissions.
+	tmp, err := os.CreateTemp(filepath.Dir(a.f.Name()), "."+filepath.Base(a.f.Name())+"-*.dec")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file to decrypt backup in to: %w", err)
+	}
+	a.tmp = tmp
+	if a.size, err = io.CopyBuffer(tmp, a.Reader, make([]byte, chunkSize)); err != nil {
+		a.size = -1
+		return err
+	}
+	a.Reader = bytes.NewReader(nil)
+	return nil
+}
+
+func (a *archive) Close() error {
+	err := a.f.Close()
+	if a.tmp != nil {
+		err = errors.Join(err, a.tmp.Close(), os.Remove(a.tmp.Name()))
+	}
+	return err
+}
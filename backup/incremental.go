@@ -0,0 +1,382 @@
+package backup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+// ManifestName is the name of the manifest at the start of every archive, which is not restored.
+const ManifestName = ".backup-manifest.json"
+
+var (
+	// ErrNoManifest is returned when an archive written before manifests were added is used as the base of an
+	// incremental backup.
+	ErrNoManifest = errors.New("backup has no manifest")
+	// ErrBaseNotFound is returned when the base of an incremental backup can't be found.
+	ErrBaseNotFound = errors.New("base backup not found")
+	// ErrBrokenChain is returned when a backup in a chain isn't the base of the one that follows it.
+	ErrBrokenChain = errors.New("backup chain is broken")
+)
+
+// FileState is what a [Manifest] records about each file, to tell whether it changed since.
+type FileState struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// Manifest lists every file of the directory an archive was written from, whether or not the file is in the archive.
+type Manifest struct {
+	// Base is the checksum of the backup an incremental backup builds on, empty for a full backup.
+	Base string `json:"base,omitempty"`
+	// BasePath is where the base backup was when the incremental backup was written.
+	BasePath string `json:"base_path,omitempty"`
+	// Files maps the slash separated path of each file to its state.
+	Files map[string]FileState `json:"files"`
+}
+
+// WithBase makes the backups written with it incremental backups on top of base: only the files that changed
+// since base was written are archived.
+func WithBase(base BackupMetadata) Option {
+	return func(o *options) {
+		o.base = &base
+	}
+}
+
+// WithKnown adds to the backups that the base of an incremental backup is looked up among by its checksum when
+// it is restored, such as those returned by [KnownBackups].
+func WithKnown(known ...BackupMetadata) Option {
+	return func(o *options) {
+		o.known = append(o.known, known...)
+	}
+}
+
+// KnownBackups returns the backups recorded in a Keeper's [metadata.Metadata.Backups], decoding those that were
+// read back from JSON.
+func KnownBackups(meta models.Metadata) []BackupMetadata {
+	m, err := metadata.CastToMetadata(meta)
+	if err != nil || m == nil {
+		return nil
+	}
+	known := make([]BackupMetadata, 0, len(m.Backups))
+	for _, v := range m.Backups {
+		switch bu := v.(type) {
+		case BackupMetadata:
+			known = append(known, bu)
+		case *BackupMetadata:
+			known = append(known, *bu)
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			var decoded BackupMetadata
+			if err = json.Unmarshal(data, &decoded); err == nil && decoded.FilePath != "" {
+				known = append(known, decoded)
+			}
+		}
+	}
+	return known
+}
+
+// IncrementalKeeper is a Keeper that can be backed up with options, which all of the Keepers in this module are.
+type IncrementalKeeper interface {
+	Meta() models.Metadata
+	BackupAllContext(ctx context.Context, archivePath string, opts ...Option) (models.Backup, error)
+}
+
+// Incremental backs up keeper to archivePath with opts, archiving only the files that changed since the backup
+// with the checksum base, which must be recorded in the Keeper's metadata.
+func Incremental(ctx context.Context, keeper IncrementalKeeper, archivePath string, base string, opts ...Option) (models.Backup, error) {
+	for _, bu := range KnownBackups(keeper.Meta()) {
+		if bu.Checksum.Value == base {
+			return keeper.BackupAllContext(ctx, archivePath, append(slices.Clip(opts), WithBase(bu))...)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrBaseNotFound, base)
+}
+
+// source is what goes in to an archive: the files of fsys, preceded by the manifest.
+type source struct {
+	fsys     fs.FS
+	manifest Manifest
+	encoded  []byte
+}
+
+// collect lists the files of inPath in a manifest, checksumming those that may have changed since the base in o,
+// if any. Only the files that changed are in the returned source's fsys.
+func collect(ctx context.Context, inPath string, o options) (source, error) {
+	var prev Manifest
+	man := Manifest{Files: make(map[string]FileState)}
+	base, incremental := o.base, o.base != nil
+	if incremental {
+		baseMan, err := readManifest(base.FilePath, o.keys)
+		if err != nil {
+			return source{}, fmt.Errorf("error reading base backup: %w", err)
+		}
+		prev = *baseMan
+		man.Base, man.BasePath = base.Checksum.Value, base.FilePath
+	}
+	dir := os.DirFS(inPath)
+	changed := make(map[string]bool)
+	err := fs.WalkDir(dir, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		state := FileState{Size: info.Size(), ModTime: info.ModTime().UTC()}
+		old, known := prev.Files[name]
+		if known && old.Size == state.Size && old.ModTime.Equal(state.ModTime) {
+			man.Files[name] = old
+			return nil
+		}
+		if state.SHA256, err = checksumFile(ctx, filepath.Join(inPath, filepath.FromSlash(name))); err != nil {
+			return err
+		}
+		man.Files[name] = state
+		changed[name] = !known || old.Size != state.Size || old.SHA256 != state.SHA256
+		return nil
+	})
+	if err != nil {
+		return source{}, fmt.Errorf("error collecting files to backup: %w", err)
+	}
+	encoded, err := json.Marshal(man)
+	if err != nil {
+		return source{}, err
+	}
+	src := source{fsys: dir, manifest: man, encoded: encoded}
+	if incremental {
+		src.fsys = changedFS{ReadDirFS: dir.(fs.ReadDirFS), changed: changed}
+	}
+	return src, nil
+}
+
+func checksumFile(ctx context.Context, name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err = io.Copy(ctxWriter{ctx: ctx, w: h}, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// changedFS hides the files of a directory that haven't changed since the base of an incremental backup.
+type changedFS struct {
+	fs.ReadDirFS
+	changed map[string]bool
+}
+
+func (c changedFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := c.ReadDirFS.ReadDir(name)
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		return e.Type().IsRegular() && !c.changed[path.Join(name, e.Name())]
+	}), err
+}
+
+// ReadManifest reads the manifest of the archive at path, returning [ErrNoManifest] if it doesn't have one.
+// An encrypted archive is decrypted with the keys in opts.
+func ReadManifest(path string, opts ...Option) (*Manifest, error) {
+	return readManifest(path, newOptions(opts).keys)
+}
+
+func readManifest(path string, keys KeyProvider) (*Manifest, error) {
+	format, err := detectFormat(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	f, err := openArchive(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var r io.Reader
+	switch format {
+	case FormatZip:
+		size, sizeErr := f.Size()
+		if sizeErr != nil {
+			return nil, sizeErr
+		}
+		zr, zipErr := zip.NewReader(f, size)
+		if zipErr != nil {
+			return nil, fmt.Errorf("error reading zip file: %w", zipErr)
+		}
+		rc, openErr := zr.Open(ManifestName)
+		if errors.Is(openErr, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNoManifest, path)
+		}
+		if openErr != nil {
+			return nil, openErr
+		}
+		defer func() {
+			_ = rc.Close()
+		}()
+		r = rc
+	default:
+		var tr io.Reader = f
+		if c, ok := getCodec(format); ok {
+			cr, codecErr := c.NewReader(f)
+			if codecErr != nil {
+				return nil, fmt.Errorf("error creating %s reader: %w", format, codecErr)
+			}
+			defer func() {
+				_ = cr.Close()
+			}()
+			tr = cr
+		}
+		if r, err = tarManifest(tar.NewReader(tr)); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, path)
+		}
+	}
+	man := &Manifest{}
+	if err = json.NewDecoder(r).Decode(man); err != nil {
+		return nil, fmt.Errorf("error decoding manifest of %s: %w", path, err)
+	}
+	return man, nil
+}
+
+// tarManifest returns the manifest at the start of a tar archive, skipping its global header.
+func tarManifest(tfr *tar.Reader) (io.Reader, error) {
+	for {
+		entry, err := tfr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoManifest
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading tar file: %w", err)
+		}
+		switch {
+		case entry.Typeflag == tar.TypeXGlobalHeader:
+			continue
+		case entry.Name == ManifestName:
+			return tfr, nil
+		default:
+			return nil, ErrNoManifest
+		}
+	}
+}
+
+// ResolveChain returns the paths of the backups that the archive at path builds on, starting with a full backup
+// and ending with path. Each base is looked up by its checksum among those given with [WithKnown], falling back
+// to where the base was when the incremental backup was written.
+func ResolveChain(path string, opts ...Option) ([]string, error) {
+	o := newOptions(opts)
+	chain := []string{path}
+	for {
+		man, err := readManifest(chain[0], o.keys)
+		if errors.Is(err, ErrNoManifest) && len(chain) == 1 {
+			// archives from before manifests were added are always full backups.
+			return chain, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if man.Base == "" {
+			return chain, nil
+		}
+		basePath := ""
+		for _, bu := range o.known {
+			if bu.Checksum.Value != man.Base {
+				continue
+			}
+			if _, statErr := os.Stat(bu.FilePath); statErr == nil {
+				basePath = bu.FilePath
+				break
+			}
+		}
+		if _, statErr := os.Stat(man.BasePath); basePath == "" && man.BasePath != "" && statErr == nil {
+			basePath = man.BasePath
+		}
+		if basePath == "" || slices.Contains(chain, basePath) {
+			return nil, fmt.Errorf("%w: %s", ErrBaseNotFound, man.Base)
+		}
+		chain = append([]string{basePath}, chain...)
+	}
+}
+
+// RestoreChain restores a full backup followed by the incremental backups on top of it, in order, in to outPath.
+// Each backup after the first must have been written on top of the one before it, which is checked by checksum.
+// Files that were removed between two backups of the chain are removed from outPath. Encrypted archives are
+// decrypted with the keys in opts.
+func RestoreChain(paths []string, outPath string, opts ...Option) error {
+	o := newOptions(opts)
+	var prev *Manifest
+	for i, p := range paths {
+		man, err := readManifest(p, o.keys)
+		switch {
+		case errors.Is(err, ErrNoManifest) && i == 0:
+		case err != nil:
+			return err
+		case i == 0 && man.Base != "":
+			return fmt.Errorf("%w: %s is not a full backup", ErrBrokenChain, p)
+		}
+		if i > 0 {
+			sum, sumErr := checksumFile(context.Background(), paths[i-1])
+			if sumErr != nil {
+				return fmt.Errorf("error checksumming %s: %w", paths[i-1], sumErr)
+			}
+			if man.Base != sum {
+				return fmt.Errorf("%w: %s is not the base of %s", ErrBrokenChain, paths[i-1], p)
+			}
+		}
+		if err = restoreArchive(p, outPath, o); err != nil {
+			return err
+		}
+		if prev != nil {
+			for name := range prev.Files {
+				if _, kept := man.Files[name]; kept {
+					continue
+				}
+				if err = os.Remove(filepath.Join(outPath, filepath.FromSlash(name))); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("error removing file deleted since the base backup: %w", err)
+				}
+			}
+		}
+		prev = man
+	}
+	return nil
+}
+
+// restoreArchive extracts a single archive in to outPath, in whichever format it was written in.
+func restoreArchive(inPath string, outPath string, o options) error {
+	format, err := detectFormat(inPath, o.keys)
+	if err != nil {
+		return err
+	}
+	b, err := newBackuper(format, o)
+	if err != nil {
+		return err
+	}
+	return b.Restore(inPath, outPath)
+}
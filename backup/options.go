@@ -0,0 +1,25 @@
+package backup
+
+// Option configures how archives are written and read by a [Backuper], the functions of this package that
+// take options, or a Keeper's BackupAll and RestoreAll. Options that don't apply to what is being done are ignored.
+type Option func(*options)
+
+// options are the settings of a [Backuper]. The zero value writes full, unencrypted backups.
+type options struct {
+	// base is the backup that incremental backups are written on top of, nil for full backups.
+	base *BackupMetadata
+	// keys encrypts the archives written and decrypts those read, nil if they aren't encrypted.
+	keys KeyProvider
+	// known are the backups that the bases of incremental backups are looked up among.
+	known []BackupMetadata
+	// level is the level that compressed archives are written at.
+	level CompressionLevel
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,67 @@
+package backup
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+func NewTarBackup(inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return NewTarBackupContext(context.Background(), inPath, outPath, stores, extraData...)
+}
+
+// NewTarBackupContext writes an uncompressed tar archive of inPath to outPath, stopping once ctx is done.
+// Extra data is recorded in a PAX global header at the start of the archive. A partially written archive is removed.
+func NewTarBackupContext(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return newTarBackup(ctx, options{}, inPath, outPath, stores, extraData...)
+}
+
+func newTarBackup(ctx context.Context, o options, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	nilBackup := BackupMetadata{}
+	outPath, err := prepare(ctx, inPath, outPath, FormatTar)
+	if err != nil {
+		return nilBackup, err
+	}
+	src, err := collect(ctx, inPath, o)
+	if err != nil {
+		return nilBackup, err
+	}
+	out, err := createOutput(outPath, o.keys)
+	if err != nil {
+		return nilBackup, err
+	}
+	defer out.abort()
+	tmpF, err := tarToTemp(ctx, src, outPath, stores, comment(extraData))
+	if err != nil {
+		return nilBackup, err
+	}
+	defer func() {
+		_ = tmpF.Close()
+		_ = os.Remove(tmpF.Name())
+	}()
+	if _, err = io.CopyBuffer(ctxWriter{ctx: ctx, w: out}, tmpF, make([]byte, copyBufferSize)); err != nil {
+		return nilBackup, fmt.Errorf("error writing final tar file: %w", err)
+	}
+	if err = tmpF.Close(); err != nil {
+		return nilBackup, fmt.Errorf("error closing temporary tar file: %w", err)
+	}
+	return out.commit(FormatTar, stores, src)
+}
+
+func RestoreTarBackup(inPath string, outPath string) error {
+	return restoreTar(nil, inPath, outPath)
+}
+
+// restoreTar extracts an uncompressed tar archive in to outPath, decrypting it with keys if it is encrypted.
+func restoreTar(keys KeyProvider, inPath string, outPath string) error {
+	f, err := openArchive(inPath, keys)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return extractTar(tar.NewReader(f), outPath)
+}
@@ -5,20 +5,25 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
-	"errors"
 	"fmt"
 	"hash"
 	"io"
 	"os"
 )
 
-func VerifyBackup(metadata BackupMetadata) error {
-	switch metadata.Format() {
-	case "tar.gz":
-		//
-	default:
-		return errors.New("unsupported backup format")
+// VerifyBackup checks the archive described by metadata against its checksum, and that it can be read in full,
+// using the [Backuper] for its format. An encrypted archive is decrypted with the keys in opts.
+func VerifyBackup(metadata BackupMetadata, opts ...Option) error {
+	b, err := NewBackuper(Format(metadata.Format()), opts...)
+	if err != nil {
+		return err
 	}
+	return b.Verify(metadata)
+}
+
+// verify checks the archive described by metadata against its checksum, then reads it with read,
+// decrypting it with keys first if it is encrypted.
+func verify(metadata BackupMetadata, keys KeyProvider, read func(a *archive) error) error {
 	file, err := os.Open(metadata.FilePath)
 	if err != nil {
 		return fmt.Errorf("error opening backup file: %w", err)
@@ -50,5 +55,15 @@ func VerifyBackup(metadata BackupMetadata) error {
 		return fmt.Errorf("checksums do not match")
 	}
 
+	a, err := openArchive(metadata.FilePath, keys)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = a.Close()
+	}()
+	if err = read(a); err != nil {
+		return fmt.Errorf("error reading backup archive: %w", err)
+	}
 	return nil
 }
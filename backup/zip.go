@@ -0,0 +1,168 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func NewZipBackup(inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return NewZipBackupContext(context.Background(), inPath, outPath, stores, extraData...)
+}
+
+// NewZipBackupContext writes a zip archive of inPath to outPath, stopping once ctx is done.
+// Extra data is recorded in the archive's comment. A partially written archive is removed.
+func NewZipBackupContext(ctx context.Context, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	return newZipBackup(ctx, options{}, inPath, outPath, stores, extraData...)
+}
+
+func newZipBackup(ctx context.Context, o options, inPath string, outPath string, stores []string, extraData ...[]byte) (BackupMetadata, error) {
+	nilBackup := BackupMetadata{}
+	outPath, err := prepare(ctx, inPath, outPath, FormatZip)
+	if err != nil {
+		return nilBackup, err
+	}
+	src, err := collect(ctx, inPath, o)
+	if err != nil {
+		return nilBackup, err
+	}
+	out, err := createOutput(outPath, o.keys)
+	if err != nil {
+		return nilBackup, err
+	}
+	defer out.abort()
+
+	zw := zip.NewWriter(ctxWriter{ctx: ctx, w: out})
+	if err = zw.SetComment(comment(extraData)); err != nil {
+		return nilBackup, fmt.Errorf("error setting zip comment: %w", err)
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: ManifestName, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return nilBackup, fmt.Errorf("error writing backup manifest: %w", err)
+	}
+	if _, err = w.Write(src.encoded); err != nil {
+		return nilBackup, fmt.Errorf("error writing backup manifest: %w", err)
+	}
+	if err = zw.AddFS(src.fsys); err != nil {
+		return nilBackup, fmt.Errorf("error adding files to backup: %w", err)
+	}
+	if err = zw.Close(); err != nil {
+		return nilBackup, fmt.Errorf("error closing final zip file: %w", err)
+	}
+	if err = out.close(); err != nil {
+		return nilBackup, err
+	}
+
+	names, err := zipNames(out.f.Name(), o.keys)
+	if err != nil {
+		return nilBackup, fmt.Errorf("error verifying backup zip file: %w", err)
+	}
+	if err = src.checkStores(names, stores); err != nil {
+		return nilBackup, err
+	}
+	return out.commit(FormatZip, stores, src)
+}
+
+// zipNames returns the names of the entries of the zip archive at path, decrypting it with keys if it is encrypted.
+func zipNames(path string, keys KeyProvider) ([]string, error) {
+	f, err := openArchive(path, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	size, err := f.Size()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(zr.File))
+	for _, entry := range zr.File {
+		names = append(names, entry.Name)
+	}
+	return names, nil
+}
+
+func RestoreZipBackup(inPath string, outPath string) error {
+	return restoreZip(nil, inPath, outPath)
+}
+
+// restoreZip extracts a zip archive in to outPath, decrypting it with keys if it is encrypted.
+func restoreZip(keys KeyProvider, inPath string, outPath string) error {
+	f, err := openArchive(inPath, keys)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	size, err := f.Size()
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return fmt.Errorf("error reading zip file: %w", err)
+	}
+	buf := make([]byte, copyBufferSize)
+	for _, entry := range zr.File {
+		if entry.Name == ManifestName {
+			continue
+		}
+		if !filepath.IsLocal(entry.Name) {
+			return fmt.Errorf("zip file contains invalid path: %s", entry.Name)
+		}
+		mode := entry.Mode()
+		switch {
+		case mode.IsDir():
+			if err = os.MkdirAll(filepath.Join(outPath, entry.Name), 0755); err != nil {
+				return fmt.Errorf("error creating directory: %w", err)
+			}
+		case mode.IsRegular():
+			if err = extractZipFile(entry, outPath, buf); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported zip file type: %s", mode.Type())
+		}
+	}
+	return nil
+}
+
+func extractZipFile(entry *zip.File, outPath string, buf []byte) error {
+	rc, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("error opening %s in zip file: %w", entry.Name, err)
+	}
+	err = extractFile(rc, outPath, entry.Name, buf)
+	return errors.Join(err, rc.Close())
+}
+
+// readZip reads every file in a zip archive, which checks their CRC-32 checksums.
+func readZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, entry := range zr.File {
+		rc, openErr := entry.Open()
+		if openErr != nil {
+			return openErr
+		}
+		_, err = io.Copy(io.Discard, rc)
+		_ = rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package database
+
+// Batch is a set of writes that are committed to a Filer together.
+//
+// Batches are atomic and isolated: a concurrent read of the Filer sees either all of a batch's writes or none
+// of them. Stores without native batches hold reads back while a batch is being applied.
+type Batch interface {
+	// Put should queue the value to be inserted at the given key once the batch is committed.
+	Put(key []byte, value []byte)
+	// Delete should queue the key to be deleted once the batch is committed.
+	Delete(key []byte)
+	// Len should return the number of writes queued in the batch.
+	Len() int
+	// Commit should apply all queued writes so that either all of them or none of them take effect,
+	// including when the process crashes part way through. The batch is empty afterwards.
+	Commit() error
+}
+
+// Batcher is a Filer that can apply several writes atomically.
+type Batcher interface {
+	Filer
+	// NewBatch should return an empty [Batch] that commits to this Filer.
+	NewBatch() Batch
+}
@@ -0,0 +1,540 @@
+package bbolt
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+// dbFile is the name of the bbolt database file inside of each store's directory.
+const dbFile = "bbolt.db"
+
+// Store is an implementation of a Filer and a Searcher using bbolt.
+// Each store is a single bucket inside of its own bbolt database file.
+type Store struct {
+	*bolt.DB
+	bucket []byte
+	closed *atomic.Bool
+}
+
+// Backend returns the underlying bbolt instance.
+func (s *Store) Backend() any {
+	return s.DB
+}
+
+// Has returns true if the given key has an associated value.
+func (s *Store) Has(key []byte) bool {
+	if s.closed.Load() {
+		return false
+	}
+	var ok bool
+	_ = s.DB.View(func(tx *bolt.Tx) error {
+		ok = tx.Bucket(s.bucket).Get(key) != nil
+		return nil
+	})
+	return ok
+}
+
+// Get retrieves the value associated with the given key.
+// bbolt values are only valid for the life of a transaction, so a copy is returned.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	if s.closed.Load() {
+		return nil, fs.ErrClosed
+	}
+	var ret []byte
+	err := s.DB.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(s.bucket).Get(key); v != nil {
+			ret = make([]byte, len(v))
+			copy(ret, v)
+		}
+		return nil
+	})
+	return ret, kv.RegularizeKVError(key, ret, err)
+}
+
+// Put inserts the given key and value in a single read/write transaction.
+func (s *Store) Put(key []byte, value []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put(key, value)
+	})
+}
+
+// Delete removes the given key in a single read/write transaction.
+func (s *Store) Delete(key []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	return s.DB.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete(key)
+	})
+}
+
+// Close is a wrapper around the bbolt Close function.
+func (s *Store) Close() error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	s.closed.Store(true)
+	return s.DB.Close()
+}
+
+// Sync is a wrapper around the bbolt Sync function.
+func (s *Store) Sync() error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	return s.DB.Sync()
+}
+
+// Keys returns all keys in the store, in byte order, as a slice of byte slices.
+func (s *Store) Keys() [][]byte {
+	var keys [][]byte
+	_ = s.DB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(s.bucket)
+		keys = make([][]byte, 0, b.Stats().KeyN)
+		return b.ForEach(func(k, _ []byte) error {
+			keys = append(keys, slices.Clone(k))
+			return nil
+		})
+	})
+	return keys
+}
+
+// Len returns the number of keys in the store.
+func (s *Store) Len() int {
+	var n int
+	_ = s.DB.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(s.bucket).Stats().KeyN
+		return nil
+	})
+	return n
+}
+
+// DB is a mapper of a Filer and Searcher implementation using bbolt.
+type DB struct {
+	store       map[string]*Store
+	path        string
+	mu          *sync.RWMutex
+	meta        *metadata.Metadata
+	initialized *atomic.Bool
+}
+
+// Meta returns the [models.Metadata] implementation of the bbolt keeper.
+func (db *DB) Meta() models.Metadata {
+	var m models.Metadata
+	db.mu.RLock()
+	m = db.meta
+	db.mu.RUnlock()
+	if m == nil {
+		m = metadata.NewPlaceholder(db.Type())
+	}
+	return m
+}
+
+func (db *DB) allStores() map[string]database.Filer {
+	var stores = make(map[string]database.Filer)
+	for n, s := range db.store {
+		stores[n] = s
+	}
+	return stores
+}
+
+// AllStores returns a map of the names of all bbolt datastores and the corresponding Filers.
+func (db *DB) AllStores() map[string]database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.RLock()
+	ast := db.allStores()
+	db.mu.RUnlock()
+	return ast
+}
+
+func (db *DB) _init() error {
+	if _, err := os.Stat(db.path); os.IsNotExist(err) {
+		err = os.MkdirAll(db.path, 0700)
+		if err != nil {
+			return fmt.Errorf("error creating bbolt directory: %w", err)
+		}
+	}
+	stat, err := os.Stat(filepath.Join(db.path, "meta.json"))
+	if err == nil && stat.IsDir() {
+		return errors.New("meta.json is a directory")
+	}
+	if err == nil && !stat.IsDir() {
+		if db.meta, err = metadata.OpenMetaFile(filepath.Join(db.path, "meta.json")); err != nil {
+			return fmt.Errorf("error opening meta file: %w", err)
+		}
+		if db.meta.Type() != db.Type() {
+			return fmt.Errorf("meta.json is not a bbolt meta file")
+		}
+		db.initialized.Store(true)
+		return nil
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		db.meta, err = metadata.NewMetaFile(db.Type(), filepath.Join(db.path, "meta.json"))
+		if err != nil {
+			return fmt.Errorf("error creating meta file: %w", err)
+		}
+		db.initialized.Store(true)
+		return nil
+	}
+
+	return err
+}
+
+func (db *DB) init() error {
+	if db.initialized.Load() {
+		return nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db._init()
+}
+
+// OpenDB will either open an existing set of bbolt datastores at the given directory, or it will create a new one.
+func OpenDB(path string) *DB {
+	ainit := &atomic.Bool{}
+	ainit.Store(false)
+	return &DB{
+		store:       make(map[string]*Store),
+		path:        path,
+		mu:          &sync.RWMutex{},
+		meta:        nil,
+		initialized: ainit,
+	}
+}
+
+// Path returns the base path where we store our bbolt "stores".
+func (db *DB) Path() string {
+	return db.path
+}
+
+var (
+	defaultBoltOptions = &bolt.Options{Timeout: time.Second}
+	defOptMu           = &sync.RWMutex{}
+)
+
+// SetDefaultBoltOptions will set the options used for all subsequent bbolt stores that are initialized.
+func SetDefaultBoltOptions(opts *bolt.Options) {
+	defOptMu.Lock()
+	defaultBoltOptions = opts
+	defOptMu.Unlock()
+}
+
+func castOptions(opts ...any) (*bolt.Options, error) {
+	defOptMu.RLock()
+	ret := defaultBoltOptions
+	defOptMu.RUnlock()
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case *bolt.Options:
+			ret = v
+		case bolt.Options:
+			ret = &v
+		default:
+			return nil, fmt.Errorf("%w (%T): %v", ErrBadOptions, opt, opt)
+		}
+	}
+	return ret, nil
+}
+
+// initStore is a helper function to initialize a bbolt store, caller must hold keeper's lock.
+func (db *DB) initStore(storeName string, opts *bolt.Options) error {
+	if _, ok := db.store[storeName]; ok {
+		return ErrStoreExists
+	}
+	if err := os.MkdirAll(filepath.Join(db.path, storeName), 0700); err != nil {
+		return err
+	}
+	c, err := bolt.Open(filepath.Join(db.path, storeName, dbFile), 0600, opts)
+	if err != nil {
+		return err
+	}
+	bucket := []byte(storeName)
+	if err = c.Update(func(tx *bolt.Tx) error {
+		_, bErr := tx.CreateBucketIfNotExists(bucket)
+		return bErr
+	}); err != nil {
+		_ = c.Close()
+		return err
+	}
+	aclosed := &atomic.Bool{}
+	aclosed.Store(false)
+	db.store[storeName] = &Store{DB: c, bucket: bucket, closed: aclosed}
+	if !slices.Contains(db.meta.KnownStores, storeName) {
+		db.meta.KnownStores = append(db.meta.KnownStores, storeName)
+	}
+	return nil
+}
+
+// Init opens a bbolt store at the given path to be referenced by storeName.
+func (db *DB) Init(storeName string, opts ...any) error {
+	if err := db.init(); err != nil {
+		return err
+	}
+	boltOpts, err := castOptions(opts...)
+	if err != nil {
+		return err
+	}
+	db.mu.Lock()
+	err = db.initStore(storeName, boltOpts)
+	db.mu.Unlock()
+	return err
+}
+
+// Destroy will remove the bbolt store and all data associated with it.
+func (db *DB) Destroy(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	if err := st.Close(); err != nil && !errors.Is(err, fs.ErrClosed) {
+		return err
+	}
+	delete(db.store, storeName)
+	db.meta.RemoveStore(storeName)
+	return os.RemoveAll(filepath.Join(db.path, storeName))
+}
+
+// With calls the given underlying bbolt instance.
+func (db *DB) With(storeName string) database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.RLock()
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		db.mu.RUnlock()
+		return d
+	}
+	db.mu.RUnlock()
+	if ok {
+		db.mu.Lock()
+		delete(db.store, storeName)
+		db.mu.Unlock()
+	}
+	return nil
+}
+
+// WithNew calls the given underlying bbolt instance, if it doesn't exist, it creates it.
+func (db *DB) WithNew(storeName string, opts ...any) database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	boltOpts, err := castOptions(opts...)
+	if err != nil {
+		fmt.Println("WARN: ignoring invalid bbolt options: ", err.Error())
+		boltOpts, _ = castOptions()
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		return d
+	}
+	if ok {
+		delete(db.store, storeName)
+	}
+	if err = db.initStore(storeName, boltOpts); err != nil {
+		fmt.Println("ERROR: failed to create bbolt store: ", err)
+		return nil
+	}
+	return db.store[storeName]
+}
+
+// Close is a simple shim for bbolt's Close function.
+func (db *DB) Close(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	if err := st.Close(); err != nil {
+		return err
+	}
+	delete(db.store, storeName)
+	return nil
+}
+
+// Sync is a simple shim for bbolt's Sync function.
+func (db *DB) Sync(storeName string) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if _, ok := db.store[storeName]; !ok {
+		return ErrBogusStore
+	}
+	return db.store[storeName].Sync()
+}
+
+// withAllAction
+type withAllAction uint8
+
+const (
+	// dclose
+	dclose withAllAction = iota
+	// dsync
+	dsync
+)
+
+// withAll performs an action on all bbolt stores that we have open.
+// In the case of an error, withAll will continue and return a compound form of any errors that occurred.
+func (db *DB) withAll(action withAllAction) error {
+	if db == nil || db.store == nil {
+		panic("bbolt: nil db or db.store")
+	}
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	var errs = make([]error, 0, len(db.store))
+	for name, store := range db.store {
+		var err error
+		if store.DB == nil {
+			errs = append(errs, namedErr(name, ErrBogusStore))
+			continue
+		}
+		if store.closed.Load() {
+			continue
+		}
+		switch action {
+		case dclose:
+			err = namedErr(name, store.Close())
+			delete(db.store, name)
+		case dsync:
+			err = namedErr(name, store.Sync())
+		default:
+			return ErrUnknownAction
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return compoundErrors(errs)
+}
+
+func (db *DB) addAllStoresToMeta() {
+	storeNames := make([]string, 0, len(db.store))
+	for name := range db.store {
+		if name == "" {
+			continue
+		}
+		storeNames = append(storeNames, name)
+	}
+	db.meta = db.meta.WithStores(storeNames...)
+}
+
+func (db *DB) syncAll() error {
+	if err := db._init(); err != nil && db.meta == nil {
+		return err
+	}
+	db.addAllStoresToMeta()
+	var errs = make([]error, 0)
+	errs = append(errs, db.withAll(dsync))
+	errs = append(errs, db.meta.Sync())
+	return compoundErrors(errs)
+}
+
+// SyncAll syncs all bbolt datastores.
+func (db *DB) SyncAll() error {
+	db.mu.Lock()
+	err := db.syncAll()
+	db.mu.Unlock()
+	return err
+}
+
+func (db *DB) closeAll() error {
+	return db.withAll(dclose)
+}
+
+// CloseAll closes all bbolt datastores.
+func (db *DB) CloseAll() error {
+	db.mu.Lock()
+	err := db.closeAll()
+	db.mu.Unlock()
+	return err
+}
+
+// SyncAndCloseAll implements the method from Keeper to sync and close all bbolt stores.
+func (db *DB) SyncAndCloseAll() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	errs := []error{namedErr("sync", db.syncAll()), namedErr("close", db.closeAll())}
+	return compoundErrors(errs)
+}
+
+// discover is a helper function to discover and initialize all existing bbolt stores at the path.
+// caller must hold write lock.
+func (db *DB) discover() ([]string, error) {
+	entries, err := os.ReadDir(db.path)
+	if err != nil {
+		return nil, err
+	}
+	stores := make([]string, 0, len(entries))
+	errs := make([]error, 0)
+	opts, _ := castOptions()
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if st, ok := db.store[name]; ok && !st.closed.Load() {
+			stores = append(stores, name)
+			continue
+		}
+		delete(db.store, name)
+		if _, statErr := os.Stat(filepath.Join(db.path, name, dbFile)); statErr != nil {
+			continue
+		}
+		if err = db.initStore(name, opts); err != nil {
+			errs = append(errs, namedErr(name, err))
+			continue
+		}
+		stores = append(stores, name)
+	}
+	return stores, compoundErrors(errs)
+}
+
+// Discover will discover and initialize all existing bbolt stores at the path opened by [OpenDB].
+func (db *DB) Discover() ([]string, error) {
+	if err := db.init(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	stores, err := db.discover()
+	db.mu.Unlock()
+	return stores, err
+}
+
+// Type returns the type of keeper, in this case "bbolt".
+// This is in order to implement [database.Keeper].
+func (db *DB) Type() string {
+	return "bbolt"
+}
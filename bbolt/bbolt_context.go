@@ -0,0 +1,20 @@
+package bbolt
+
+import "context"
+
+// GetContext is like Get, but returns ctx's error instead if it is already done.
+// bbolt has no way of interrupting a single read or write, so ctx is only checked before it starts.
+func (s *Store) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+// PutContext is like Put, but returns ctx's error instead if it is already done.
+func (s *Store) PutContext(ctx context.Context, key []byte, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Put(key, value)
+}
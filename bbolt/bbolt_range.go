@@ -0,0 +1,48 @@
+package bbolt
+
+import (
+	"io/fs"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+)
+
+// cursor adapts a bbolt cursor to [iterator.Cursor].
+type cursor struct {
+	*bolt.Cursor
+	k, v []byte
+}
+
+func (c *cursor) set(k, v []byte) bool {
+	c.k, c.v = k, v
+	return k != nil
+}
+
+func (c *cursor) Seek(key []byte) bool { return c.set(c.Cursor.Seek(key)) }
+func (c *cursor) First() bool          { return c.set(c.Cursor.First()) }
+func (c *cursor) Last() bool           { return c.set(c.Cursor.Last()) }
+func (c *cursor) Next() bool           { return c.set(c.Cursor.Next()) }
+func (c *cursor) Prev() bool           { return c.set(c.Cursor.Prev()) }
+func (c *cursor) Key() []byte          { return c.k }
+func (c *cursor) Value() []byte        { return c.v }
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Each page of results is read in its own read-only transaction, so writes aren't blocked while iterating.
+func (s *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if s.closed.Load() {
+		return iterator.Error(fs.ErrClosed)
+	}
+	return iterator.Paged(func(after []byte, n int) (keys [][]byte, values [][]byte, err error) {
+		if s.closed.Load() {
+			return nil, nil, fs.ErrClosed
+		}
+		err = s.DB.View(func(tx *bolt.Tx) error {
+			c := &cursor{Cursor: tx.Bucket(s.bucket).Cursor()}
+			keys, values = iterator.Walk(c, start, end, after, opts.Reverse, n)
+			return nil
+		})
+		return keys, values, err
+	}, opts.Limit)
+}
@@ -0,0 +1,99 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// stream collects the key/value pairs passed to emit by walk inside of a read transaction, then sends them
+// to the returned channel. Results are collected before sending so that a slow reader doesn't hold a read
+// transaction open. walk should return ctx's error once it is done, which also stops the sending.
+func (s *Store) stream(ctx context.Context, walk func(b *bolt.Bucket, emit func(k, v []byte)) error) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		var results []kv.KeyValue
+		err := s.DB.View(func(tx *bolt.Tx) error {
+			return walk(tx.Bucket(s.bucket), func(k, v []byte) {
+				results = append(results, kv.NewKeyValueFromBytes(bytes.Clone(k), bytes.Clone(v)))
+			})
+		})
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, keyVal := range results {
+			if !kv.Send(ctx, resChan, keyVal) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return resChan, errChan
+}
+
+// Search will search for a given string within all values inside of a Store.
+// Note, type casting will be necessary. (e.g: []byte or string)
+func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+func (s *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, func(b *bolt.Bucket, emit func(k, v []byte)) error {
+		return b.ForEach(func(k, v []byte) error {
+			if strings.Contains(string(v), query) {
+				emit(k, v)
+			}
+			return ctx.Err()
+		})
+	})
+}
+
+// ValueExists will check for the existence of a Value anywhere within the keyspace;
+// returning the first Key found, true if found || nil and false if not found.
+func (s *Store) ValueExists(value []byte) (key []byte, ok bool) {
+	_ = s.DB.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(s.bucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if bytes.Equal(v, value) {
+				key = bytes.Clone(k)
+				ok = true
+				return nil
+			}
+		}
+		return nil
+	})
+	return
+}
+
+// PrefixScan will scan a Store for all keys that have a matching prefix of the given string
+// and stream the matching key/value pairs, in byte order, to the returned channel.
+// Being a B+tree, bbolt is able to seek directly to the prefix.
+func (s *Store) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
+	return s.PrefixScanContext(context.Background(), prefix)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+func (s *Store) PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error) {
+	p := []byte(prefix)
+	return s.stream(ctx, func(b *bolt.Bucket, emit func(k, v []byte)) error {
+		c := b.Cursor()
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			emit(k, v)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,52 @@
+package bbolt
+
+import (
+	"bytes"
+	"context"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// matchKeys returns a walk for stream that emits every pair whose key is accepted by match, in byte order.
+func matchKeys(ctx context.Context, match func(k []byte) bool) func(b *bolt.Bucket, emit func(k, v []byte)) error {
+	return func(b *bolt.Bucket, emit func(k, v []byte)) error {
+		return b.ForEach(func(k, v []byte) error {
+			if match(k) {
+				emit(k, v)
+			}
+			return ctx.Err()
+		})
+	}
+}
+
+// SuffixScan will stream every key/value pair whose key ends with suffix, in byte order.
+// Keys are sorted from their start, so the whole bucket is walked.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(suffix)
+	return s.stream(ctx, matchKeys(ctx, func(k []byte) bool {
+		return bytes.HasSuffix(k, needle)
+	}))
+}
+
+// KeyContains will stream every key/value pair whose key contains substr, in byte order.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return s.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (s *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(substr)
+	return s.stream(ctx, matchKeys(ctx, func(k []byte) bool {
+		return bytes.Contains(k, needle)
+	}))
+}
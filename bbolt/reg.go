@@ -0,0 +1,21 @@
+package bbolt
+
+import (
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/registry"
+)
+
+func init() {
+	registry.RegisterKeeper("bbolt", func(path string, opts ...any) (database.Keeper, error) {
+		if len(opts) > 0 {
+			casted, err := castOptions(opts...)
+			if err != nil {
+				return nil, err
+			}
+			SetDefaultBoltOptions(casted)
+		}
+		db := OpenDB(path)
+		err := db.init()
+		return db, err
+	})
+}
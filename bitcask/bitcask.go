@@ -14,6 +14,8 @@ import (
 	"git.mills.io/prologic/bitcask"
 
 	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/expiry"
+	"github.com/tcp-direct/database/journal"
 	"github.com/tcp-direct/database/kv"
 	"github.com/tcp-direct/database/metadata"
 	"github.com/tcp-direct/database/models"
@@ -23,7 +25,12 @@ import (
 type Store struct {
 	*bitcask.Bitcask
 	database.Searcher
-	closed *atomic.Bool
+	closed  *atomic.Bool
+	ttl     *expiry.Tracker
+	journal *journal.Journal
+	// batches is held for writing while a batch is applied, and for reading by reads of the store,
+	// so that reads see either all of a batch's writes or none of them.
+	batches *sync.RWMutex
 }
 
 // Get is a wrapper around the bitcask Get function for error regularization.
@@ -31,6 +38,11 @@ func (s *Store) Get(key []byte) ([]byte, error) {
 	if s.closed.Load() {
 		return nil, fs.ErrClosed
 	}
+	s.batches.RLock()
+	defer s.batches.RUnlock()
+	if s.expire(key) {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
 	ret, err := s.Bitcask.Get(key)
 	err = kv.RegularizeKVError(key, ret, err)
 	return ret, err
@@ -42,7 +54,19 @@ func (s *Store) Close() error {
 		return fs.ErrClosed
 	}
 	s.closed.Store(true)
-	return s.Bitcask.Close()
+	ttlErr := s.ttl.Close()
+	if err := s.Bitcask.Close(); err != nil {
+		return err
+	}
+	return ttlErr
+}
+
+// Sync is a wrapper around the bitcask Sync function that also syncs the store's expiry log.
+func (s *Store) Sync() error {
+	if err := s.ttl.Sync(); err != nil {
+		return err
+	}
+	return s.Bitcask.Sync()
 }
 
 // Backend returns the underlying bitcask instance.
@@ -57,6 +81,7 @@ type DB struct {
 	mu          *sync.RWMutex
 	meta        *metadata.Metadata
 	initialized *atomic.Bool
+	sweeper     *expiry.Sweeper
 }
 
 // Meta returns the [models.Metadata] implementation of the bitcask keeper.
@@ -138,13 +163,15 @@ func (db *DB) init() error {
 func OpenDB(path string) *DB {
 	ainit := &atomic.Bool{}
 	ainit.Store(false)
-	return &DB{
+	db := &DB{
 		store:       make(map[string]*Store),
 		path:        path,
 		mu:          &sync.RWMutex{},
 		meta:        nil,
 		initialized: ainit,
 	}
+	db.sweeper = expiry.NewSweeper(expiry.DefaultSweepInterval, db.sweep)
+	return db
 }
 
 // discover is a helper function to discover and initialize all existing bitcask stores at the path.
@@ -223,9 +250,13 @@ func (db *DB) discover(force ...bool) ([]string, error) {
 			errs = append(errs, e)
 			continue
 		}
-		aclosed := &atomic.Bool{}
-		aclosed.Store(false)
-		db.store[name] = &Store{Bitcask: c, closed: aclosed}
+		st, stErr := newStore(c, filepath.Join(db.path, name))
+		if stErr != nil {
+			errs = append(errs, stErr)
+			continue
+		}
+		db.store[name] = st
+		db.sweeper.Start()
 		if db.meta == nil {
 			// TODO: verify this:
 			// bitcask should store it's config in each store's individual metadata files (whereas pogreb doesn't seem to)
@@ -335,9 +366,12 @@ func (db *DB) initStore(storeName string, opts ...bitcask.Option) error {
 		return e
 	}
 
-	aclosed := &atomic.Bool{}
-	aclosed.Store(false)
-	db.store[storeName] = &Store{Bitcask: c, closed: aclosed}
+	st, err := newStore(c, filepath.Join(db.Path(), storeName))
+	if err != nil {
+		return err
+	}
+	db.store[storeName] = st
+	db.sweeper.Start()
 	return nil
 }
 
@@ -354,7 +388,14 @@ func (db *DB) Destroy(storeName string) error {
 		return err
 	}
 	delete(db.store, storeName)
-	return os.RemoveAll(filepath.Join(db.path, storeName))
+	path := filepath.Join(db.path, storeName)
+	errs := []error{os.RemoveAll(path)}
+	for _, name := range sidecars {
+		if err = os.Remove(sidecar(path, name)); !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // With calls the given underlying bitcask instance.
@@ -515,6 +556,7 @@ func (db *DB) syncAndCloseAll() error {
 
 // SyncAndCloseAll implements the method from Keeper to sync and close all bitcask stores.
 func (db *DB) SyncAndCloseAll() error {
+	db.sweeper.Stop()
 	db.mu.Lock()
 	err := db.syncAndCloseAll()
 	db.mu.Unlock()
@@ -527,6 +569,7 @@ func (db *DB) closeAll() error {
 
 // CloseAll closes all bitcask datastores.
 func (db *DB) CloseAll() error {
+	db.sweeper.Stop()
 	db.mu.Lock()
 	err := db.closeAll()
 	db.mu.Unlock()
@@ -1,8 +1,11 @@
 package bitcask
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"slices"
@@ -12,45 +15,241 @@ import (
 	"github.com/tcp-direct/database/models"
 )
 
-func (db *DB) BackupAll(archivePath string) (models.Backup, error) {
-	// calling write lock should stop any other operations on the stores while we backup. shouldn't need to close.
-	db.mu.Lock()
-	defer db.mu.Unlock()
+// skipSnapshot are the files of a bitcask store left out of its snapshot. Without an index, bitcask rebuilds it
+// from every data file when the restored store is opened, rather than trusting one saved before the latest writes.
+var skipSnapshot = []string{"lock", "index", "ttl_index", "temp_index"}
+
+// pendingCopy is a file of a snapshot that is copied once writes are let through again,
+// up to the length it had while they were held back.
+type pendingCopy struct {
+	src     *os.File
+	dst     string
+	size    int64
+	modTime time.Time
+}
+
+func openPending(src string, dst string) (pendingCopy, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return pendingCopy{}, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return pendingCopy{}, err
+	}
+	return pendingCopy{src: f, dst: dst, size: stat.Size(), modTime: stat.ModTime()}, nil
+}
 
+// copy writes the file out, keeping its modification time so that incremental backups can tell it hasn't changed.
+func (pc pendingCopy) copy() error {
+	out, err := os.OpenFile(pc.dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, io.NewSectionReader(pc.src, 0, pc.size))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(pc.dst, pc.modTime, pc.modTime)
+}
+
+func closePending(pending []pendingCopy) {
+	for _, pc := range pending {
+		_ = pc.src.Close()
+	}
+}
+
+// snapshotFiles snapshots the regular files directly within src in to dst. Data files other than the last, which
+// bitcask no longer writes to, are hard linked. The data file being written to, and any that couldn't be linked, are
+// returned to be copied later. Other files are small, and are copied straight away, apart from the sidecars of stores
+// which are snapshotted along with their store.
+func snapshotFiles(src string, dst string) ([]pendingCopy, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dst, 0700); err != nil {
+		return nil, err
+	}
 	var (
-		storeNames []string
-		discovErr  error
+		dataFiles []string
+		pending   []pendingCopy
 	)
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case !entry.Type().IsRegular(), slices.Contains(skipSnapshot, name), isSidecar(name):
+			continue
+		case filepath.Ext(name) == ".data":
+			dataFiles = append(dataFiles, name)
+			continue
+		}
+		pc, openErr := openPending(filepath.Join(src, name), filepath.Join(dst, name))
+		if openErr != nil {
+			closePending(pending)
+			return nil, openErr
+		}
+		err = pc.copy()
+		_ = pc.src.Close()
+		if err != nil {
+			closePending(pending)
+			return nil, err
+		}
+	}
+	// bitcask names its data files after zero padded IDs, so they are read in order and the last is the active one.
+	for i, name := range dataFiles {
+		if i < len(dataFiles)-1 && os.Link(filepath.Join(src, name), filepath.Join(dst, name)) == nil {
+			continue
+		}
+		pc, openErr := openPending(filepath.Join(src, name), filepath.Join(dst, name))
+		if openErr != nil {
+			closePending(pending)
+			return nil, openErr
+		}
+		pending = append(pending, pc)
+	}
+	return pending, nil
+}
+
+// snapshotSidecars copies the sidecars of the store at src, those that exist, next to dst.
+func snapshotSidecars(src string, dst string) error {
+	for _, name := range sidecars {
+		pc, err := openPending(sidecar(src, name), sidecar(dst, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = pc.copy()
+		_ = pc.src.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotStore snapshots the directory of the store at src and its sidecars in to dst. See snapshotFiles.
+func snapshotStore(src string, dst string) ([]pendingCopy, error) {
+	pending, err := snapshotFiles(src, dst)
+	if err != nil {
+		return pending, err
+	}
+	if err = snapshotSidecars(src, dst); err != nil {
+		closePending(pending)
+		return nil, err
+	}
+	return pending, nil
+}
+
+// snapshot snapshots the store's directory src in to dst, holding back writes to the store while it is synced
+// and its files are linked or noted down. See snapshotStore.
+func (s *Store) snapshot(src string, dst string) ([]pendingCopy, error) {
+	var pending []pendingCopy
+	err := s.ttl.Freeze(func() error {
+		if err := s.Bitcask.Sync(); err != nil {
+			return err
+		}
+		var snapErr error
+		pending, snapErr = snapshotStore(src, dst)
+		return snapErr
+	})
+	if errors.Is(err, fs.ErrClosed) {
+		// a closed store's files don't change, so there are no writes to hold back.
+		return snapshotStore(src, dst)
+	}
+	return pending, err
+}
+
+// snapshot snapshots the keeper's metadata and every store in to staging, returning the names of the stores.
+func (db *DB) snapshot(staging string) ([]string, []pendingCopy, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-	if storeNames, discovErr = db.discover(); discovErr != nil {
-		return nil, discovErr
+	if _, err := db.discover(); err != nil {
+		return nil, nil, err
+	}
+	db.addAllStoresToMeta()
+	if err := db.meta.Sync(); err != nil {
+		return nil, nil, err
+	}
+	pending, err := snapshotFiles(db.path, staging)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := db.SyncAll(); err != nil {
+	storeNames := make([]string, 0, len(db.store))
+	for name, st := range db.store {
+		if name == "" {
+			continue
+		}
+		storePending, snapErr := st.snapshot(filepath.Join(db.path, name), filepath.Join(staging, name))
+		pending = append(pending, storePending...)
+		if snapErr != nil {
+			return nil, pending, namedErr(name, snapErr)
+		}
+		storeNames = append(storeNames, name)
+	}
+	slices.Sort(storeNames)
+	return storeNames, pending, nil
+}
+
+// BackupAll writes all bitcask stores and the keeper's metadata to an archive, leaving the stores open and usable.
+// See BackupAllContext.
+func (db *DB) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	return db.BackupAllContext(context.Background(), archivePath, opts...)
+}
+
+// BackupAllContext is like BackupAll, but writes the archive with opts and abandons the backup once ctx is done.
+//
+// Writes to each store are only held back while it is synced and its files are snapshotted in to a staging
+// directory next to the keeper's: data files that bitcask no longer writes to are hard linked, and the length of
+// the one being written to is noted down so it can be copied up to there once writes carry on. The archive is then
+// written from the staging directory. Writes made straight to the bitcask instance returned by Backend aren't
+// held back.
+func (db *DB) BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	if err := db.closeAll(); err != nil {
+	staging, err := os.MkdirTemp(filepath.Dir(db.path), "bitcask-backup-")
+	if err != nil {
 		return nil, err
 	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
 
-	for name, store := range db.store {
-		if !slices.Contains(storeNames, name) {
-			println("WARN: store", name, "not found in discovered stores... appending but this is unexpected behavior.")
-			if err := store.Sync(); err != nil {
-				return nil, err
-			}
-			if err := store.Close(); err != nil {
-				return nil, err
-			}
-			storeNames = append(storeNames, name)
+	storeNames, pending, err := db.snapshot(staging)
+	defer closePending(pending)
+	if err != nil {
+		return nil, err
+	}
+	for _, pc := range pending {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err = pc.copy(); err != nil {
+			return nil, err
 		}
 	}
 
-	bu, err := backup.NewTarGzBackup(db.path, archivePath, storeNames)
+	b, err := backup.NewBackuper(backup.FormatFromPath(archivePath), opts...)
 	if err != nil {
 		return nil, err
 	}
+	bu, err := b.Backup(ctx, staging, archivePath, storeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	if db.meta.Backups == nil {
 		db.meta.Backups = make(map[string]any)
 	}
@@ -59,7 +258,7 @@ func (db *DB) BackupAll(archivePath string) (models.Backup, error) {
 	return bu, err
 }
 
-func (db *DB) RestoreAll(archivePath string) error {
+func (db *DB) RestoreAll(archivePath string, opts ...backup.Option) error {
 	var preBu models.Backup
 
 	if err := db.SyncAndCloseAll(); err != nil && !errors.Is(err, ErrNoStores) {
@@ -89,7 +288,8 @@ func (db *DB) RestoreAll(archivePath string) error {
 
 	db.initialized.Store(false)
 
-	if err := backup.RestoreTarGzBackup(archivePath, db.path); err != nil {
+	opts = append([]backup.Option{backup.WithKnown(backup.KnownBackups(db.meta)...)}, opts...)
+	if err := backup.RestoreBackup(archivePath, db.path, opts...); err != nil {
 		return err
 	}
 
@@ -1,7 +1,124 @@
 package bitcask
 
-import "testing"
+import (
+	"bytes"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tcp-direct/database/backup"
+)
 
 func TestBackupAll(t *testing.T) {
+	parent := t.TempDir()
+	db := OpenDB(filepath.Join(parent, "db"))
+	t.Cleanup(func() {
+		_ = db.SyncAndCloseAll()
+	})
+	for _, name := range []string{"yeet", "yote"} {
+		// small data files, so that the stores have sealed data files to link as well as an active one.
+		if err := db.Init(name, WithMaxDatafileSize(4096)); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 500; i++ {
+			if err := db.With(name).Put([]byte(name+strconv.Itoa(i)), []byte("value"+strconv.Itoa(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	yeet := db.With("yeet").(*Store)
+	if err := yeet.PutWithTTL([]byte("expiring"), []byte("soon"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	dataFiles, _ := filepath.Glob(filepath.Join(db.path, "yeet", "*.data"))
+	if len(dataFiles) < 2 {
+		t.Fatalf("expected the store to span several data files, got %d", len(dataFiles))
+	}
+
+	// keep writing in order while the backup is taken, a point in time snapshot holds an unbroken run of them.
+	var (
+		stop    atomic.Bool
+		wg      sync.WaitGroup
+		live    atomic.Int64
+		liveErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; !stop.Load(); i++ {
+			if liveErr = yeet.Put([]byte("live"+strconv.Itoa(i)), []byte(strconv.Itoa(i))); liveErr != nil {
+				return
+			}
+			live.Add(1)
+		}
+	}()
+	for live.Load() < 100 {
+		time.Sleep(time.Millisecond)
+	}
+
+	bu, err := db.BackupAll(filepath.Join(t.TempDir(), "hot.tar.gz"))
+	written := live.Load()
+	stop.Store(true)
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if liveErr != nil {
+		t.Fatalf("expected writes to carry on during the backup, got %v", liveErr)
+	}
+	if got := bu.(backup.BackupMetadata).Stores; len(got) != 2 {
+		t.Errorf("expected both stores in the backup, got %v", got)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(parent, "bitcask-backup-*")); len(leftovers) > 0 {
+		t.Errorf("expected the staging directory to be removed, got %v", leftovers)
+	}
+
+	// the stores must still be open and usable.
+	if db.With("yote") == nil {
+		t.Fatal("expected store to still be open after the backup")
+	}
+	if err = db.With("yote").Put([]byte("after"), []byte("backup")); err != nil {
+		t.Fatalf("expected writes after the backup to succeed, got %v", err)
+	}
+	if v, getErr := yeet.Get([]byte("yeet42")); getErr != nil || string(v) != "value42" {
+		t.Fatalf("expected reads after the backup to succeed, got %q (%v)", v, getErr)
+	}
 
+	restored := OpenDB(filepath.Join(t.TempDir(), "restored"))
+	if err = backup.RestoreBackup(bu.Path(), restored.path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = restored.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = restored.SyncAndCloseAll()
+	})
+	for _, name := range []string{"yeet", "yote"} {
+		for i := 0; i < 500; i++ {
+			v, getErr := restored.With(name).Get([]byte(name + strconv.Itoa(i)))
+			if getErr != nil || !bytes.Equal(v, []byte("value"+strconv.Itoa(i))) {
+				t.Fatalf("%s: expected value%d, got %q (%v)", name, i, v, getErr)
+			}
+		}
+	}
+	if ttl, ttlErr := restored.With("yeet").(*Store).TTL([]byte("expiring")); ttlErr != nil || ttl <= 0 {
+		t.Errorf("expected the expiration time to be restored, got %v (%v)", ttl, ttlErr)
+	}
+	if restored.With("yote").Has([]byte("after")) {
+		t.Error("expected a write made after the backup to not be in it")
+	}
+	n := 0
+	for restored.With("yeet").Has([]byte("live" + strconv.Itoa(n))) {
+		n++
+	}
+	if n == 0 || int64(n) > written {
+		t.Errorf("expected between 1 and %d of the live writes, got %d", written, n)
+	}
+	if c := restored.With("yeet").Len(); c != 500+1+n {
+		t.Errorf("expected the live writes to be an unbroken run, got %d keys for %d of them", c, n)
+	}
 }
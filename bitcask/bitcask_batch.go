@@ -0,0 +1,50 @@
+package bitcask
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+
+	"git.mills.io/prologic/bitcask"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+)
+
+// rawStore gives the batch journal direct access to the bitcask instance, bypassing the expiry tracker.
+type rawStore struct {
+	*bitcask.Bitcask
+}
+
+func (r rawStore) Get(key []byte) ([]byte, error) {
+	ret, err := r.Bitcask.Get(key)
+	if errors.Is(err, bitcask.ErrKeyNotFound) || errors.Is(err, bitcask.ErrKeyExpired) {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
+	return ret, err
+}
+
+// NewBatch returns an empty batch of writes that will be applied to the store together.
+// Bitcask has no native batches, so a journal next to the store's directory is used to roll back
+// any batch that is interrupted part way through, and reads of the store wait while a batch is applied.
+func (s *Store) NewBatch() database.Batch {
+	return journal.NewBatch(s.commit)
+}
+
+func (s *Store) commit(ops []journal.Op) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	keys := make([][]byte, 0, len(ops))
+	for _, op := range ops {
+		keys = append(keys, op.Key)
+	}
+	// reads wait for the whole batch to be applied, and holding the expiry tracker's lock keeps other writes
+	// and sweeps from interleaving with it.
+	s.batches.Lock()
+	defer s.batches.Unlock()
+	return s.ttl.SetAll(keys, time.Time{}, func() error {
+		return s.journal.Apply(ops)
+	})
+}
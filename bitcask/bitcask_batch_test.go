@@ -0,0 +1,143 @@
+package bitcask
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/journal"
+)
+
+// crashTarget panics after a number of writes to simulate the process dying part way through a batch.
+type crashTarget struct {
+	journal.Target
+	writes int
+}
+
+func (c *crashTarget) Put(key []byte, value []byte) error {
+	if c.writes == 0 {
+		panic("crash")
+	}
+	c.writes--
+	return c.Target.Put(key, value)
+}
+
+// pauseTarget stops after a number of writes until resumed, to read the store part way through a batch.
+type pauseTarget struct {
+	journal.Target
+	writes  int
+	paused  chan struct{}
+	resumed chan struct{}
+}
+
+func (p *pauseTarget) Put(key []byte, value []byte) error {
+	if p.writes == 0 {
+		close(p.paused)
+		<-p.resumed
+	}
+	p.writes--
+	return p.Target.Put(key, value)
+}
+
+func TestStore_Batch(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	st := db.WithNew("test").(*Store)
+	if err := st.Put([]byte("keep"), []byte("me")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.PutWithTTL([]byte("expiring"), []byte("soon"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	batch := st.NewBatch()
+	batch.Put([]byte("yeet"), []byte("yeeterson"))
+	batch.Put([]byte("expiring"), []byte("never"))
+	batch.Delete([]byte("keep"))
+	if err := batch.Commit(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if st.Has([]byte("keep")) || !st.Has([]byte("yeet")) || !st.Has([]byte("expiring")) {
+		t.Errorf("expected batch to be applied and clear ttls, got keys %s", st.Keys())
+	}
+
+	// simulate a crash after the first write of a batch has been applied.
+	crasher := journal.New(sidecar(filepath.Join(path, "test"), journal.File), &crashTarget{Target: rawStore{st.Bitcask}, writes: 1})
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+		_ = crasher.Apply([]journal.Op{
+			{Key: []byte("yeet"), Value: []byte("changed")},
+			{Key: []byte("new"), Value: []byte("value")},
+		})
+	}()
+	if v, _ := st.Get([]byte("yeet")); string(v) != "changed" {
+		t.Fatalf("expected partially applied batch before reopening, got %s", v)
+	}
+	if err := db.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = OpenDB(path)
+	if _, err := db.Discover(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	reopened := db.With("test").(database.Filer)
+	if v, _ := reopened.Get([]byte("yeet")); string(v) != "yeeterson" {
+		t.Errorf("expected interrupted batch to be rolled back, got %s", v)
+	}
+	if reopened.Has([]byte("new")) {
+		t.Error("expected key from interrupted batch to not exist")
+	}
+}
+
+func TestStore_BatchIsolation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test")
+	db := OpenDB(filepath.Dir(path))
+	st := db.WithNew("test").(*Store)
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	if err := st.Put([]byte("old"), []byte("yeet")); err != nil {
+		t.Fatal(err)
+	}
+	pause := &pauseTarget{Target: rawStore{st.Bitcask}, writes: 1, paused: make(chan struct{}), resumed: make(chan struct{})}
+	st.journal = journal.New(sidecar(path, journal.File), pause)
+
+	batch := st.NewBatch()
+	batch.Put([]byte("first"), []byte("yeet"))
+	batch.Put([]byte("second"), []byte("yeet"))
+	batch.Delete([]byte("old"))
+	committed := make(chan error)
+	go func() {
+		committed <- batch.Commit()
+	}()
+	<-pause.paused
+
+	reads := make(chan [][]byte)
+	go func() {
+		reads <- st.Keys()
+	}()
+	select {
+	case keys := <-reads:
+		t.Fatalf("expected reads to wait for the batch, got keys %q", keys)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(pause.resumed)
+	if err := <-committed; err != nil {
+		t.Fatal(err)
+	}
+	keys := <-reads
+	slices.SortFunc(keys, bytes.Compare)
+	if len(keys) != 2 || string(keys[0]) != "first" || string(keys[1]) != "second" {
+		t.Errorf("expected the whole batch to be applied, got keys %q", keys)
+	}
+}
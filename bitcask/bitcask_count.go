@@ -0,0 +1,86 @@
+package bitcask
+
+import (
+	"bytes"
+	"io/fs"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.Counter = (*Store)(nil)
+
+// CountPrefix returns the number of keys with the given prefix. It is answered from bitcask's in-memory index,
+// without reading any values.
+func (s *Store) CountPrefix(prefix string) (int, error) {
+	if s.closed.Load() {
+		return 0, fs.ErrClosed
+	}
+	s.batches.RLock()
+	defer s.batches.RUnlock()
+	var keys [][]byte
+	err := s.Bitcask.Scan([]byte(prefix), func(key []byte) error {
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// writes hold the expiry tracker's lock while taking bitcask's, so expiry is only checked once Scan has
+	// released bitcask's lock.
+	return len(s.ttl.Live(keys)), nil
+}
+
+// each calls fn with every live key/value pair. Keys that are deleted or expire while it runs are skipped.
+func (s *Store) each(fn func(key, value []byte)) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	for _, key := range s.Keys() {
+		value, err := s.Get(key)
+		if kv.IsNonExistentKey(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		fn(key, value)
+	}
+	return nil
+}
+
+// CountMatches returns the number of values that contain query. Every value is read, as with Search,
+// but none are kept.
+func (s *Store) CountMatches(query string) (int, error) {
+	n := 0
+	err := s.each(func(_, value []byte) {
+		if bytes.Contains(value, []byte(query)) {
+			n++
+		}
+	})
+	return n, err
+}
+
+// SizeStats summarizes the sizes of the store's keys and values. It is answered from bitcask's in-memory index,
+// which records the size of every value, without reading any values.
+func (s *Store) SizeStats() (database.SizeStats, error) {
+	var stats database.SizeStats
+	if s.closed.Load() {
+		return stats, fs.ErrClosed
+	}
+	s.batches.RLock()
+	keys, sizes, ok := valueSizes(s.Bitcask)
+	s.batches.RUnlock()
+	if !ok {
+		err := s.each(func(key, value []byte) {
+			stats.Add(len(key), len(value))
+		})
+		return stats, err
+	}
+	for i, key := range keys {
+		if !s.ttl.Expired(key) {
+			stats.Add(len(key), sizes[i])
+		}
+	}
+	return stats, nil
+}
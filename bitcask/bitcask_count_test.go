@@ -0,0 +1,125 @@
+package bitcask
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_Count(t *testing.T) {
+	db := setupTest("count", t)
+	st := db.With("count").(*Store)
+	for key, value := range map[string]string{
+		"user/1":  "alice",
+		"user/2":  "bob",
+		"user/10": strings.Repeat("x", 300),
+		"order/1": "a",
+	} {
+		if err := st.Put([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for prefix, want := range map[string]int{"user/": 3, "user/1": 2, "order/": 1, "": 4, "nope": 0} {
+		if n, err := st.CountPrefix(prefix); err != nil || n != want {
+			t.Errorf("CountPrefix(%q): expected %d, got %d (%v)", prefix, want, n, err)
+		}
+	}
+	for query, want := range map[string]int{"b": 1, "xx": 1, "a": 2, "zzz": 0} {
+		if n, err := st.CountMatches(query); err != nil || n != want {
+			t.Errorf("CountMatches(%q): expected %d, got %d (%v)", query, want, n, err)
+		}
+	}
+
+	if _, _, ok := valueSizes(st.Bitcask); !ok {
+		t.Error("expected the sizes of values to be read from bitcask's index")
+	}
+	stats, err := st.SizeStats()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stats.Keys != 4 || stats.KeyBytes != 6+6+7+7 || stats.ValueBytes != 5+3+300+1 {
+		t.Errorf("expected sizes of 4 keys, got %+v", stats)
+	}
+	// 1 byte, 3 and 5 bytes, then 300 bytes in the 256-511 bucket.
+	if want := []int{0, 1, 1, 1, 0, 0, 0, 0, 0, 1}; !slices.Equal(stats.Histogram, want) {
+		t.Errorf("expected histogram %v, got %v", want, stats.Histogram)
+	}
+
+	if err = db.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.CountPrefix(""); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+	if _, err = st.SizeStats(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+	// the sizes of values are read from the index that bitcask saved when it was closed.
+	reopened, err := db.WithNew("count").(*Store).SizeStats()
+	if err != nil || reopened.Keys != stats.Keys || reopened.ValueBytes != stats.ValueBytes {
+		t.Errorf("expected %+v after reopening, got %+v (%v)", stats, reopened, err)
+	}
+}
+
+func TestStore_CountWhileWriting(t *testing.T) {
+	db := setupTest("count_writes", t)
+	st := db.With("count_writes").(*Store)
+	// enough keys that writes start while a count is walking the index.
+	const n = 20000
+	for i := 0; i < n; i++ {
+		if err := st.Bitcask.Put([]byte(fmt.Sprintf("user/%d", i)), []byte("yeet")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writes, counts := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(writes)
+		for i := 0; i < 200; i++ {
+			key := []byte(fmt.Sprintf("user/%d", i))
+			if err := st.PutWithTTL(key, []byte("yeet"), time.Hour); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := st.Put(key, []byte("yeets")); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(counts)
+		for {
+			select {
+			case <-writes:
+				return
+			default:
+			}
+			if _, err := st.CountPrefix("user/"); err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := st.SizeStats(); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+	select {
+	case <-counts:
+	case <-time.After(30 * time.Second):
+		t.Fatal("counting while writing deadlocked")
+	}
+
+	if count, err := st.CountPrefix("user/"); err != nil || count != n {
+		t.Errorf("expected %d keys, got %d (%v)", n, count, err)
+	}
+	if stats, err := st.SizeStats(); err != nil || stats.Keys != n || stats.ValueBytes != 200*5+(n-200)*4 {
+		t.Errorf("expected the sizes of %d values, got %+v (%v)", n, stats, err)
+	}
+}
@@ -0,0 +1,53 @@
+package bitcask
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"git.mills.io/prologic/bitcask"
+	art "github.com/plar/go-adaptive-radix-tree"
+)
+
+// entryOverhead is the number of bytes bitcask writes around the key and value of each entry in its data files:
+// the lengths of both, a checksum and an expiry time.
+const entryOverhead = 4 + 8 + 4 + 8
+
+var (
+	mutexType = reflect.TypeOf(sync.RWMutex{})
+	treeType  = reflect.TypeOf((*art.Tree)(nil)).Elem()
+)
+
+// valueSizes returns every key in bitcask's in-memory index, its keydir, along with the size of its value,
+// without reading any values. Bitcask doesn't export its keydir, so it is reached by reflection, and ok is
+// false if a different version of bitcask keeps it in a different shape.
+func valueSizes(b *bitcask.Bitcask) (keys [][]byte, sizes []int, ok bool) {
+	v := reflect.ValueOf(b).Elem()
+	mu, trie := v.FieldByName("mu"), v.FieldByName("trie")
+	if !mu.IsValid() || mu.Type() != mutexType || !trie.IsValid() || trie.Type() != treeType {
+		return nil, nil, false
+	}
+	lock := (*sync.RWMutex)(unsafe.Pointer(mu.UnsafeAddr()))
+	lock.RLock()
+	defer lock.RUnlock()
+	// bitcask replaces its keydir when merging, so it is only read while holding bitcask's lock.
+	keydir := *(*art.Tree)(unsafe.Pointer(trie.UnsafeAddr()))
+	keys = make([][]byte, 0, keydir.Size())
+	sizes = make([]int, 0, keydir.Size())
+	ok = true
+	keydir.ForEach(func(node art.Node) bool {
+		// the keydir's items hold the size of the whole entry, not just of the value.
+		size := reflect.ValueOf(node.Value()).FieldByName("Size")
+		if !size.IsValid() || size.Kind() != reflect.Int64 {
+			ok = false
+			return false
+		}
+		keys = append(keys, node.Key())
+		sizes = append(sizes, int(size.Int())-entryOverhead-len(node.Key()))
+		return true
+	})
+	if !ok {
+		return nil, nil, false
+	}
+	return keys, sizes, true
+}
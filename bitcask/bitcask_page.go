@@ -0,0 +1,33 @@
+package bitcask
+
+import (
+	"bytes"
+	"io/fs"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.Pager = (*Store)(nil)
+
+// PrefixScanPage returns up to limit key/value pairs whose keys have the given prefix, in key order, starting
+// after cursor. The returned cursor resumes the scan and is empty once there are no more pairs.
+// Cursors stay valid when keys are added or removed between pages, see [database.Pager].
+// Each page is read from bitcask's ordered index starting after the cursor's key.
+func (s *Store) PrefixScanPage(prefix string, cursor string, limit int) ([]kv.KeyValue, string, error) {
+	if s.closed.Load() {
+		return nil, "", fs.ErrClosed
+	}
+	return iterator.Page(s.Range, []byte(prefix), iterator.PrefixEnd([]byte(prefix)), cursor, limit, nil)
+}
+
+// SearchPage is like PrefixScanPage, for the key/value pairs whose values contain query.
+func (s *Store) SearchPage(query string, cursor string, limit int) ([]kv.KeyValue, string, error) {
+	if s.closed.Load() {
+		return nil, "", fs.ErrClosed
+	}
+	return iterator.Page(s.Range, nil, nil, cursor, limit, func(_, value []byte) bool {
+		return bytes.Contains(value, []byte(query))
+	})
+}
@@ -0,0 +1,126 @@
+package bitcask
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+func pageKeys(t *testing.T, page []kv.KeyValue, err error) []string {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	keys := make([]string, 0, len(page))
+	for _, keyVal := range page {
+		keys = append(keys, keyVal.Key.String())
+	}
+	return keys
+}
+
+func itemKeys(from, to int) []string {
+	var keys []string
+	for i := from; i < to; i++ {
+		keys = append(keys, fmt.Sprintf("item/%02d", i))
+	}
+	return keys
+}
+
+func TestStore_Page(t *testing.T) {
+	db := setupTest("page", t)
+	st := db.With("page").(*Store)
+	for i := 0; i < 25; i++ {
+		parity := "odd"
+		if i%2 == 0 {
+			parity = "even"
+		}
+		if err := st.Put([]byte(fmt.Sprintf("item/%02d", i)), []byte(parity)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.Put([]byte("other"), []byte("even")); err != nil {
+		t.Fatal(err)
+	}
+
+	page, cursor, err := st.PrefixScanPage("item/", "", 10)
+	if got := pageKeys(t, page, err); !slices.Equal(got, itemKeys(0, 10)) || cursor == "" {
+		t.Fatalf("expected the first 10 items and a cursor, got %q (%q)", got, cursor)
+	}
+
+	// keys added before the cursor are skipped, those after it and removed ones are picked up.
+	for _, key := range []string{"item/05x", "item/09x"} {
+		if err = st.Put([]byte(key), []byte("new")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = st.Delete([]byte("item/10")); err != nil {
+		t.Fatal(err)
+	}
+	page, cursor, err = st.PrefixScanPage("item/", cursor, 10)
+	if got, want := pageKeys(t, page, err), append([]string{"item/09x"}, itemKeys(11, 20)...); !slices.Equal(got, want) || cursor == "" {
+		t.Fatalf("expected %q and a cursor, got %q (%q)", want, got, cursor)
+	}
+	page, cursor, err = st.PrefixScanPage("item/", cursor, 10)
+	if got := pageKeys(t, page, err); !slices.Equal(got, itemKeys(20, 25)) || cursor != "" {
+		t.Fatalf("expected the last 5 items and no cursor, got %q (%q)", got, cursor)
+	}
+
+	page, cursor, err = st.SearchPage("even", "", 7)
+	if got, want := pageKeys(t, page, err), []string{"item/00", "item/02", "item/04", "item/06", "item/08", "item/12", "item/14"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	page, cursor, err = st.SearchPage("even", cursor, 0)
+	if got, want := pageKeys(t, page, err), []string{"item/16", "item/18", "item/20", "item/22", "item/24", "other"}; !slices.Equal(got, want) || cursor != "" {
+		t.Fatalf("expected %q and no cursor, got %q (%q)", want, got, cursor)
+	}
+	if page[0].Value.String() != "even" {
+		t.Errorf("expected values to be returned, got %q", page[0].Value.String())
+	}
+
+	if _, _, err = st.PrefixScanPage("item/", "not a cursor", 10); !errors.Is(err, database.ErrBadCursor) {
+		t.Errorf("expected ErrBadCursor, got %v", err)
+	}
+	if err = db.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = st.SearchPage("even", "", 10); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+}
+
+func TestStore_PageThrough(t *testing.T) {
+	db := setupTest("page_through", t)
+	st := db.With("page_through").(*Store)
+	want := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("host/%03d", i)
+		if err := st.Put([]byte(key), []byte("up")); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, key)
+	}
+	for _, key := range []string{"hos", "host", "hosu"} {
+		if err := st.Put([]byte(key), []byte("other")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	cursor := ""
+	for pages := 0; pages == 0 || cursor != ""; pages++ {
+		if pages > 30 {
+			t.Fatal("expected paging to end")
+		}
+		page, next, err := st.PrefixScanPage("host/", cursor, 37)
+		got = append(got, pageKeys(t, page, err)...)
+		cursor = next
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected every key once, in order, got %d keys", len(got))
+	}
+}
@@ -0,0 +1,180 @@
+package bitcask
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"slices"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+	"github.com/tcp-direct/database/kv"
+)
+
+var errStopScan = errors.New("stop scan")
+
+// ascend returns up to n keys of bitcask's index from the key from onwards, in byte order, stopping before end.
+// from itself is only included if inclusive is true, and a nil from starts at the first key.
+//
+// Bitcask's index is a radix tree that can only be walked from the start of a prefix, so rather than walking
+// every key before from, the keys starting with from are walked, then those starting with each larger sibling
+// of its prefixes, shortest last.
+func (s *Store) ascend(from []byte, inclusive bool, end []byte, n int) ([][]byte, error) {
+	keys := make([][]byte, 0, n)
+	visit := func(key []byte) error {
+		if end != nil && bytes.Compare(key, end) >= 0 {
+			return errStopScan
+		}
+		if !inclusive && bytes.Equal(key, from) {
+			return nil
+		}
+		keys = append(keys, slices.Clone(key))
+		if len(keys) == n {
+			return errStopScan
+		}
+		return nil
+	}
+	// a nil prefix matches nothing in bitcask's index, while an empty one matches everything.
+	err := s.Bitcask.Scan(append([]byte{}, from...), visit)
+	for i := len(from) - 1; err == nil && i >= 0; i-- {
+		for c := int(from[i]) + 1; err == nil && c <= 0xff; c++ {
+			prefix := append(slices.Clone(from[:i]), byte(c))
+			if end != nil && bytes.Compare(prefix, end) >= 0 {
+				err = errStopScan
+				break
+			}
+			err = s.Bitcask.Scan(prefix, visit)
+		}
+	}
+	if errors.Is(err, errStopScan) {
+		err = nil
+	}
+	return keys, err
+}
+
+// descend returns up to n keys of bitcask's index before the key before, in reverse byte order, stopping
+// before start. A nil before starts at the last key.
+//
+// The keys starting with a prefix are read in byte order, so a prefix is only read at once if it doesn't
+// start more than the n keys needed. Otherwise it is split by the next byte, from the largest.
+func (s *Store) descend(before, start []byte, n int) ([][]byte, error) {
+	keys := make([][]byte, 0, n)
+	done := false
+	emit := func(key []byte) {
+		if start != nil && bytes.Compare(key, start) < 0 {
+			done = true
+			return
+		}
+		keys = append(keys, slices.Clone(key))
+		done = len(keys) == n
+	}
+	var subtree func(prefix []byte) error
+	subtree = func(prefix []byte) error {
+		// every key starting with prefix comes before start.
+		if start != nil && bytes.Compare(prefix, start) < 0 && !bytes.HasPrefix(start, prefix) {
+			done = true
+			return nil
+		}
+		need := n - len(keys)
+		found := make([][]byte, 0, need)
+		err := s.Bitcask.Scan(prefix, func(key []byte) error {
+			if len(found) == need {
+				return errStopScan
+			}
+			found = append(found, key)
+			return nil
+		})
+		if errors.Is(err, errStopScan) {
+			for c := 0xff; !done && c >= 0; c-- {
+				if err = subtree(append(slices.Clone(prefix), byte(c))); err != nil {
+					return err
+				}
+			}
+			// the prefix itself comes before every other key starting with it.
+			if !done && len(prefix) > 0 && s.Bitcask.Has(prefix) {
+				emit(prefix)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for i := len(found) - 1; !done && i >= 0; i-- {
+			emit(found[i])
+		}
+		return nil
+	}
+
+	if before == nil {
+		return keys, subtree([]byte{})
+	}
+	// the keys before the key before are those starting with a smaller sibling of one of its prefixes,
+	// longest first, each followed by that prefix itself.
+	for i := len(before) - 1; !done && i >= 0; i-- {
+		for c := int(before[i]) - 1; !done && c >= 0; c-- {
+			if err := subtree(append(slices.Clone(before[:i]), byte(c))); err != nil {
+				return keys, err
+			}
+		}
+		if !done && i > 0 && s.Bitcask.Has(before[:i]) {
+			emit(before[:i])
+		}
+	}
+	return keys, nil
+}
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Bitcask's in-memory index is ordered, so each page of results is read from it after the last key of the
+// previous page, and values are only read for the keys on the page.
+func (s *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if s.closed.Load() {
+		return iterator.Error(fs.ErrClosed)
+	}
+	return iterator.Paged(func(after []byte, n int) ([][]byte, [][]byte, error) {
+		keys := make([][]byte, 0, n)
+		values := make([][]byte, 0, n)
+		// keys that expire or are deleted before their values are read leave the page short, so more are read.
+		for len(keys) < n {
+			if s.closed.Load() {
+				return keys, values, fs.ErrClosed
+			}
+			var (
+				page [][]byte
+				err  error
+			)
+			want := n - len(keys)
+			s.batches.RLock()
+			switch {
+			case opts.Reverse && after == nil:
+				page, err = s.descend(end, start, want)
+			case opts.Reverse:
+				page, err = s.descend(after, start, want)
+			case after == nil:
+				page, err = s.ascend(start, true, end, want)
+			default:
+				page, err = s.ascend(after, false, end, want)
+			}
+			s.batches.RUnlock()
+			if err != nil || len(page) == 0 {
+				return keys, values, err
+			}
+			after = page[len(page)-1]
+			exhausted := len(page) < want
+			for _, key := range s.ttl.Live(page) {
+				value, getErr := s.Get(key)
+				if kv.IsNonExistentKey(getErr) {
+					continue
+				}
+				if getErr != nil {
+					return keys, values, getErr
+				}
+				keys = append(keys, key)
+				values = append(values, value)
+			}
+			if exhausted {
+				break
+			}
+		}
+		return keys, values, nil
+	}, opts.Limit)
+}
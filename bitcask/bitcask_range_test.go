@@ -0,0 +1,90 @@
+package bitcask
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tcp-direct/database"
+)
+
+func TestStore_Range(t *testing.T) {
+	db := setupTest("range", t)
+	st := db.With("range").(*Store)
+
+	// keys that are prefixes of each other, and bytes at both ends of the alphabet, across several pages.
+	rng := rand.New(rand.NewSource(1))
+	alphabet := []byte{0x00, 'a', 'b', 'c', 0xfe, 0xff}
+	unique := make(map[string]struct{})
+	for len(unique) < 1500 {
+		key := make([]byte, 1+rng.Intn(4))
+		for i := range key {
+			key[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		unique[string(key)] = struct{}{}
+	}
+	keys := make([]string, 0, len(unique))
+	for key := range unique {
+		if err := st.Put([]byte(key), []byte("v"+key)); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	expired := keys[rng.Intn(len(keys))]
+	if err := st.PutWithTTL([]byte(expired), []byte("gone"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	keys = slices.DeleteFunc(keys, func(key string) bool {
+		return key == expired
+	})
+
+	bounds := [][]byte{nil, {0x00}, []byte("a"), []byte("ab"), []byte("b\xff"), []byte("c\x00c"), {0xff}, {0xff, 0xff, 0xff, 0xff, 0xff}}
+	for _, start := range bounds {
+		for _, end := range bounds {
+			for _, opts := range []database.RangeOptions{{}, {Reverse: true}, {Limit: 300}, {Reverse: true, Limit: 7}} {
+				var want []string
+				for _, key := range keys {
+					if (start == nil || key >= string(start)) && (end == nil || key < string(end)) {
+						want = append(want, key)
+					}
+				}
+				if opts.Reverse {
+					slices.Reverse(want)
+				}
+				if opts.Limit > 0 && len(want) > opts.Limit {
+					want = want[:opts.Limit]
+				}
+
+				it := st.Range(start, end, opts)
+				var got []string
+				for it.Next() {
+					if !bytes.Equal(it.Value(), []byte("v"+string(it.Key()))) {
+						t.Fatalf("%q: expected its own value, got %q", it.Key(), it.Value())
+					}
+					got = append(got, string(it.Key()))
+				}
+				if err := it.Err(); err != nil {
+					t.Fatal(err)
+				}
+				_ = it.Close()
+				if !slices.Equal(got, want) {
+					t.Errorf("Range(%q, %q, %+v): expected %d keys, got %d%s", start, end, opts, len(want), len(got), firstDiff(want, got))
+				}
+			}
+		}
+	}
+}
+
+func firstDiff(want, got []string) string {
+	for i := range min(len(want), len(got)) {
+		if want[i] != got[i] {
+			return fmt.Sprintf(", first difference at %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	return ""
+}
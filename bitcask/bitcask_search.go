@@ -1,6 +1,7 @@
 package bitcask
 
 import (
+	"context"
 	"strings"
 
 	"github.com/tcp-direct/database/kv"
@@ -9,7 +10,25 @@ import (
 // Search will search for a given string within all values inside of a Store.
 // Note, type casting will be necessary. (e.g: []byte or string)
 func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
-	var errChan = make(chan error)
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+// At most one error is sent to the error channel, so it never has to be read for the search to finish.
+func (s *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	return s.scan(ctx, anyKey, func(_, value []byte) bool {
+		return strings.Contains(string(value), query)
+	})
+}
+
+func anyKey([]byte) bool { return true }
+
+func anyValue(_, _ []byte) bool { return true }
+
+// scan reads the value of every key accepted by wantKey and sends the pairs accepted by match to the returned channel,
+// stopping once ctx is done. Keys that are deleted or expire while the scan is running are skipped.
+func (s *Store) scan(ctx context.Context, wantKey func(key []byte) bool, match func(key, value []byte) bool) (<-chan kv.KeyValue, chan error) {
+	var errChan = make(chan error, 1)
 	var resChan = make(chan kv.KeyValue, 5)
 	go func() {
 		defer func() {
@@ -17,14 +36,27 @@ func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
 			close(errChan)
 		}()
 		for _, key := range s.Keys() {
+			if ctx.Err() != nil {
+				errChan <- ctx.Err()
+				return
+			}
+			if !wantKey(key) {
+				continue
+			}
 			raw, err := s.Get(key)
+			if kv.IsNonExistentKey(err) {
+				continue
+			}
 			if err != nil {
 				errChan <- err
+				return
+			}
+			if raw == nil || !match(key, raw) {
 				continue
 			}
-			if raw != nil && strings.Contains(string(raw), query) {
-				keyVal := kv.NewKeyValue(kv.NewKey(key), kv.NewValue(raw))
-				resChan <- keyVal
+			if !kv.Send(ctx, resChan, kv.NewKeyValue(kv.NewKey(key), kv.NewValue(raw))) {
+				errChan <- ctx.Err()
+				return
 			}
 		}
 	}()
@@ -50,34 +82,55 @@ func (s *Store) ValueExists(value []byte) (key []byte, ok bool) {
 // PrefixScan will scan a Store for all keys that have a matching prefix of the given string
 // and return a map of keys and values. (map[Key]Value)
 func (s *Store) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
-	errChan := make(chan error)
+	return s.PrefixScanContext(context.Background(), prefix)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+// At most one error is sent to the error channel, so it never has to be read for the scan to finish.
+func (s *Store) PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
 	resChan := make(chan kv.KeyValue, 5)
 	go func() {
-		var err error
-		defer func(e error) {
-			if e != nil {
-				errChan <- e
-			}
+		defer func() {
 			close(resChan)
 			close(errChan)
-		}(err)
-		err = s.Scan([]byte(prefix), func(key []byte) error {
-			raw, _ := s.Get(key)
-			if key != nil && raw != nil {
-				k := kv.NewKey(key)
-				resChan <- kv.NewKeyValue(k, kv.NewValue(raw))
+		}()
+		// bitcask holds its lock for the duration of Scan, so values are read after the keys are collected.
+		var keys [][]byte
+		s.batches.RLock()
+		err := s.Scan([]byte(prefix), func(key []byte) error {
+			if err := ctx.Err(); err != nil {
+				return err
 			}
+			keys = append(keys, key)
 			return nil
 		})
+		s.batches.RUnlock()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, key := range keys {
+			raw, _ := s.Get(key)
+			if key == nil || raw == nil {
+				continue
+			}
+			if !kv.Send(ctx, resChan, kv.NewKeyValue(kv.NewKey(key), kv.NewValue(raw))) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
 	}()
 	return resChan, errChan
 }
 
 // Keys will return all keys in the database as a slice of byte slices.
 func (s *Store) Keys() (keys [][]byte) {
+	s.batches.RLock()
+	defer s.batches.RUnlock()
 	allkeys := s.Bitcask.Keys()
 	for key := range allkeys {
 		keys = append(keys, key)
 	}
-	return
+	return s.ttl.Live(keys)
 }
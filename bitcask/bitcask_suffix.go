@@ -0,0 +1,39 @@
+package bitcask
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// SuffixScan will stream every key/value pair whose key ends with suffix.
+// Keys are matched against bitcask's in-memory index, values are only read for the keys that match.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(suffix)
+	return s.scan(ctx, func(key []byte) bool {
+		return bytes.HasSuffix(key, needle)
+	}, anyValue)
+}
+
+// KeyContains will stream every key/value pair whose key contains substr.
+// Values are only read for the keys that match.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return s.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (s *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(substr)
+	return s.scan(ctx, func(key []byte) bool {
+		return bytes.Contains(key, needle)
+	}, anyValue)
+}
@@ -0,0 +1,159 @@
+package bitcask
+
+import (
+	"io/fs"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"git.mills.io/prologic/bitcask"
+
+	"github.com/tcp-direct/database/expiry"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+)
+
+// sidecars are the files kept next to each store's directory, named after the store.
+// bitcask's Merge removes every file inside of a store's directory that isn't one of its own.
+var sidecars = []string{expiry.LogFile, journal.File}
+
+// sidecar returns the path of the named file kept next to the directory of the store at path.
+func sidecar(path string, name string) string {
+	return path + "." + name
+}
+
+// isSidecar returns true if the file name in a keeper's directory is one of the sidecars of a store.
+func isSidecar(name string) bool {
+	for _, suffix := range sidecars {
+		if len(name) > len(suffix)+1 && strings.HasSuffix(name, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// newStore wraps an open bitcask instance along with the batch journal and expiry tracker kept next to its directory.
+// Any batch that was interrupted the last time the store was open is rolled back first.
+func newStore(c *bitcask.Bitcask, path string) (*Store, error) {
+	jrnl := journal.New(sidecar(path, journal.File), rawStore{c})
+	if _, err := jrnl.Recover(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	tracker, err := expiry.Open(sidecar(path, expiry.LogFile))
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	aclosed := &atomic.Bool{}
+	aclosed.Store(false)
+	return &Store{Bitcask: c, closed: aclosed, ttl: tracker, journal: jrnl, batches: &sync.RWMutex{}}, nil
+}
+
+// expire lazily removes the key if it has expired, returning true if it had.
+func (s *Store) expire(key []byte) bool {
+	expired, _ := s.ttl.Expire(key, s.Bitcask.Delete)
+	return expired
+}
+
+// Put is a wrapper around the bitcask Put function that clears any expiration time for the key.
+func (s *Store) Put(key []byte, value []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	return s.ttl.Set(key, time.Time{}, func() error {
+		return s.Bitcask.Put(key, value)
+	})
+}
+
+// PutWithTTL inserts the value and expires the key once ttl has elapsed.
+// The expiration time is tracked alongside the store rather than with bitcask's own TTL support,
+// which doesn't hide expired keys from Keys, Len or Scan.
+func (s *Store) PutWithTTL(key []byte, value []byte, ttl time.Duration) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	at, err := expiry.Deadline(ttl)
+	if err != nil {
+		return err
+	}
+	return s.ttl.Set(key, at, func() error {
+		return s.Bitcask.Put(key, value)
+	})
+}
+
+// TTL returns the time remaining before the given key expires, or zero if it never expires.
+func (s *Store) TTL(key []byte) (time.Duration, error) {
+	if s.closed.Load() {
+		return 0, fs.ErrClosed
+	}
+	if !s.Has(key) {
+		return 0, kv.RegularizeKVError(key, nil, nil)
+	}
+	ttl, ok := s.ttl.TTL(key)
+	if ok && ttl <= 0 {
+		return 0, kv.RegularizeKVError(key, nil, nil)
+	}
+	return ttl, nil
+}
+
+// Delete is a wrapper around the bitcask Delete function that clears any expiration time for the key.
+func (s *Store) Delete(key []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	return s.ttl.Set(key, time.Time{}, func() error {
+		return s.Bitcask.Delete(key)
+	})
+}
+
+// Has returns true if the given key has an associated value that hasn't expired.
+func (s *Store) Has(key []byte) bool {
+	if s.closed.Load() {
+		return false
+	}
+	s.batches.RLock()
+	defer s.batches.RUnlock()
+	return !s.expire(key) && s.Bitcask.Has(key)
+}
+
+// Len returns the number of keys in the store that haven't expired.
+func (s *Store) Len() int {
+	s.batches.RLock()
+	defer s.batches.RUnlock()
+	n := s.Bitcask.Len()
+	for _, key := range s.ttl.Expiring() {
+		if s.Bitcask.Has(key) {
+			n--
+		}
+	}
+	return n
+}
+
+// sweep removes all expired keys from the store.
+func (s *Store) sweep() (int, error) {
+	if s.Bitcask == nil || s.ttl == nil || s.closed.Load() {
+		return 0, fs.ErrClosed
+	}
+	return s.ttl.Sweep(s.Bitcask.Delete)
+}
+
+// sweep removes expired keys from every open store, it is run in the background by the keeper's sweeper.
+func (db *DB) sweep() {
+	db.mu.RLock()
+	stores := make([]*Store, 0, len(db.store))
+	for _, st := range db.store {
+		stores = append(stores, st)
+	}
+	db.mu.RUnlock()
+	for _, st := range stores {
+		_, _ = st.sweep()
+	}
+}
+
+// SetSweepInterval changes how often expired keys are removed from all stores in the background.
+// Expired keys are hidden from reads regardless of when they are removed.
+func (db *DB) SetSweepInterval(interval time.Duration) {
+	db.sweeper.SetInterval(interval)
+}
@@ -0,0 +1,366 @@
+// Package bloom adds opt-in bloom filters to the stores of any [database.Keeper], so that lookups of keys
+// that aren't in a store are answered without reaching its backend.
+//
+// Has and Get on the Filers handed out by [Keeper] test the store's filter first, and only ask the
+// backend about keys the filter may contain. Keys are added to the filter before they are written, and never
+// removed, so deleting keys only makes the filter less selective. A filter that has had more keys added than
+// it was sized for is rebuilt at twice the size.
+//
+// Filters are sized for the false positive rate set in the Keeper's [metadata.Metadata.DefStoreOpts], see
+// [metadata.Metadata.WithBloomFalsePositiveRate], or [DefaultFalsePositiveRate]. Which stores have a filter is
+// recorded in the metadata as well. Each filter is persisted alongside its store, named after the store with
+// [Ext] appended, whenever the store is synced or closed. The file is removed by the first write after that, so a
+// process that dies without syncing leaves no file behind, and the filter is rebuilt from the store's keys when
+// the Keeper is next wrapped. [Keeper.Discover] always rebuilds filters, picking up writes made to the
+// Keeper's stores directly, which are not added to them.
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/models"
+)
+
+// Ext is appended to the name of a store to name the file its filter is persisted in.
+const Ext = ".bloom"
+
+var (
+	// ErrNoStore is returned when enabling a filter on a store that isn't open.
+	ErrNoStore = wrap.ErrNoStore
+	// ErrEnabled is returned when enabling a filter on a store that already has one.
+	ErrEnabled = errors.New("store already has a bloom filter")
+	// ErrNotEnabled is returned when disabling or rebuilding the filter of a store that doesn't have one.
+	ErrNotEnabled = errors.New("store does not have a bloom filter")
+	// ErrNoMetadata is returned when enabling a filter on a Keeper without [metadata.Metadata] to record it in.
+	ErrNoMetadata = wrap.ErrNoMetadata
+	// ErrCorrupt is returned when a persisted filter can't be decoded.
+	ErrCorrupt = errors.New("corrupt bloom filter")
+)
+
+// Keeper wraps a [database.Keeper], maintaining the bloom filters of its stores.
+// Filers returned by With, WithNew and AllStores are [Store]s. Discover rebuilds the filters recorded in the
+// metadata from the stores' keys, and Destroy removes a store's filter along with it.
+type Keeper struct {
+	wrap.Keeper
+	// mu guards states and changes to the filters recorded in the Keeper's metadata.
+	mu     sync.Mutex
+	states map[string]*state
+}
+
+// state is the filter of a single store. Writes to the store hold mu shared while adding to the filter,
+// rebuilding and persisting the filter hold it exclusively. Lookups only load the filter.
+type state struct {
+	mu     sync.RWMutex
+	filter atomic.Pointer[Filter]
+	path   string
+	// persisted is true while the file at path matches the filter.
+	persisted atomic.Bool
+}
+
+// Wrap adds bloom filters to the given Keeper, loading the filters recorded in its metadata for the stores
+// that are already open, or rebuilding those that weren't persisted.
+func Wrap(keeper database.Keeper) (*Keeper, error) {
+	k := &Keeper{states: make(map[string]*state)}
+	k.Keeper = wrap.NewKeeper(keeper, wrap.Hooks{Wrap: k.wrap, Attach: k.reattach, Forget: k.forget})
+	if err := k.attach(false); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func (k *Keeper) rate() float64 {
+	if meta, err := wrap.Metadata(k.Unwrap()); err == nil {
+		if rate := meta.BloomFalsePositiveRate(); rate > 0 {
+			return rate
+		}
+	}
+	return DefaultFalsePositiveRate
+}
+
+// state returns the filter state of the given store, caller must hold k.mu.
+func (k *Keeper) state(store string) *state {
+	st, ok := k.states[store]
+	if !ok {
+		st = &state{path: k.filterPath(store)}
+		k.states[store] = st
+	}
+	return st
+}
+
+// filterPath returns where the filter of the given store is persisted, or an empty path for in-memory stores.
+func (k *Keeper) filterPath(store string) string {
+	if database.IsInMemory(k.Unwrap()) {
+		return ""
+	}
+	return filepath.Join(k.Unwrap().Path(), store+Ext)
+}
+
+func (k *Keeper) wrap(name string, f database.Filer) database.Filer {
+	k.mu.Lock()
+	st := k.state(name)
+	k.mu.Unlock()
+	return &Store{Filer: wrap.Filer{Filer: f}, keeper: k, state: st}
+}
+
+// attach sets up the filters recorded in the Keeper's metadata for every open store. Persisted filters
+// are loaded unless rebuild is true, every other filter is built from its store's keys.
+func (k *Keeper) attach(rebuild bool) error {
+	meta, ok := wrap.Recorded(k.Unwrap())
+	if !ok {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var errs []error
+	for _, store := range meta.BloomFilters {
+		data := k.Unwrap().With(store)
+		if data == nil {
+			continue
+		}
+		st := k.state(store)
+		st.mu.Lock()
+		if rebuild || !st.load() {
+			if err := st.build(data, 0, k.rate()); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", store, err))
+			}
+		}
+		st.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// load loads the persisted filter, returning false if there is none. Caller must hold st.mu.
+func (st *state) load() bool {
+	if st.path == "" {
+		return false
+	}
+	data, err := os.ReadFile(st.path)
+	if err != nil {
+		return false
+	}
+	f := &Filter{}
+	if err = f.UnmarshalBinary(data); err != nil {
+		return false
+	}
+	st.filter.Store(f)
+	st.persisted.Store(true)
+	return true
+}
+
+// build replaces the filter with one holding every key in data, sized for at least capacity keys.
+// Caller must hold st.mu.
+func (st *state) build(data database.Filer, capacity int, rate float64) error {
+	keys := data.Keys()
+	f := NewFilter(max(capacity, 2*len(keys)), rate)
+	for _, key := range keys {
+		f.Add(key)
+	}
+	st.filter.Store(f)
+	return st.unpersist()
+}
+
+// unpersist removes the persisted filter, which no longer matches the filter in memory.
+func (st *state) unpersist() error {
+	if st.path == "" || !st.persisted.Swap(false) {
+		return nil
+	}
+	if err := os.Remove(st.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// persist writes the filter to its file, unless it's already there. Caller must hold st.mu.
+func (st *state) persist() error {
+	f := st.filter.Load()
+	if st.path == "" || f == nil || st.persisted.Load() {
+		return nil
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, st.path); err != nil {
+		return err
+	}
+	st.persisted.Store(true)
+	return nil
+}
+
+// Enable adds a bloom filter to the given store, adding its existing keys, and records it in the Keeper's metadata.
+func (k *Keeper) Enable(store string) error {
+	meta, err := wrap.Metadata(k.Unwrap())
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data := k.Unwrap().With(store)
+	if data == nil {
+		return fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.filter.Load() != nil {
+		return fmt.Errorf("%w: %s", ErrEnabled, store)
+	}
+	if err = st.build(data, 0, k.rate()); err != nil {
+		return err
+	}
+	meta.AddBloomFilter(store)
+	return k.Unwrap().SyncAll()
+}
+
+// Disable removes the bloom filter of the given store, along with its persisted file.
+func (k *Keeper) Disable(store string) error {
+	meta, err := wrap.Metadata(k.Unwrap())
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.filter.Load() == nil {
+		return fmt.Errorf("%w: %s", ErrNotEnabled, store)
+	}
+	st.filter.Store(nil)
+	if err = st.unpersist(); err != nil {
+		return err
+	}
+	meta.RemoveBloomFilter(store)
+	return k.Unwrap().SyncAll()
+}
+
+// Rebuild discards the bloom filter of the given store and adds the store's keys to a new one.
+func (k *Keeper) Rebuild(store string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data := k.Unwrap().With(store)
+	if data == nil {
+		return fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.filter.Load() == nil {
+		return fmt.Errorf("%w: %s", ErrNotEnabled, store)
+	}
+	return st.build(data, 0, k.rate())
+}
+
+// persistAll persists the filter of every store that has one.
+func (k *Keeper) persistAll() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var errs []error
+	for name, st := range k.states {
+		st.mu.Lock()
+		if err := st.persist(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		st.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// SyncAll syncs all stores of the underlying Keeper, then persists their filters.
+func (k *Keeper) SyncAll() error {
+	if err := k.Unwrap().SyncAll(); err != nil {
+		return err
+	}
+	return k.persistAll()
+}
+
+// Close persists the filter of the given store, then closes it.
+func (k *Keeper) Close(name string) error {
+	k.mu.Lock()
+	st, ok := k.states[name]
+	k.mu.Unlock()
+	var err error
+	if ok {
+		st.mu.Lock()
+		err = st.persist()
+		st.mu.Unlock()
+	}
+	return errors.Join(err, k.Unwrap().Close(name))
+}
+
+// CloseAll persists the filters of all stores, then closes the underlying Keeper's stores.
+func (k *Keeper) CloseAll() error {
+	return errors.Join(k.persistAll(), k.Unwrap().CloseAll())
+}
+
+// SyncAndCloseAll syncs and closes all stores of the underlying Keeper, persisting their filters.
+func (k *Keeper) SyncAndCloseAll() error {
+	return errors.Join(k.persistAll(), k.Unwrap().SyncAndCloseAll())
+}
+
+// BackupAll persists the filters of all stores, then backs up the underlying Keeper.
+func (k *Keeper) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	if err := k.persistAll(); err != nil {
+		return nil, err
+	}
+	return k.Unwrap().BackupAll(archivePath, opts...)
+}
+
+// RestoreAll restores all stores of the underlying Keeper, then loads or rebuilds the filters recorded in the
+// restored metadata.
+func (k *Keeper) RestoreAll(archivePath string, opts ...backup.Option) error {
+	k.mu.Lock()
+	for _, st := range k.states {
+		st.mu.Lock()
+		st.filter.Store(nil)
+		st.persisted.Store(false)
+		st.mu.Unlock()
+	}
+	k.mu.Unlock()
+	if err := k.Unwrap().RestoreAll(archivePath, opts...); err != nil {
+		return err
+	}
+	return k.attach(false)
+}
+
+// reattach rebuilds the filters recorded in the Keeper's metadata from the stores' keys, once Discover has
+// discovered the underlying Keeper's stores.
+func (k *Keeper) reattach() error {
+	return k.attach(true)
+}
+
+// forget removes the filter of a destroyed store.
+func (k *Keeper) forget(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	st, ok := k.states[name]
+	if !ok {
+		return nil
+	}
+	delete(k.states, name)
+	st.mu.Lock()
+	enabled := st.filter.Swap(nil) != nil
+	err := st.unpersist()
+	st.mu.Unlock()
+	if !enabled {
+		return err
+	}
+	errs := []error{err}
+	if meta, metaErr := wrap.Metadata(k.Unwrap()); metaErr == nil {
+		meta.RemoveBloomFilter(name)
+		errs = append(errs, k.Unwrap().SyncAll())
+	}
+	return errors.Join(errs...)
+}
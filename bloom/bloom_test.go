@@ -0,0 +1,230 @@
+package bloom
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/leveldb"
+	"github.com/tcp-direct/database/memory"
+	"github.com/tcp-direct/database/metadata"
+)
+
+func TestFilter(t *testing.T) {
+	f := NewFilter(10000, 0.01)
+	for i := 0; i < 10000; i++ {
+		f.Add([]byte(fmt.Sprintf("key%d", i)))
+	}
+	for i := 0; i < 10000; i++ {
+		if !f.MayContain([]byte(fmt.Sprintf("key%d", i))) {
+			t.Fatalf("expected key%d to be in the filter", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.MayContain([]byte(fmt.Sprintf("other%d", i))) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 200 {
+		t.Errorf("expected about 1%% false positives, got %d in 10000", falsePositives)
+	}
+	if f.Full() {
+		t.Error("expected the filter not to be full yet")
+	}
+	f.Add([]byte("one too many"))
+	if !f.Full() {
+		t.Error("expected the filter to be full")
+	}
+
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Filter{}
+	if err = decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if decoded.Count() != f.Count() || !decoded.MayContain([]byte("key42")) || decoded.MayContain([]byte("other0")) != f.MayContain([]byte("other0")) {
+		t.Error("expected the decoded filter to match the original")
+	}
+	if err = decoded.UnmarshalBinary(data[:len(data)-1]); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+// counting counts the lookups that reach the backend.
+type counting struct {
+	database.Filer
+	lookups int
+}
+
+func (c *counting) Has(key []byte) bool {
+	c.lookups++
+	return c.Filer.Has(key)
+}
+
+func (c *counting) Get(key []byte) ([]byte, error) {
+	c.lookups++
+	return c.Filer.Get(key)
+}
+
+func TestKeeper_Enable(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("seen")
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := k.With("seen").(*Store)
+	if err = st.Put([]byte("before"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if st.Filter() != nil {
+		t.Error("expected no filter before Enable")
+	}
+	if err = k.Enable("seen"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = k.Enable("seen"); !errors.Is(err, ErrEnabled) {
+		t.Errorf("expected ErrEnabled, got %v", err)
+	}
+	if err = k.Enable("nope"); !errors.Is(err, ErrNoStore) {
+		t.Errorf("expected ErrNoStore, got %v", err)
+	}
+	if err = st.Put([]byte("after"), []byte("2")); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &counting{Filer: st.Unwrap()}
+	st.Filer.Filer = backend
+	for _, key := range []string{"before", "after"} {
+		if !st.Has([]byte(key)) {
+			t.Errorf("expected %s to be found", key)
+		}
+	}
+	misses := 0
+	for i := 0; i < 1000; i++ {
+		if st.Has([]byte(fmt.Sprintf("missing%d", i))) {
+			t.Fatalf("expected missing%d not to be found", i)
+		}
+		if _, err = st.Get([]byte(fmt.Sprintf("missing%d", i))); !kv.IsNonExistentKey(err) {
+			t.Fatalf("expected a NonExistentKeyError, got %v", err)
+		}
+		misses += 2
+	}
+	if backend.lookups > 2+misses/20 {
+		t.Errorf("expected most missing keys to be answered by the filter, %d of %d reached the backend", backend.lookups-2, misses)
+	}
+
+	if err = k.Disable("seen"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = k.Disable("seen"); !errors.Is(err, ErrNotEnabled) {
+		t.Errorf("expected ErrNotEnabled, got %v", err)
+	}
+	backend.lookups = 0
+	st.Has([]byte("missing"))
+	if backend.lookups != 1 {
+		t.Error("expected lookups to reach the backend without a filter")
+	}
+}
+
+func TestStore_Grow(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("seen")
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Enable("seen"); err != nil {
+		t.Fatal(err)
+	}
+	st := k.With("seen").(*Store)
+	first := st.Filter()
+	for i := 0; i < 3000; i++ {
+		if err = st.Put([]byte(fmt.Sprintf("key%d", i)), []byte("1")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st.Filter() == first || st.Filter().Full() {
+		t.Error("expected the full filter to be rebuilt larger")
+	}
+	for i := 0; i < 3000; i++ {
+		if !st.Has([]byte(fmt.Sprintf("key%d", i))) {
+			t.Fatalf("expected key%d to be found after growing", i)
+		}
+	}
+}
+
+func TestKeeper_Persist(t *testing.T) {
+	path := t.TempDir()
+	inner := leveldb.OpenDB(path)
+	inner.WithNew("seen")
+	k, err := Wrap(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	meta, err := metadata.CastToMetadata(k.Meta())
+	if err != nil || meta == nil {
+		t.Fatalf("expected metadata, got %v", err)
+	}
+	meta.WithBloomFalsePositiveRate(0.001)
+	if err = k.Enable("seen"); err != nil {
+		t.Fatal(err)
+	}
+	if rate := k.With("seen").(*Store).Filter().rate; rate != 0.001 {
+		t.Errorf("expected the rate from the metadata, got %v", rate)
+	}
+	if err = k.With("seen").Put([]byte("synced"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(path, "seen"+Ext)
+	if err = k.SyncAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(file); err != nil {
+		t.Fatalf("expected the filter to be persisted, got %v", err)
+	}
+	if err = k.With("seen").Put([]byte("unsynced"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a write to remove the persisted filter, got %v", err)
+	}
+	if err = k.SyncAndCloseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	inner = leveldb.OpenDB(path)
+	if _, err = inner.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	// written around the filter, so only a rebuild finds it.
+	if err = inner.With("seen").Put([]byte("direct"), []byte("1")); err != nil {
+		t.Fatal(err)
+	}
+	if k, err = Wrap(inner); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = k.CloseAll()
+	})
+	st := k.With("seen").(*Store)
+	if st.Filter() == nil || !st.Has([]byte("synced")) || !st.Has([]byte("unsynced")) {
+		t.Fatal("expected the persisted filter to be loaded")
+	}
+	if st.Has([]byte("direct")) {
+		t.Error("expected a key written around the loaded filter not to be found")
+	}
+	if _, err = k.Discover(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !st.Has([]byte("direct")) {
+		t.Error("expected Discover to rebuild the filter")
+	}
+}
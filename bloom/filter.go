@@ -0,0 +1,122 @@
+package bloom
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync/atomic"
+)
+
+const (
+	// DefaultFalsePositiveRate is used when the Keeper's metadata doesn't set a false positive rate.
+	DefaultFalsePositiveRate = 0.01
+	// minCapacity keeps filters of empty or tiny stores from having to grow right away.
+	minCapacity = 1024
+	// header is the magic and version that encoded filters start with.
+	header = "bloom\x01"
+)
+
+// Filter is a bloom filter over keys. It is safe for concurrent use, keys may be added while others are tested.
+type Filter struct {
+	bits     []atomic.Uint64
+	m        uint64
+	k        uint64
+	capacity uint64
+	rate     float64
+	count    atomic.Uint64
+}
+
+// NewFilter returns an empty Filter sized so that testing keys that weren't added returns true with
+// probability rate, until more than capacity keys have been added.
+func NewFilter(capacity int, rate float64) *Filter {
+	if rate <= 0 || rate >= 1 {
+		rate = DefaultFalsePositiveRate
+	}
+	n := uint64(max(capacity, minCapacity))
+	m := uint64(math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := uint64(max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &Filter{bits: make([]atomic.Uint64, m/64), m: m, k: k, capacity: n, rate: rate}
+}
+
+// hashes derives the two hashes that every bit of a key is computed from.
+func hashes(key []byte) (uint64, uint64) {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+	h1 := h.Sum64()
+	// splitmix64 finalizer, so that the second hash isn't correlated with the first.
+	h2 := h1 + 0x9e3779b97f4a7c15
+	h2 = (h2 ^ (h2 >> 30)) * 0xbf58476d1ce4e5b9
+	h2 = (h2 ^ (h2 >> 27)) * 0x94d049bb133111eb
+	h2 ^= h2 >> 31
+	return h1, h2 | 1
+}
+
+// Add adds key to the filter.
+func (f *Filter) Add(key []byte) {
+	h1, h2 := hashes(key)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64].Or(1 << (bit % 64))
+	}
+	f.count.Add(1)
+}
+
+// MayContain returns false if key was never added to the filter, and true if it probably was.
+func (f *Filter) MayContain(key []byte) bool {
+	h1, h2 := hashes(key)
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64].Load()&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Count returns the number of times Add was called, which counts keys added more than once as often as they were.
+func (f *Filter) Count() int {
+	return int(f.count.Load())
+}
+
+// Full returns true once more keys have been added than the filter was sized for,
+// after which its false positive rate rises above the one it was created with.
+func (f *Filter) Full() bool {
+	return f.count.Load() > f.capacity
+}
+
+// MarshalBinary encodes the filter.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, len(header)+8*5+len(f.bits)*8)
+	buf = append(buf, header...)
+	buf = binary.LittleEndian.AppendUint64(buf, f.m)
+	buf = binary.LittleEndian.AppendUint64(buf, f.k)
+	buf = binary.LittleEndian.AppendUint64(buf, f.capacity)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(f.rate))
+	buf = binary.LittleEndian.AppendUint64(buf, f.count.Load())
+	for i := range f.bits {
+		buf = binary.LittleEndian.AppendUint64(buf, f.bits[i].Load())
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a filter encoded by MarshalBinary, replacing the contents of f.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	if len(data) < len(header)+8*5 || string(data[:len(header)]) != header {
+		return fmt.Errorf("%w: bad header", ErrCorrupt)
+	}
+	data = data[len(header):]
+	word := func(i int) uint64 { return binary.LittleEndian.Uint64(data[i*8:]) }
+	m, k := word(0), word(1)
+	if m == 0 || m%64 != 0 || k == 0 || uint64(len(data)) != 8*5+m/8 {
+		return fmt.Errorf("%w: bad size", ErrCorrupt)
+	}
+	f.m, f.k, f.capacity, f.rate = m, k, word(2), math.Float64frombits(word(3))
+	f.count.Store(word(4))
+	f.bits = make([]atomic.Uint64, m/64)
+	for i := range f.bits {
+		f.bits[i].Store(word(5 + i))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package bloom
+
+import (
+	"errors"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.Store = (*Store)(nil)
+
+// Store wraps a Filer of a [Keeper], answering lookups of keys that aren't in the store from its bloom filter.
+// It implements [database.Searcher] by passing searches through to the underlying Filer.
+// Other backend specific methods are reached through [Store.Unwrap].
+type Store struct {
+	wrap.Filer
+	keeper *Keeper
+	state  *state
+}
+
+// Unwrap returns the underlying Filer. Keys written to it directly are not added to the filter.
+func (s *Store) Unwrap() database.Filer {
+	return s.Filer.Filer
+}
+
+// Filter returns the store's bloom filter, or nil if it doesn't have one.
+func (s *Store) Filter() *Filter {
+	return s.state.filter.Load()
+}
+
+// Has returns false without asking the underlying Filer if the filter doesn't contain key.
+func (s *Store) Has(key []byte) bool {
+	if f := s.state.filter.Load(); f != nil && !f.MayContain(key) {
+		return false
+	}
+	return s.Filer.Has(key)
+}
+
+// Get returns a [kv.NonExistentKeyError] without asking the underlying Filer if the filter doesn't contain key.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	if f := s.state.filter.Load(); f != nil && !f.MayContain(key) {
+		return nil, &kv.NonExistentKeyError{Key: key}
+	}
+	return s.Filer.Get(key)
+}
+
+// Put adds key to the filter, then inserts the value at key. A filter that is full afterwards is rebuilt
+// at twice its size.
+func (s *Store) Put(key []byte, value []byte) error {
+	s.state.mu.RLock()
+	f := s.state.filter.Load()
+	if f == nil {
+		s.state.mu.RUnlock()
+		return s.Filer.Put(key, value)
+	}
+	f.Add(key)
+	err := errors.Join(s.state.unpersist(), s.Filer.Put(key, value))
+	s.state.mu.RUnlock()
+	if err != nil || !f.Full() {
+		return err
+	}
+	return s.grow(f)
+}
+
+// grow rebuilds the full filter f at twice its size, unless another write already has.
+func (s *Store) grow(f *Filter) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if s.state.filter.Load() != f {
+		return nil
+	}
+	return s.state.build(s.Filer, 2*f.Count(), s.keeper.rate())
+}
+
+// Sync syncs the underlying Filer, then persists the filter.
+func (s *Store) Sync() error {
+	if err := s.Filer.Sync(); err != nil {
+		return err
+	}
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	return s.state.persist()
+}
+
+// Close persists the filter, then closes the underlying Filer.
+func (s *Store) Close() error {
+	s.state.mu.Lock()
+	err := s.state.persist()
+	s.state.mu.Unlock()
+	return errors.Join(err, s.Filer.Close())
+}
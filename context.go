@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/models"
+)
+
+// ContextFiler is a Filer whose operations can be cancelled with a context.
+type ContextFiler interface {
+	Filer
+	// GetContext should behave like Get, returning the context's error if it is done before the value is read.
+	GetContext(ctx context.Context, key []byte) ([]byte, error)
+	// PutContext should behave like Put, returning the context's error if it is done before the value is written.
+	PutContext(ctx context.Context, key []byte, value []byte) error
+}
+
+// ContextSearcher is a Searcher whose scans stop once a context is done.
+type ContextSearcher interface {
+	Searcher
+	// PrefixScanContext should behave like PrefixScan. Once ctx is done the scan should stop,
+	// send the context's error to the error channel and close both channels.
+	PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error)
+	// SearchContext should behave like Search. Once ctx is done the search should stop,
+	// send the context's error to the error channel and close both channels.
+	SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error)
+}
+
+// ContextKeeper is a Keeper whose long-running operations can be cancelled with a context.
+type ContextKeeper interface {
+	Keeper
+	// BackupAllContext should behave like BackupAll, writing the archive with opts, such as backup.WithBase for
+	// an incremental backup. Once ctx is done it should abandon the backup and remove any partially written archive.
+	BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error)
+}
@@ -0,0 +1,40 @@
+package database
+
+import "math/bits"
+
+// SizeStats summarizes the sizes of the keys and values in a store.
+type SizeStats struct {
+	// Keys is the number of key/value pairs.
+	Keys int
+	// KeyBytes is the total size of the keys.
+	KeyBytes int64
+	// ValueBytes is the total size of the values.
+	ValueBytes int64
+	// Histogram counts the values by size in powers of two: Histogram[0] counts empty values and Histogram[i]
+	// counts the values of 2^(i-1) up to 2^i-1 bytes. It is only as long as the largest value needs.
+	Histogram []int
+}
+
+// Add records a key/value pair of the given sizes.
+func (s *SizeStats) Add(keySize, valueSize int) {
+	s.Keys++
+	s.KeyBytes += int64(keySize)
+	s.ValueBytes += int64(valueSize)
+	bucket := bits.Len(uint(valueSize))
+	for len(s.Histogram) <= bucket {
+		s.Histogram = append(s.Histogram, 0)
+	}
+	s.Histogram[bucket]++
+}
+
+// Counter is a Filer that can count its keys and measure its contents without streaming them.
+type Counter interface {
+	Filer
+	// CountPrefix should return the number of keys with the given prefix.
+	CountPrefix(prefix string) (int, error)
+	// CountMatches should return the number of values that contain query, the pairs that Search would stream.
+	CountMatches(query string) (int, error)
+	// SizeStats should summarize the sizes of the store's keys and values. Stores that can't tell the size of
+	// a value without reading it should say so, as reading every value is too costly to do often.
+	SizeStats() (SizeStats, error)
+}
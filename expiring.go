@@ -0,0 +1,15 @@
+package database
+
+import "time"
+
+// ExpiringFiler is a Filer that can associate a time to live with a key.
+// Once a key has expired it should no longer be returned by any Filer or Searcher methods.
+type ExpiringFiler interface {
+	Filer
+	// PutWithTTL should insert the value like Put, but expire the key once ttl has elapsed.
+	// Calling Put on the same key afterwards should remove the expiration.
+	PutWithTTL(key []byte, value []byte, ttl time.Duration) error
+	// TTL should return the time remaining before the given key expires, or zero if it never expires.
+	// A [kv.NonExistentKeyError] should be returned if the key does not exist or has already expired.
+	TTL(key []byte) (time.Duration, error)
+}
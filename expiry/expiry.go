@@ -0,0 +1,390 @@
+// Package expiry keeps track of key expiration times for stores that don't support them natively.
+//
+// Each store gets a [Tracker] which records expiration times in an append-only log kept with the store,
+// so they survive restarts and are carried along with the store in backups.
+// Keepers own a [Sweeper] that periodically removes expired keys from all of their stores.
+package expiry
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogFile is the name of the file that a store's expiration times are recorded in.
+const LogFile = "expiry.log"
+
+const (
+	opClear byte = iota
+	opSet
+)
+
+// compactThreshold is the minimum number of records in the log before it is considered for compaction.
+const compactThreshold = 1024
+
+var (
+	// ErrBadTTL is returned when a time to live is not a positive duration.
+	ErrBadTTL = errors.New("ttl must be greater than zero")
+	// ErrBadLog is returned when the expiry log contains an unknown record type.
+	ErrBadLog = errors.New("malformed expiry log")
+)
+
+// Tracker records when keys in a single store expire.
+// The log file is only created once the first expiration time is set.
+type Tracker struct {
+	path    string
+	f       *os.File
+	expires map[string]time.Time
+	records int
+	closed  bool
+	mu      sync.RWMutex
+}
+
+// Open loads the expiry log at path, if it exists, and returns a Tracker that appends to it.
+// A partially written record at the end of the log is discarded.
+func Open(path string) (*Tracker, error) {
+	t := &Tracker{path: path, expires: make(map[string]time.Time)}
+	f, err := os.OpenFile(path, os.O_RDWR, 0600)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	good, err := t.load(bufio.NewReader(f))
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err = f.Truncate(good); err == nil {
+		_, err = f.Seek(good, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	t.f = f
+	return t, nil
+}
+
+// load replays the log, returning the offset of the end of the last complete record.
+func (t *Tracker) load(r *bufio.Reader) (int64, error) {
+	var good int64
+	for {
+		op, err := r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return good, nil
+		}
+		if err != nil {
+			return good, err
+		}
+		if op != opSet && op != opClear {
+			return good, fmt.Errorf("%w: unknown record type %d at offset %d", ErrBadLog, op, good)
+		}
+		klen, err := binary.ReadUvarint(r)
+		if err != nil {
+			return good, nil
+		}
+		size := int(klen)
+		if op == opSet {
+			size += 8
+		}
+		rec := make([]byte, size)
+		if _, err = io.ReadFull(r, rec); err != nil {
+			return good, nil
+		}
+		key := string(rec[:klen])
+		switch op {
+		case opSet:
+			t.expires[key] = time.Unix(0, int64(binary.BigEndian.Uint64(rec[klen:])))
+		case opClear:
+			delete(t.expires, key)
+		}
+		t.records++
+		good += 1 + int64(uvarintLen(klen)) + int64(size)
+	}
+}
+
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
+
+func encode(op byte, key []byte, at time.Time) []byte {
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64+len(key)+8)
+	buf = append(buf, op)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	if op == opSet {
+		buf = binary.BigEndian.AppendUint64(buf, uint64(at.UnixNano()))
+	}
+	return buf
+}
+
+// append writes a record to the log, creating it if needed. caller must hold write lock.
+func (t *Tracker) append(op byte, key []byte, at time.Time) error {
+	if t.f == nil {
+		f, err := os.OpenFile(t.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		t.f = f
+	}
+	if _, err := t.f.Write(encode(op, key, at)); err != nil {
+		return err
+	}
+	t.records++
+	return nil
+}
+
+// Set runs write and, if it succeeds, records that the key expires at the given time.
+// A zero time clears any expiration time for the key.
+// Both happen under the tracker's lock, so a concurrent [Tracker.Expire] or [Tracker.Sweep]
+// can never remove a value that was just written.
+func (t *Tracker) Set(key []byte, at time.Time, write func() error) error {
+	return t.SetAll([][]byte{key}, at, write)
+}
+
+// SetAll is like [Tracker.Set] for several keys written at once, such as by a batch.
+func (t *Tracker) SetAll(keys [][]byte, at time.Time, write func() error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return fs.ErrClosed
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, t.record(key, at))
+	}
+	return errors.Join(errs...)
+}
+
+// record sets or clears the expiration time for a key, caller must hold write lock.
+func (t *Tracker) record(key []byte, at time.Time) error {
+	if at.IsZero() {
+		if _, ok := t.expires[string(key)]; !ok {
+			return nil
+		}
+		delete(t.expires, string(key))
+		return t.append(opClear, key, at)
+	}
+	t.expires[string(key)] = at
+	return t.append(opSet, key, at)
+}
+
+func (t *Tracker) expired(key []byte, now time.Time) bool {
+	at, ok := t.expires[string(key)]
+	return ok && !now.Before(at)
+}
+
+// Expired returns true if the key has an expiration time that has passed.
+func (t *Tracker) Expired(key []byte) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.expired(key, time.Now())
+}
+
+// TTL returns the time remaining until the key expires, and false if the key has no expiration time.
+// The duration will be zero or negative if the key has already expired.
+func (t *Tracker) TTL(key []byte) (time.Duration, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	at, ok := t.expires[string(key)]
+	if !ok {
+		return 0, false
+	}
+	return time.Until(at), true
+}
+
+// Len returns the number of keys that have an expiration time, expired or not.
+func (t *Tracker) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.expires)
+}
+
+// Live filters the given keys in place, dropping any that have expired.
+func (t *Tracker) Live(keys [][]byte) [][]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.expires) == 0 {
+		return keys
+	}
+	now := time.Now()
+	live := keys[:0]
+	for _, key := range keys {
+		if !t.expired(key, now) {
+			live = append(live, key)
+		}
+	}
+	return live
+}
+
+// Expiring returns all keys whose expiration time has passed but that have not yet been removed.
+func (t *Tracker) Expiring() [][]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	now := time.Now()
+	keys := make([][]byte, 0)
+	for key := range t.expires {
+		if t.expired([]byte(key), now) {
+			keys = append(keys, []byte(key))
+		}
+	}
+	return keys
+}
+
+// expire removes a single expired key, caller must hold write lock.
+func (t *Tracker) expire(key []byte, remove func(key []byte) error) error {
+	if err := remove(key); err != nil {
+		return err
+	}
+	delete(t.expires, string(key))
+	return t.append(opClear, key, time.Time{})
+}
+
+// Expire removes the key with remove if it has expired, returning true if it had.
+func (t *Tracker) Expire(key []byte, remove func(key []byte) error) (bool, error) {
+	// reads are far more common than expirations, so don't take the write lock unless it's needed.
+	if !t.Expired(key) {
+		return false, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed || !t.expired(key, time.Now()) {
+		return false, nil
+	}
+	return true, t.expire(key, remove)
+}
+
+// Sweep removes every expired key with remove, returning the number of keys removed.
+// Once the log is mostly made up of stale records it is rewritten.
+func (t *Tracker) Sweep(remove func(key []byte) error) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return 0, fs.ErrClosed
+	}
+	now := time.Now()
+	var (
+		n    int
+		errs []error
+	)
+	for key := range t.expires {
+		if !t.expired([]byte(key), now) {
+			continue
+		}
+		if err := t.expire([]byte(key), remove); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		n++
+	}
+	if t.records > compactThreshold && t.records > 2*len(t.expires) {
+		errs = append(errs, t.compact())
+	}
+	return n, errors.Join(errs...)
+}
+
+// compact rewrites the log with only the current expiration times, caller must hold write lock.
+func (t *Tracker) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(t.path), LogFile+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	w := bufio.NewWriter(tmp)
+	for key, at := range t.expires {
+		if _, err = w.Write(encode(opSet, []byte(key), at)); err != nil {
+			_ = tmp.Close()
+			return err
+		}
+	}
+	if err = w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if t.f != nil {
+		_ = t.f.Close()
+		t.f = nil
+	}
+	if err = os.Rename(tmp.Name(), t.path); err != nil {
+		return err
+	}
+	t.records = len(t.expires)
+	return nil
+}
+
+// Sync flushes the log to disk.
+func (t *Tracker) Sync() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return fs.ErrClosed
+	}
+	if t.f == nil {
+		return nil
+	}
+	return t.f.Sync()
+}
+
+// Freeze syncs the log and runs fn under the tracker's lock, so that no write made through [Tracker.Set],
+// [Tracker.SetAll], [Tracker.Expire] or [Tracker.Sweep] can happen until fn returns.
+// Stores whose writes all go through their tracker use it to hold writes back while their files are snapshotted.
+func (t *Tracker) Freeze(fn func() error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return fs.ErrClosed
+	}
+	if t.f != nil {
+		if err := t.f.Sync(); err != nil {
+			return err
+		}
+	}
+	return fn()
+}
+
+// Close syncs and closes the log, after which the tracker can no longer be used.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return fs.ErrClosed
+	}
+	t.closed = true
+	if t.f == nil {
+		return nil
+	}
+	err := t.f.Sync()
+	if closeErr := t.f.Close(); err == nil {
+		err = closeErr
+	}
+	t.f = nil
+	return err
+}
+
+// Deadline converts a time to live in to an absolute expiration time.
+func Deadline(ttl time.Duration) (time.Time, error) {
+	if ttl <= 0 {
+		return time.Time{}, ErrBadTTL
+	}
+	return time.Now().Add(ttl), nil
+}
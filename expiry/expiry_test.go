@@ -0,0 +1,202 @@
+package expiry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func noop() error { return nil }
+
+func TestTracker_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LogFile)
+	tr, err := Open(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected log to not be created until needed, got %v", err)
+	}
+	if err = tr.Set([]byte("soon"), time.Now().Add(-time.Second), noop); err != nil {
+		t.Fatal(err)
+	}
+	if err = tr.Set([]byte("later"), time.Now().Add(time.Hour), noop); err != nil {
+		t.Fatal(err)
+	}
+	if err = tr.Set([]byte("cleared"), time.Now().Add(-time.Second), noop); err != nil {
+		t.Fatal(err)
+	}
+	if err = tr.Set([]byte("cleared"), time.Time{}, noop); err != nil {
+		t.Fatal(err)
+	}
+	if err = tr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash part way through writing a record
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{opSet, 10, 'x'})
+	_ = f.Close()
+
+	if tr, err = Open(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer func() {
+		_ = tr.Close()
+	}()
+	if !tr.Expired([]byte("soon")) {
+		t.Error("expected 'soon' to be expired")
+	}
+	if tr.Expired([]byte("later")) || tr.Expired([]byte("cleared")) {
+		t.Error("expected 'later' and 'cleared' to not be expired")
+	}
+	if ttl, ok := tr.TTL([]byte("later")); !ok || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected ttl under an hour, got %v (%t)", ttl, ok)
+	}
+	if _, ok := tr.TTL([]byte("cleared")); ok {
+		t.Error("expected 'cleared' to have no ttl")
+	}
+	live := tr.Live([][]byte{[]byte("soon"), []byte("later"), []byte("other")})
+	if len(live) != 2 {
+		t.Errorf("expected 2 live keys, got %d", len(live))
+	}
+
+	// the truncated record should have been discarded so that new records are readable
+	if err = tr.Set([]byte("after"), time.Now().Add(time.Hour), noop); err != nil {
+		t.Fatal(err)
+	}
+	_ = tr.Close()
+	if tr, err = Open(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := tr.TTL([]byte("after")); !ok {
+		t.Error("expected record written after truncation to be loaded")
+	}
+}
+
+func TestTracker_SetFailure(t *testing.T) {
+	tr, err := Open(filepath.Join(t.TempDir(), LogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	if err = tr.Set([]byte("yeet"), time.Now(), func() error { return boom }); !errors.Is(err, boom) {
+		t.Fatalf("expected write error, got %v", err)
+	}
+	if tr.Len() != 0 {
+		t.Error("expected failed write to not be tracked")
+	}
+	_ = tr.Close()
+	if err = tr.Set([]byte("yeet"), time.Now(), noop); err == nil {
+		t.Error("expected error after close")
+	}
+}
+
+func TestTracker_SweepAndCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LogFile)
+	tr, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Second)
+	for i := 0; i < compactThreshold; i++ {
+		if err = tr.Set([]byte(strconv.Itoa(i)), past, noop); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tr.Set([]byte("keep"), time.Now().Add(time.Hour), noop); err != nil {
+		t.Fatal(err)
+	}
+	if len(tr.Expiring()) != compactThreshold {
+		t.Fatalf("expected %d expiring keys, got %d", compactThreshold, len(tr.Expiring()))
+	}
+	var removed []string
+	n, err := tr.Sweep(func(key []byte) error {
+		removed = append(removed, string(key))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n != compactThreshold || len(removed) != compactThreshold {
+		t.Errorf("expected %d keys swept, got %d", compactThreshold, n)
+	}
+	if tr.records != 1 {
+		t.Errorf("expected log to be compacted to a single record, got %d", tr.records)
+	}
+	if expired, _ := tr.Expire([]byte("keep"), func([]byte) error { return nil }); expired {
+		t.Error("expected 'keep' to not be expired")
+	}
+	_ = tr.Close()
+
+	if tr, err = Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = tr.Close()
+	}()
+	if tr.Len() != 1 {
+		t.Errorf("expected 1 key after reopening compacted log, got %d", tr.Len())
+	}
+}
+
+func TestTracker_Freeze(t *testing.T) {
+	tr, err := Open(filepath.Join(t.TempDir(), LogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wrote atomic.Bool
+	done := make(chan error)
+	err = tr.Freeze(func() error {
+		go func() {
+			done <- tr.Set([]byte("yeet"), time.Time{}, func() error {
+				wrote.Store(true)
+				return nil
+			})
+		}()
+		time.Sleep(50 * time.Millisecond)
+		if wrote.Load() {
+			return errors.New("expected write to be held back while frozen")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = <-done; err != nil || !wrote.Load() {
+		t.Errorf("expected write to go through once thawed, got %v", err)
+	}
+	_ = tr.Close()
+	if err = tr.Freeze(noop); err == nil {
+		t.Error("expected error after close")
+	}
+}
+
+func TestSweeper(t *testing.T) {
+	var n atomic.Int64
+	s := NewSweeper(time.Millisecond, func() {
+		n.Add(1)
+	})
+	s.Start()
+	s.Start()
+	if !s.Running() {
+		t.Fatal("expected sweeper to be running")
+	}
+	time.Sleep(50 * time.Millisecond)
+	s.SetInterval(time.Hour)
+	s.Stop()
+	s.Stop()
+	if s.Running() {
+		t.Fatal("expected sweeper to be stopped")
+	}
+	if n.Load() == 0 {
+		t.Error("expected sweep to have run")
+	}
+}
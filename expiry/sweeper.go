@@ -0,0 +1,98 @@
+package expiry
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultSweepInterval is how often a [Sweeper] runs unless told otherwise.
+const DefaultSweepInterval = time.Minute
+
+// Sweeper runs a sweep function on an interval in its own goroutine until it is stopped.
+// Keepers start their sweeper when a store is opened and stop it when all stores are closed.
+type Sweeper struct {
+	sweep    func()
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewSweeper returns a stopped Sweeper that will call sweep every interval once started.
+func NewSweeper(interval time.Duration, sweep func()) *Sweeper {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+	return &Sweeper{sweep: sweep, interval: interval}
+}
+
+func (s *Sweeper) run(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.sweep()
+		}
+	}
+}
+
+// start launches the sweeper goroutine if it isn't already running. caller must hold lock.
+func (s *Sweeper) start() {
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.run(s.interval, s.stop, s.done)
+}
+
+// halt stops the sweeper goroutine and waits for it to exit. caller must hold lock.
+func (s *Sweeper) halt() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+	s.done = nil
+}
+
+// Start launches the sweeper if it isn't already running.
+func (s *Sweeper) Start() {
+	s.mu.Lock()
+	s.start()
+	s.mu.Unlock()
+}
+
+// Stop stops the sweeper and waits for any sweep in progress to finish.
+// The sweep function must not wait on anything held by the caller of Stop.
+func (s *Sweeper) Stop() {
+	s.mu.Lock()
+	s.halt()
+	s.mu.Unlock()
+}
+
+// Running returns true if the sweeper goroutine is running.
+func (s *Sweeper) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stop != nil
+}
+
+// SetInterval changes how often the sweeper runs, restarting it if it is running.
+func (s *Sweeper) SetInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interval = interval
+	if s.stop != nil {
+		s.halt()
+		s.start()
+	}
+}
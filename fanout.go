@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// ErrNotSearcher is reported for the stores of a Keeper that don't implement [Searcher].
+var ErrNotSearcher = errors.New("provided Filer does not implement Searcher")
+
+// StoreErrors is sent by [SearchAll] and [PrefixScanAll] once they finish if searching any of the stores failed.
+// It maps the names of those stores to their errors.
+type StoreErrors map[string]error
+
+func (e StoreErrors) names() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (e StoreErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, name := range e.names() {
+		msgs = append(msgs, name+": "+e[name].Error())
+	}
+	return fmt.Sprintf("search failed in %d stores: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the stores, for use with [errors.Is] and [errors.As].
+func (e StoreErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, name := range e.names() {
+		errs = append(errs, e[name])
+	}
+	return errs
+}
+
+// SearchAll searches the values of every store in keeper for query, see [SearchAllContext].
+func SearchAll(keeper Keeper, query string) (<-chan kv.StoreKeyValue, chan error) {
+	return SearchAllContext(context.Background(), keeper, query, 0)
+}
+
+// SearchAllContext searches the values of every store in keeper for query, streaming the results of all of them
+// tagged with the name of the store they came from. At most workers stores are searched at once, zero or less
+// uses one worker per CPU. Once every store has been searched, a [StoreErrors] is sent to the error channel if
+// any of them failed, stores that don't implement [Searcher] fail with [ErrNotSearcher]. If ctx is done first,
+// the searches stop and the context's error is sent instead. Both channels are closed once the searches stop.
+func SearchAllContext(ctx context.Context, keeper Keeper, query string, workers int) (<-chan kv.StoreKeyValue, chan error) {
+	return fanOut(ctx, keeper, workers, func(ctx context.Context, s Searcher) (<-chan kv.KeyValue, chan error) {
+		if cs, ok := s.(ContextSearcher); ok {
+			return cs.SearchContext(ctx, query)
+		}
+		return s.Search(query)
+	})
+}
+
+// PrefixScanAll scans every store in keeper for keys with the given prefix, see [PrefixScanAllContext].
+func PrefixScanAll(keeper Keeper, prefix string) (<-chan kv.StoreKeyValue, chan error) {
+	return PrefixScanAllContext(context.Background(), keeper, prefix, 0)
+}
+
+// PrefixScanAllContext is like SearchAllContext, for the keys with the given prefix.
+func PrefixScanAllContext(ctx context.Context, keeper Keeper, prefix string, workers int) (<-chan kv.StoreKeyValue, chan error) {
+	return fanOut(ctx, keeper, workers, func(ctx context.Context, s Searcher) (<-chan kv.KeyValue, chan error) {
+		if cs, ok := s.(ContextSearcher); ok {
+			return cs.PrefixScanContext(ctx, prefix)
+		}
+		return s.PrefixScan(prefix)
+	})
+}
+
+type scanFunc func(ctx context.Context, s Searcher) (<-chan kv.KeyValue, chan error)
+
+// fanOut runs scan over every store of keeper with a pool of workers, merging their results.
+func fanOut(ctx context.Context, keeper Keeper, workers int, scan scanFunc) (<-chan kv.StoreKeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.StoreKeyValue, 5)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	stores := keeper.AllStores()
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		names := make([]string, 0, len(stores))
+		for name := range stores {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		var mu sync.Mutex
+		failed := make(StoreErrors)
+		jobs := make(chan string)
+		var wg sync.WaitGroup
+		for i := 0; i < min(workers, len(names)); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for name := range jobs {
+					if err := scanStore(ctx, name, stores[name], scan, resChan); err != nil {
+						mu.Lock()
+						failed[name] = err
+						mu.Unlock()
+					}
+				}
+			}()
+		}
+	feed:
+		for _, name := range names {
+			select {
+			case jobs <- name:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		close(jobs)
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			errChan <- err
+			return
+		}
+		if len(failed) > 0 {
+			errChan <- failed
+		}
+	}()
+	return resChan, errChan
+}
+
+// scanStore forwards the results of scanning a single store, returning the error that ended the scan.
+// Searchers may send errors before their results are done and on an unbuffered channel,
+// so both channels are read until they are closed.
+func scanStore(ctx context.Context, name string, filer Filer, scan scanFunc, resChan chan<- kv.StoreKeyValue) error {
+	s, ok := filer.(Searcher)
+	if !ok {
+		return ErrNotSearcher
+	}
+	storeRes, storeErr := scan(ctx, s)
+	var errs []error
+	for storeRes != nil || storeErr != nil {
+		select {
+		case keyVal, ok := <-storeRes:
+			if !ok {
+				storeRes = nil
+				continue
+			}
+			select {
+			case resChan <- kv.NewStoreKeyValue(name, keyVal):
+			case <-ctx.Done():
+				drainScan(storeRes, storeErr)
+				return ctx.Err()
+			}
+		case err, ok := <-storeErr:
+			if !ok {
+				storeErr = nil
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			drainScan(storeRes, storeErr)
+			return ctx.Err()
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// drainScan lets a store's scan run down, since not every Searcher stops once ctx is done.
+func drainScan(storeRes <-chan kv.KeyValue, storeErr <-chan error) {
+	go func() {
+		for storeRes != nil || storeErr != nil {
+			select {
+			case _, ok := <-storeRes:
+				if !ok {
+					storeRes = nil
+				}
+			case _, ok := <-storeErr:
+				if !ok {
+					storeErr = nil
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,28 @@
+package fs
+
+import (
+	"errors"
+	"fmt"
+)
+
+//goland:noinspection GoExportedElementShouldHaveComment
+var (
+	ErrUnknownAction = errors.New("unknown action")
+	ErrBogusStore    = errors.New("bogus store backend")
+	ErrBadStoreName  = errors.New("invalid fs store name")
+	ErrStoreExists   = errors.New("store name already exists")
+	ErrNoStores      = errors.New("no stores initialized")
+	ErrEmptyKey      = errors.New("empty key")
+	ErrKeyTooLong    = errors.New("key too long to be used as a file name")
+)
+
+func namedErr(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+func compoundErrors(errs []error) (err error) {
+	return errors.Join(errs...)
+}
@@ -0,0 +1,597 @@
+package fs
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+const (
+	// tmpPrefix is prepended to the names of files that are still being written,
+	// hex encoded key names can never start with it.
+	tmpPrefix = ".tmp-"
+	// maxNameLen is the longest file name most filesystems will accept.
+	maxNameLen = 255
+)
+
+// Store is an implementation of a Filer and a Searcher where every key is a file inside of the store's directory.
+// File names are the lowercase hex encoding of the key, which keeps directory listings in key order.
+type Store struct {
+	path   string
+	mu     *sync.Mutex
+	closed *atomic.Bool
+}
+
+// Backend returns the directory that the store's files are kept in.
+func (s *Store) Backend() any {
+	return s.path
+}
+
+func keyName(key []byte) (string, error) {
+	if len(key) == 0 {
+		return "", ErrEmptyKey
+	}
+	if hex.EncodedLen(len(key)) > maxNameLen {
+		return "", fmt.Errorf("%w: %d bytes", ErrKeyTooLong, len(key))
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// nameKey decodes a file name back in to its key, returning false for anything that isn't a key file.
+func nameKey(name string) ([]byte, bool) {
+	if name == "" || strings.HasPrefix(name, tmpPrefix) {
+		return nil, false
+	}
+	key, err := hex.DecodeString(name)
+	if err != nil || hex.EncodeToString(key) != name {
+		return nil, false
+	}
+	return key, true
+}
+
+// Has returns true if the given key has an associated value.
+func (s *Store) Has(key []byte) bool {
+	if s.closed.Load() {
+		return false
+	}
+	name, err := keyName(key)
+	if err != nil {
+		return false
+	}
+	stat, err := os.Stat(filepath.Join(s.path, name))
+	return err == nil && stat.Mode().IsRegular()
+}
+
+// Get reads the file associated with the given key.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	if s.closed.Load() {
+		return nil, iofs.ErrClosed
+	}
+	name, err := keyName(key)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := os.ReadFile(filepath.Join(s.path, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
+	if ret == nil && err == nil {
+		ret = []byte{}
+	}
+	return ret, kv.RegularizeKVError(key, ret, err)
+}
+
+// Put writes the value to a temporary file and then renames it over the key's file,
+// so readers will only ever see a complete value.
+func (s *Store) Put(key []byte, value []byte) error {
+	if s.closed.Load() {
+		return iofs.ErrClosed
+	}
+	name, err := keyName(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.path, tmpPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(value); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp.Name(), filepath.Join(s.path, name))
+	return err
+}
+
+// Delete removes the file associated with the given key.
+func (s *Store) Delete(key []byte) error {
+	if s.closed.Load() {
+		return iofs.ErrClosed
+	}
+	name, err := keyName(key)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err = os.Remove(filepath.Join(s.path, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Close marks the store as closed, there are no open handles to release.
+func (s *Store) Close() error {
+	if s.closed.Load() {
+		return iofs.ErrClosed
+	}
+	s.closed.Store(true)
+	return nil
+}
+
+// Sync flushes the store's directory entries to disk, values are already synced when they are written.
+func (s *Store) Sync() error {
+	if s.closed.Load() {
+		return iofs.ErrClosed
+	}
+	d, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// keyEntries returns the names of all key files in the store, in key order.
+func (s *Store) keyEntries() ([]string, error) {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if _, ok := nameKey(entry.Name()); ok {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Keys returns all keys in the store, in byte order, as a slice of byte slices.
+func (s *Store) Keys() [][]byte {
+	keys := make([][]byte, 0)
+	if s.closed.Load() {
+		return keys
+	}
+	names, err := s.keyEntries()
+	if err != nil {
+		return keys
+	}
+	for _, name := range names {
+		key, _ := nameKey(name)
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Len returns the number of keys in the store.
+func (s *Store) Len() int {
+	if s.closed.Load() {
+		return 0
+	}
+	names, err := s.keyEntries()
+	if err != nil {
+		return 0
+	}
+	return len(names)
+}
+
+// openStore prepares the store directory at path, removing any files left behind by interrupted writes.
+func openStore(path string) (*Store, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	stale, err := filepath.Glob(filepath.Join(path, tmpPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range stale {
+		_ = os.Remove(f)
+	}
+	aclosed := &atomic.Bool{}
+	aclosed.Store(false)
+	return &Store{path: path, mu: &sync.Mutex{}, closed: aclosed}, nil
+}
+
+// DB is a mapper of a Filer and Searcher implementation using plain files.
+type DB struct {
+	store       map[string]*Store
+	path        string
+	mu          *sync.RWMutex
+	meta        *metadata.Metadata
+	initialized *atomic.Bool
+}
+
+// Meta returns the [models.Metadata] implementation of the fs keeper.
+func (db *DB) Meta() models.Metadata {
+	var m models.Metadata
+	db.mu.RLock()
+	m = db.meta
+	db.mu.RUnlock()
+	if m == nil {
+		m = metadata.NewPlaceholder(db.Type())
+	}
+	return m
+}
+
+func (db *DB) allStores() map[string]database.Filer {
+	var stores = make(map[string]database.Filer)
+	for n, s := range db.store {
+		stores[n] = s
+	}
+	return stores
+}
+
+// AllStores returns a map of the names of all fs datastores and the corresponding Filers.
+func (db *DB) AllStores() map[string]database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.RLock()
+	ast := db.allStores()
+	db.mu.RUnlock()
+	return ast
+}
+
+func (db *DB) _init() error {
+	if _, err := os.Stat(db.path); os.IsNotExist(err) {
+		err = os.MkdirAll(db.path, 0700)
+		if err != nil {
+			return fmt.Errorf("error creating fs directory: %w", err)
+		}
+	}
+	stat, err := os.Stat(filepath.Join(db.path, "meta.json"))
+	if err == nil && stat.IsDir() {
+		return errors.New("meta.json is a directory")
+	}
+	if err == nil && !stat.IsDir() {
+		if db.meta, err = metadata.OpenMetaFile(filepath.Join(db.path, "meta.json")); err != nil {
+			return fmt.Errorf("error opening meta file: %w", err)
+		}
+		if db.meta.Type() != db.Type() {
+			return fmt.Errorf("meta.json is not a fs meta file")
+		}
+		db.initialized.Store(true)
+		return nil
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		db.meta, err = metadata.NewMetaFile(db.Type(), filepath.Join(db.path, "meta.json"))
+		if err != nil {
+			return fmt.Errorf("error creating meta file: %w", err)
+		}
+		db.initialized.Store(true)
+		return nil
+	}
+
+	return err
+}
+
+func (db *DB) init() error {
+	if db.initialized.Load() {
+		return nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db._init()
+}
+
+// OpenDB will either open an existing set of fs datastores at the given directory, or it will create a new one.
+func OpenDB(path string) *DB {
+	ainit := &atomic.Bool{}
+	ainit.Store(false)
+	return &DB{
+		store:       make(map[string]*Store),
+		path:        path,
+		mu:          &sync.RWMutex{},
+		meta:        nil,
+		initialized: ainit,
+	}
+}
+
+// Path returns the base path where we store our fs "stores".
+func (db *DB) Path() string {
+	return db.path
+}
+
+// initStore is a helper function to initialize a fs store, caller must hold keeper's lock.
+func (db *DB) initStore(storeName string) error {
+	if _, ok := db.store[storeName]; ok {
+		return ErrStoreExists
+	}
+	if storeName == "" || !filepath.IsLocal(storeName) || filepath.Base(storeName) != storeName {
+		return fmt.Errorf("%w: %q", ErrBadStoreName, storeName)
+	}
+	st, err := openStore(filepath.Join(db.path, storeName))
+	if err != nil {
+		return err
+	}
+	db.store[storeName] = st
+	if !slices.Contains(db.meta.KnownStores, storeName) {
+		db.meta.KnownStores = append(db.meta.KnownStores, storeName)
+	}
+	return nil
+}
+
+// Init opens a fs store at the given path to be referenced by storeName.
+// The fs keeper has no options, any that are passed in are ignored.
+func (db *DB) Init(storeName string, _ ...any) error {
+	if err := db.init(); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	err := db.initStore(storeName)
+	db.mu.Unlock()
+	return err
+}
+
+// Destroy will remove the fs store and all data associated with it.
+func (db *DB) Destroy(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	if err := st.Close(); err != nil && !errors.Is(err, iofs.ErrClosed) {
+		return err
+	}
+	delete(db.store, storeName)
+	db.meta.RemoveStore(storeName)
+	return os.RemoveAll(filepath.Join(db.path, storeName))
+}
+
+// With returns the fs store by the given name, or nil if it is not open.
+func (db *DB) With(storeName string) database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.RLock()
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		db.mu.RUnlock()
+		return d
+	}
+	db.mu.RUnlock()
+	if ok {
+		db.mu.Lock()
+		delete(db.store, storeName)
+		db.mu.Unlock()
+	}
+	return nil
+}
+
+// WithNew returns the fs store by the given name, if it doesn't exist, it creates it.
+func (db *DB) WithNew(storeName string, _ ...any) database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		return d
+	}
+	if ok {
+		delete(db.store, storeName)
+	}
+	if err := db.initStore(storeName); err != nil {
+		fmt.Println("ERROR: failed to create fs store: ", err)
+		return nil
+	}
+	return db.store[storeName]
+}
+
+// Close closes the given fs store.
+func (db *DB) Close(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	if err := st.Close(); err != nil {
+		return err
+	}
+	delete(db.store, storeName)
+	return nil
+}
+
+// Sync flushes the given store's directory to disk.
+func (db *DB) Sync(storeName string) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if _, ok := db.store[storeName]; !ok {
+		return ErrBogusStore
+	}
+	return db.store[storeName].Sync()
+}
+
+// withAllAction
+type withAllAction uint8
+
+const (
+	// dclose
+	dclose withAllAction = iota
+	// dsync
+	dsync
+)
+
+// withAll performs an action on all fs stores that we have open.
+// In the case of an error, withAll will continue and return a compound form of any errors that occurred.
+func (db *DB) withAll(action withAllAction) error {
+	if db == nil || db.store == nil {
+		panic("fs: nil db or db.store")
+	}
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	var errs = make([]error, 0, len(db.store))
+	for name, store := range db.store {
+		var err error
+		if store == nil {
+			errs = append(errs, namedErr(name, ErrBogusStore))
+			continue
+		}
+		if store.closed.Load() {
+			continue
+		}
+		switch action {
+		case dclose:
+			err = namedErr(name, store.Close())
+			delete(db.store, name)
+		case dsync:
+			err = namedErr(name, store.Sync())
+		default:
+			return ErrUnknownAction
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return compoundErrors(errs)
+}
+
+func (db *DB) addAllStoresToMeta() {
+	storeNames := make([]string, 0, len(db.store))
+	for name := range db.store {
+		if name == "" {
+			continue
+		}
+		storeNames = append(storeNames, name)
+	}
+	db.meta = db.meta.WithStores(storeNames...)
+}
+
+func (db *DB) syncAll() error {
+	db.addAllStoresToMeta()
+	var errs = make([]error, 0)
+	errs = append(errs, db.withAll(dsync))
+	errs = append(errs, db.meta.Sync())
+	return compoundErrors(errs)
+}
+
+// SyncAll syncs all fs datastores.
+func (db *DB) SyncAll() error {
+	if err := db.init(); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	err := db.syncAll()
+	db.mu.Unlock()
+	return err
+}
+
+func (db *DB) closeAll() error {
+	return db.withAll(dclose)
+}
+
+// CloseAll closes all fs datastores.
+func (db *DB) CloseAll() error {
+	db.mu.Lock()
+	err := db.closeAll()
+	db.mu.Unlock()
+	return err
+}
+
+// SyncAndCloseAll implements the method from Keeper to sync and close all fs stores.
+func (db *DB) SyncAndCloseAll() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	errs := []error{namedErr("sync", db.syncAll()), namedErr("close", db.closeAll())}
+	return compoundErrors(errs)
+}
+
+// discover is a helper function to discover and initialize all existing fs stores at the path.
+// Every directory at the top level of the path is treated as a store.
+// caller must hold write lock.
+func (db *DB) discover() ([]string, error) {
+	entries, err := os.ReadDir(db.path)
+	if err != nil {
+		return nil, err
+	}
+	stores := make([]string, 0, len(entries))
+	errs := make([]error, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+		if st, ok := db.store[name]; ok && !st.closed.Load() {
+			stores = append(stores, name)
+			continue
+		}
+		delete(db.store, name)
+		if err = db.initStore(name); err != nil {
+			errs = append(errs, namedErr(name, err))
+			continue
+		}
+		stores = append(stores, name)
+	}
+	return stores, compoundErrors(errs)
+}
+
+// Discover will discover and initialize all existing fs stores at the path opened by [OpenDB].
+func (db *DB) Discover() ([]string, error) {
+	if err := db.init(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	stores, err := db.discover()
+	db.mu.Unlock()
+	return stores, err
+}
+
+// Type returns the type of keeper, in this case "fs".
+// This is in order to implement [database.Keeper].
+func (db *DB) Type() string {
+	return "fs"
+}
@@ -0,0 +1,114 @@
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/models"
+)
+
+// BackupAll creates an archive of all fs stores and the keeper's metadata.
+// Writes to every store are held off while the archive is written, but the stores remain open.
+func (db *DB) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	return db.BackupAllContext(context.Background(), archivePath, opts...)
+}
+
+// BackupAllContext is like BackupAll, but writes the archive with opts and abandons the backup once ctx is done.
+func (db *DB) BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error) {
+	if err := db.init(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	storeNames, err := db.discover()
+	if err != nil {
+		return nil, err
+	}
+	if err = db.syncAll(); err != nil && !errors.Is(err, ErrNoStores) {
+		return nil, err
+	}
+
+	for _, name := range storeNames {
+		st := db.store[name]
+		st.mu.Lock()
+		defer st.mu.Unlock()
+	}
+
+	b, err := backup.NewBackuper(backup.FormatFromPath(archivePath), opts...)
+	if err != nil {
+		return nil, err
+	}
+	bu, err := b.Backup(ctx, db.path, archivePath, storeNames)
+	if err != nil {
+		return nil, err
+	}
+	if db.meta.Backups == nil {
+		db.meta.Backups = make(map[string]any)
+	}
+	db.meta.Backups[bu.FilePath] = bu
+	err = db.meta.Sync()
+	return bu, err
+}
+
+// RestoreAll replaces all fs stores with the contents of the given archive.
+// A backup of the existing stores is taken first and referenced in any errors.
+func (db *DB) RestoreAll(archivePath string, opts ...backup.Option) error {
+	if err := db.init(); err != nil {
+		return err
+	}
+
+	var preBackupPath string
+
+	db.mu.RLock()
+	hasStores := len(db.store) > 0
+	db.mu.RUnlock()
+
+	if hasStores {
+		preBu, err := db.BackupAll(filepath.Join(os.TempDir(), "pre-restore-"+time.Now().Format(time.RFC3339)+".tar.gz"))
+		if err != nil {
+			return fmt.Errorf("failed to create pre-restore backup: %w", err)
+		}
+		preBackupPath = fmt.Sprintf(" (backup: %s)", preBu.Path())
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for name, st := range db.store {
+		_ = st.Close()
+		delete(db.store, name)
+		if err := os.RemoveAll(filepath.Join(db.path, name)); err != nil {
+			return fmt.Errorf("failed to remove existing store %s%s: %w", name, preBackupPath, err)
+		}
+	}
+
+	// release the handle on the current meta.json before it is overwritten by the archive's copy.
+	db.meta.WithWriter(nil)
+	db.initialized.Store(false)
+
+	opts = append([]backup.Option{backup.WithKnown(backup.KnownBackups(db.meta)...)}, opts...)
+	if err := backup.RestoreBackup(archivePath, db.path, opts...); err != nil {
+		return fmt.Errorf("failed to restore archive%s: %w", preBackupPath, err)
+	}
+
+	if err := db._init(); err != nil {
+		return fmt.Errorf("failed to re-init db after restore%s: %w", preBackupPath, err)
+	}
+
+	if _, err := db.discover(); err != nil {
+		return fmt.Errorf("failed during discover call after restore%s: %w", preBackupPath, err)
+	}
+
+	if err := db.meta.Sync(); err != nil {
+		return fmt.Errorf("failed to sync meta after restore%s: %w", preBackupPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package fs
+
+import "context"
+
+// GetContext is like Get, but returns ctx's error instead if it is already done.
+// Reading or writing a single file can't be interrupted, so ctx is only checked before it starts.
+func (s *Store) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.Get(key)
+}
+
+// PutContext is like Put, but returns ctx's error instead if it is already done.
+func (s *Store) PutContext(ctx context.Context, key []byte, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.Put(key, value)
+}
@@ -0,0 +1,37 @@
+package fs
+
+import (
+	"encoding/hex"
+	iofs "io/fs"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+)
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Hex encoding preserves ordering, so only the files whose names fall within the range are read.
+func (s *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if s.closed.Load() {
+		return iterator.Error(iofs.ErrClosed)
+	}
+	names, err := s.keyEntries()
+	if err != nil {
+		return iterator.Error(err)
+	}
+	var hexStart, hexEnd []byte
+	if start != nil {
+		hexStart = []byte(hex.EncodeToString(start))
+	}
+	if end != nil {
+		hexEnd = []byte(hex.EncodeToString(end))
+	}
+	keys := make([][]byte, 0)
+	for _, name := range names {
+		if !iterator.InRange([]byte(name), hexStart, hexEnd) {
+			continue
+		}
+		key, _ := nameKey(name)
+		keys = append(keys, key)
+	}
+	return iterator.Sorted(keys, start, end, opts, s.Get)
+}
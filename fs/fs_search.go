@@ -0,0 +1,108 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// stream reads every key file accepted by matchName and sends those whose values satisfy matchValue to the returned channel.
+// Files that are deleted while the scan is running are skipped, and the scan stops once ctx is done.
+func (s *Store) stream(ctx context.Context, matchName func(name string) bool, matchValue func(v []byte) bool) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		names, err := s.keyEntries()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, name := range names {
+			if ctx.Err() != nil {
+				errChan <- ctx.Err()
+				return
+			}
+			if !matchName(name) {
+				continue
+			}
+			v, readErr := os.ReadFile(filepath.Join(s.path, name))
+			if errors.Is(readErr, os.ErrNotExist) {
+				continue
+			}
+			if readErr != nil {
+				errChan <- readErr
+				return
+			}
+			if !matchValue(v) {
+				continue
+			}
+			key, _ := nameKey(name)
+			if !kv.Send(ctx, resChan, kv.NewKeyValueFromBytes(key, v)) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return resChan, errChan
+}
+
+func anyName(string) bool { return true }
+
+func anyValue([]byte) bool { return true }
+
+// Search will search for a given string within all values inside of a Store.
+// Note, type casting will be necessary. (e.g: []byte or string)
+func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+func (s *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(query)
+	return s.stream(ctx, anyName, func(v []byte) bool {
+		return bytes.Contains(v, needle)
+	})
+}
+
+// ValueExists will check for the existence of a Value anywhere within the keyspace;
+// returning the first Key found, true if found || nil and false if not found.
+func (s *Store) ValueExists(value []byte) (key []byte, ok bool) {
+	names, err := s.keyEntries()
+	if err != nil {
+		return nil, false
+	}
+	for _, name := range names {
+		v, readErr := os.ReadFile(filepath.Join(s.path, name))
+		if readErr != nil || !bytes.Equal(v, value) {
+			continue
+		}
+		key, _ = nameKey(name)
+		return key, true
+	}
+	return nil, false
+}
+
+// PrefixScan will scan a Store for all keys that have a matching prefix of the given string
+// and stream the matching key/value pairs, in byte order, to the returned channel.
+// Hex encoding preserves prefixes, so only the files whose names match are read.
+func (s *Store) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
+	return s.PrefixScanContext(context.Background(), prefix)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+func (s *Store) PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error) {
+	hexPrefix := hex.EncodeToString([]byte(prefix))
+	return s.stream(ctx, func(name string) bool {
+		return strings.HasPrefix(name, hexPrefix)
+	}, anyValue)
+}
@@ -0,0 +1,43 @@
+package fs
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// SuffixScan will stream every key/value pair whose key ends with suffix, in byte order.
+// Hex encoding preserves suffixes as well as prefixes, so only the files whose names match are read.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	hexSuffix := hex.EncodeToString([]byte(suffix))
+	return s.stream(ctx, func(name string) bool {
+		return strings.HasSuffix(name, hexSuffix)
+	}, anyValue)
+}
+
+// KeyContains will stream every key/value pair whose key contains substr, in byte order.
+// Only the files whose names match are read.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return s.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (s *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(substr)
+	return s.stream(ctx, func(name string) bool {
+		// a hex substring may straddle two bytes, so names are decoded before they are matched.
+		key, ok := nameKey(name)
+		return ok && bytes.Contains(key, needle)
+	}, anyValue)
+}
@@ -0,0 +1,201 @@
+package fs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	c "git.tcp.direct/kayos/common/entropy"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/loader"
+	"github.com/tcp-direct/database/memory"
+	"github.com/tcp-direct/database/migrate"
+)
+
+func TestStore_Basic(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	if err := db.Init("test"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := db.Init("test"); !errors.Is(err, ErrStoreExists) {
+		t.Fatalf("expected ErrStoreExists, got %v", err)
+	}
+	if err := db.Init("../escape"); !errors.Is(err, ErrBadStoreName) {
+		t.Fatalf("expected ErrBadStoreName, got %v", err)
+	}
+	st := db.With("test")
+	if err := st.Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(path, "test", hex.EncodeToString([]byte("yeet"))))
+	if err != nil || string(raw) != "yeeterson" {
+		t.Errorf("expected value to be readable from hex named file, got %q (err: %v)", raw, err)
+	}
+	ret, err := st.Get([]byte("yeet"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s", ret)
+	}
+	if !st.Has([]byte("yeet")) || st.Len() != 1 || len(st.Keys()) != 1 {
+		t.Errorf("expected exactly one key in store")
+	}
+	if err = st.Put(bytes.Repeat([]byte("a"), 200), []byte("yeet")); !errors.Is(err, ErrKeyTooLong) {
+		t.Errorf("expected ErrKeyTooLong, got %v", err)
+	}
+	if err = st.Put(nil, []byte("yeet")); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("expected ErrEmptyKey, got %v", err)
+	}
+	if err = st.Delete([]byte("yeet")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !kv.IsNonExistentKey(err) {
+		t.Errorf("expected NonExistentKeyError, got %v", err)
+	}
+	if err = db.Close("test"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !errors.Is(err, iofs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+}
+
+func TestStore_StaleTempFiles(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	if err := db.WithNew("test").Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(path, "test", tmpPrefix+"123")
+	if err := os.WriteFile(stale, []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Discover(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected stale temp file to be removed, got %v", err)
+	}
+	if db.With("test").Len() != 1 {
+		t.Errorf("expected 1 key, got %d", db.With("test").Len())
+	}
+}
+
+func TestStore_PrefixScanOrdered(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	st := db.WithNew("test").(database.Store)
+	for i := 0; i < 100; i++ {
+		if err := st.Put([]byte(fmt.Sprintf("key_%03d", i)), []byte(c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Put([]byte(c.RandStr(10)), []byte("needle"+c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resChan, errChan := st.PrefixScan("key_0")
+	var last []byte
+	n := 0
+	for res := range resChan {
+		if last != nil && bytes.Compare(last, res.Key.Bytes()) >= 0 {
+			t.Errorf("expected keys in order, got %s after %s", res.Key.String(), last)
+		}
+		last = res.Key.Bytes()
+		n++
+	}
+	for err := range errChan {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("expected 100 results, got %d", n)
+	}
+
+	resChan, errChan = st.Search("needle")
+	n = 0
+	for range resChan {
+		n++
+	}
+	for err := range errChan {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("expected 100 search results, got %d", n)
+	}
+}
+
+func TestDB_BackupKeepsStoresOpen(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	if err := db.WithNew("test").Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatal(err)
+	}
+	bu, err := db.BackupAll(filepath.Join(t.TempDir(), "fs.tar.gz"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = db.With("test").Put([]byte("after"), []byte("backup")); err != nil {
+		t.Fatalf("expected store to be usable after backup, got %v", err)
+	}
+	if err = db.RestoreAll(bu.Path()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if db.With("test").Has([]byte("after")) {
+		t.Error("expected key written after the backup to be gone after restore")
+	}
+	if !db.With("test").Has([]byte("yeet")) {
+		t.Error("expected key written before the backup to exist after restore")
+	}
+	if err = db.SyncAndCloseAll(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	keeper, err := loader.OpenKeeper(path)
+	if err != nil {
+		t.Fatalf("expected loader to open fs keeper, got %v", err)
+	}
+	if ret, getErr := keeper.With("test").Get([]byte("yeet")); getErr != nil || string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s (err: %v)", ret, getErr)
+	}
+	_ = keeper.CloseAll()
+}
+
+func TestMigrate(t *testing.T) {
+	from := memory.OpenDB("")
+	for i := 0; i < 100; i++ {
+		if err := from.WithNew("test").Put([]byte(c.RandStr(10)), []byte(c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	to := OpenDB(t.TempDir())
+	t.Cleanup(func() {
+		_ = to.CloseAll()
+	})
+	m, err := migrate.NewMigrator(from, to)
+	if err != nil {
+		t.Fatalf("error creating migrator: %v", err)
+	}
+	if err = m.Migrate(); err != nil {
+		t.Fatalf("error migrating: %v", err)
+	}
+	if to.With("test").Len() != 100 {
+		t.Fatalf("expected 100 keys after migration, got %d", to.With("test").Len())
+	}
+	for _, key := range from.With("test").Keys() {
+		want, _ := from.With("test").Get(key)
+		got, getErr := to.With("test").Get(key)
+		if getErr != nil || !bytes.Equal(want, got) {
+			t.Errorf("expected %q, got %q (err: %v)", want, got, getErr)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package fs
+
+import (
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/registry"
+)
+
+func init() {
+	registry.RegisterKeeper("fs", func(path string, _ ...any) (database.Keeper, error) {
+		db := OpenDB(path)
+		err := db.init()
+		return db, err
+	})
+}
@@ -0,0 +1,334 @@
+// Package fulltext adds opt-in full-text indexes to the stores of any [database.Keeper].
+//
+// Values are split in to terms with [Tokenize], and an inverted index of where each term appears is kept in a
+// sidecar store named after the indexed store with [Suffix] appended, within the same Keeper. The index is
+// updated whenever Put or Delete is called through the Filers handed out by [Keeper]. Each write and its index
+// updates are applied together through an undo journal, so the index never disagrees with the store, even if
+// the process dies part way through a write.
+//
+// Queries are made up of terms, "quoted phrases", AND, OR, NOT (or a leading '-') and parentheses.
+// Terms next to each other must all match, as if joined by AND. Results are ranked with BM25.
+//
+// Because the sidecar store belongs to the same Keeper, it is carried along by BackupAll and RestoreAll,
+// and which stores are indexed is recorded in the Keeper's [metadata.Metadata] so that [Keeper.Discover]
+// can reattach the indexes, or rebuild those whose sidecar store is missing. Writes made directly to the
+// Keeper's stores are not indexed, [Keeper.Rebuild] brings an index back in line with its store afterwards.
+package fulltext
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/index"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+// Suffix is appended to the name of an indexed store to name its sidecar store.
+const Suffix = "_fulltext"
+
+var (
+	// ErrNoStore is returned when enabling full-text search on a store that isn't open.
+	ErrNoStore = wrap.ErrNoStore
+	// ErrNotIndexed is returned when searching or rebuilding a store without a full-text index.
+	ErrNotIndexed = errors.New("store does not have a full-text index")
+	// ErrIndexed is returned when enabling full-text search on a store that already has it.
+	ErrIndexed = errors.New("store already has a full-text index")
+	// ErrNoMetadata is returned when enabling full-text search on a Keeper without [metadata.Metadata]
+	// to record it in.
+	ErrNoMetadata = wrap.ErrNoMetadata
+	// ErrBadQuery is returned when a query can't be parsed.
+	ErrBadQuery = errors.New("malformed query")
+	// ErrCorrupt is returned when a record in a sidecar store can't be decoded.
+	ErrCorrupt = errors.New("corrupt full-text index")
+	// ErrNotSearcher is returned when a store that needs to be scanned doesn't implement [database.Searcher].
+	ErrNotSearcher = database.ErrNotSearcher
+)
+
+// Keeper wraps a [database.Keeper], maintaining the full-text indexes of its stores.
+// Filers returned by With, WithNew and AllStores are [Store]s. Discover reattaches or rebuilds the full-text
+// indexes recorded in the metadata, and Destroy removes a store's sidecar store and index definition along with it.
+type Keeper struct {
+	wrap.Keeper
+	// mu guards states and changes to the full-text indexes recorded in the Keeper's metadata.
+	mu     sync.Mutex
+	states map[string]*state
+}
+
+// state is the full-text index of a single store. Writes to the store hold mu exclusively,
+// searches hold it shared.
+type state struct {
+	mu          sync.RWMutex
+	enabled     bool
+	field       string
+	journalPath string
+}
+
+// tokens returns the terms of a value, in order.
+func (st *state) tokens(key, value []byte) []string {
+	if st.field == "" {
+		return Tokenize(string(value))
+	}
+	var tokens []string
+	texts, _ := index.JSONField(st.field)(key, value)
+	for _, text := range texts {
+		tokens = append(tokens, Tokenize(string(text))...)
+	}
+	return tokens
+}
+
+// Wrap adds full-text indexes to the given Keeper, reattaching any indexes recorded in its metadata
+// for the stores that are already open.
+func Wrap(keeper database.Keeper) (*Keeper, error) {
+	k := &Keeper{states: make(map[string]*state)}
+	k.Keeper = wrap.NewKeeper(keeper, wrap.Hooks{Wrap: k.wrap, Attach: k.attach, Forget: k.forget})
+	if err := k.attach(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// state returns the full-text index of the given store, caller must hold k.mu.
+func (k *Keeper) state(store string) *state {
+	st, ok := k.states[store]
+	if !ok {
+		st = &state{}
+		k.states[store] = st
+	}
+	return st
+}
+
+// journalPath returns where the journal for writes to the given store is kept, or an empty path
+// for in-memory stores, which don't survive a crash to recover from one.
+func (k *Keeper) journalPath(store string) string {
+	if database.IsInMemory(k.Unwrap()) {
+		return ""
+	}
+	return filepath.Join(k.Unwrap().Path(), store+Suffix+".journal")
+}
+
+func (k *Keeper) wrap(name string, f database.Filer) database.Filer {
+	k.mu.Lock()
+	st := k.state(name)
+	k.mu.Unlock()
+	return &Store{Filer: wrap.Filer{Filer: f}, keeper: k.Unwrap(), name: name, state: st}
+}
+
+// attach enables the full-text indexes recorded in the Keeper's metadata for every open store,
+// finishing or rolling back any interrupted write and rebuilding indexes whose sidecar store doesn't exist.
+func (k *Keeper) attach() error {
+	meta, ok := wrap.Recorded(k.Unwrap())
+	if !ok {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var errs []error
+	for store, idx := range meta.TextIndexes {
+		data := k.Unwrap().With(store)
+		if data == nil {
+			continue
+		}
+		st := k.state(store)
+		st.mu.Lock()
+		if !st.enabled {
+			st.field, st.journalPath = idx.Field, k.journalPath(store)
+			if err := k.reattach(st, store, data); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", store, err))
+			} else {
+				st.enabled = true
+			}
+		}
+		st.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (k *Keeper) reattach(st *state, store string, data database.Filer) error {
+	side := k.Unwrap().With(store + Suffix)
+	if side == nil {
+		return build(k.Unwrap(), st, store, data)
+	}
+	_, err := journal.New(st.journalPath, &target{data: data, side: side}).Recover()
+	return err
+}
+
+// Enable adds a full-text index to the given store, indexing its existing keys, and records it in the
+// Keeper's metadata. If field is not empty, only the text of that JSON field is indexed, see [index.JSONField].
+func (k *Keeper) Enable(store string, field string) error {
+	meta, err := wrap.Metadata(k.Unwrap())
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data := k.Unwrap().With(store)
+	if data == nil {
+		return fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.enabled {
+		return fmt.Errorf("%w: %s", ErrIndexed, store)
+	}
+	st.field, st.journalPath = field, k.journalPath(store)
+	if err = build(k.Unwrap(), st, store, data); err != nil {
+		return err
+	}
+	st.enabled = true
+	meta.AddTextIndex(store, metadata.TextIndex{Field: field})
+	return k.Unwrap().SyncAll()
+}
+
+// Disable removes the full-text index of the given store, destroying its sidecar store.
+func (k *Keeper) Disable(store string) error {
+	meta, err := wrap.Metadata(k.Unwrap())
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.enabled {
+		return fmt.Errorf("%w: %s", ErrNotIndexed, store)
+	}
+	if k.Unwrap().With(store+Suffix) != nil {
+		if err = k.Unwrap().Destroy(store + Suffix); err != nil {
+			return err
+		}
+	}
+	st.enabled = false
+	meta.RemoveTextIndex(store)
+	return k.Unwrap().SyncAll()
+}
+
+// Rebuild discards the full-text index of the given store and indexes the store's keys again.
+func (k *Keeper) Rebuild(store string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data := k.Unwrap().With(store)
+	if data == nil {
+		return fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.enabled {
+		return fmt.Errorf("%w: %s", ErrNotIndexed, store)
+	}
+	return build(k.Unwrap(), st, store, data)
+}
+
+// build empties the sidecar store of the given store, creating it if needed, and indexes every key in data.
+func build(keeper database.Keeper, st *state, store string, data database.Filer) error {
+	side, err := sidecar(keeper, store, true)
+	if err != nil {
+		return err
+	}
+	for _, key := range side.Keys() {
+		if err = side.Delete(key); err != nil && !kv.IsNonExistentKey(err) {
+			return err
+		}
+	}
+	var total stats
+	for _, key := range data.Keys() {
+		value, getErr := data.Get(key)
+		if kv.IsNonExistentKey(getErr) {
+			continue
+		}
+		if getErr != nil {
+			return getErr
+		}
+		tokens := st.tokens(key, value)
+		for _, op := range indexOps(key, tokens, nil) {
+			if err = side.Put(op.Key, op.Value); err != nil {
+				return err
+			}
+		}
+		total.docs++
+		total.terms += uint64(len(tokens))
+	}
+	if err = side.Put(statsKey, total.encode()); err != nil {
+		return err
+	}
+	return side.Sync()
+}
+
+// sidecar returns the sidecar store of the given store, initializing it if create is true.
+func sidecar(keeper database.Keeper, store string, create bool) (database.Filer, error) {
+	return wrap.Sidecar(keeper, store+Suffix, create)
+}
+
+// lockAll blocks writes to every indexed store, returning a function that unblocks them.
+func (k *Keeper) lockAll() func() {
+	k.mu.Lock()
+	for _, st := range k.states {
+		st.mu.Lock()
+	}
+	return func() {
+		for _, st := range k.states {
+			st.mu.Unlock()
+		}
+		k.mu.Unlock()
+	}
+}
+
+// BackupAll backs up all stores of the underlying Keeper while none of them are being written to,
+// so that every sidecar store in the backup matches the store it indexes.
+func (k *Keeper) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	unlock := k.lockAll()
+	defer unlock()
+	return k.Unwrap().BackupAll(archivePath, opts...)
+}
+
+// RestoreAll restores all stores of the underlying Keeper, then reattaches the full-text indexes
+// recorded in the restored metadata.
+func (k *Keeper) RestoreAll(archivePath string, opts ...backup.Option) error {
+	unlock := k.lockAll()
+	err := k.Unwrap().RestoreAll(archivePath, opts...)
+	for _, st := range k.states {
+		st.enabled = false
+	}
+	unlock()
+	if err != nil {
+		return err
+	}
+	return k.attach()
+}
+
+// forget removes the sidecar store and full-text index definition of a destroyed store.
+func (k *Keeper) forget(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	st, ok := k.states[name]
+	if !ok {
+		return nil
+	}
+	delete(k.states, name)
+	st.mu.Lock()
+	enabled := st.enabled
+	st.enabled = false
+	st.mu.Unlock()
+	if !enabled {
+		return nil
+	}
+	var errs []error
+	if k.Unwrap().With(name+Suffix) != nil {
+		errs = append(errs, k.Unwrap().Destroy(name+Suffix))
+	}
+	if meta, err := wrap.Metadata(k.Unwrap()); err == nil {
+		meta.RemoveTextIndex(name)
+		errs = append(errs, k.Unwrap().SyncAll())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,249 @@
+package fulltext
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/leveldb"
+	"github.com/tcp-direct/database/memory"
+	"github.com/tcp-direct/database/metadata"
+)
+
+var errFail = errors.New("fail")
+
+// failKeeper hands out a "docs" store that refuses to write the key "fail".
+type failKeeper struct {
+	database.Keeper
+}
+
+func (f failKeeper) With(name string) database.Filer {
+	st := f.Keeper.With(name)
+	if st != nil && name == "docs" {
+		return failFiler{st}
+	}
+	return st
+}
+
+type failFiler struct {
+	database.Filer
+}
+
+func (f failFiler) Put(key []byte, value []byte) error {
+	if string(key) == "fail" {
+		return errFail
+	}
+	return f.Filer.Put(key, value)
+}
+
+func searchKeys(t *testing.T, st database.Filer, query string) []string {
+	t.Helper()
+	resChan, errChan := st.(database.TextSearcher).SearchText(query)
+	var keys []string
+	for keyVal := range resChan {
+		keys = append(keys, keyVal.Key.String())
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("%s: expected no error, got %v", query, err)
+	}
+	return keys
+}
+
+func expectSearch(t *testing.T, st database.Filer, query string, want ...string) {
+	t.Helper()
+	if got := searchKeys(t, st, query); !slices.Equal(got, want) {
+		t.Errorf("%s: expected %v, got %v", query, want, got)
+	}
+}
+
+func newMemoryKeeper(t *testing.T, docs map[string]string) (*Keeper, database.Filer) {
+	t.Helper()
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("docs")
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Enable("docs", ""); err != nil {
+		t.Fatal(err)
+	}
+	st := k.With("docs")
+	for key, value := range docs {
+		if err = st.Put([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return k, st
+}
+
+func TestStore_SearchText(t *testing.T) {
+	_, st := newMemoryKeeper(t, map[string]string{
+		"fox":    "The quick brown fox jumps over the lazy dog",
+		"foxes":  "Fox, fox and more fox! A brown fox den.",
+		"cat":    "A lazy cat sleeps",
+		"empty":  "",
+		"brown":  "fox brown",
+		"cheese": "brown cheese",
+	})
+
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"fox", []string{"foxes", "brown", "fox"}},
+		{"FOX lazy", []string{"fox"}},
+		{"fox AND lazy", []string{"fox"}},
+		{`"brown fox"`, []string{"fox", "foxes"}},
+		{"cat OR cheese", []string{"cheese", "cat"}},
+		{"fox -lazy", []string{"foxes", "brown"}},
+		{"fox NOT (lazy OR den)", []string{"brown"}},
+		{"NOT fox", []string{"cat", "cheese", "empty"}},
+		{"unicorn", nil},
+	} {
+		expectSearch(t, st, tc.query, tc.want...)
+	}
+
+	for _, bad := range []string{`"unterminated`, "(fox", "fox)", "!!!", "NOT"} {
+		resChan, errChan := st.(database.TextSearcher).SearchText(bad)
+		for range resChan {
+			t.Errorf("%s: expected no results", bad)
+		}
+		if err := <-errChan; !errors.Is(err, ErrBadQuery) {
+			t.Errorf("%s: expected ErrBadQuery, got %v", bad, err)
+		}
+	}
+}
+
+func TestStore_PutDelete(t *testing.T) {
+	k, st := newMemoryKeeper(t, map[string]string{"a": "red apple", "b": "green apple"})
+
+	if err := st.Put([]byte("a"), []byte("red cherry")); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, st, "apple", "b")
+	expectSearch(t, st, "cherry", "a")
+
+	if err := st.Delete([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, st, "apple")
+	expectSearch(t, st, "NOT cherry")
+
+	side := k.Keeper.With("docs" + Suffix)
+	stats, err := readStats(side)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.docs != 1 || stats.terms != 2 {
+		t.Errorf("expected 1 document of 2 terms, got %+v", stats)
+	}
+	// one posting per term, the document record and the statistics.
+	if side.Len() != 4 {
+		t.Errorf("expected stale postings to be removed, got %d records", side.Len())
+	}
+}
+
+func TestStore_PutRollback(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("docs")
+	k, err := Wrap(failKeeper{db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Enable("docs", ""); err != nil {
+		t.Fatal(err)
+	}
+	st := k.With("docs")
+	if err = st.Put([]byte("fail"), []byte("hello world")); !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	expectSearch(t, st, "hello")
+	if n := db.With("docs" + Suffix).Len(); n != 1 {
+		t.Errorf("expected only the statistics record in the sidecar store, got %d records", n)
+	}
+}
+
+func TestKeeper_Enable(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	if err := db.WithNew("docs").Put([]byte("a"), []byte(`{"title":"Hello World","body":"ignored"}`)); err != nil {
+		t.Fatal(err)
+	}
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Enable("docs", "title"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectSearch(t, k.With("docs"), "hello", "a")
+	expectSearch(t, k.With("docs"), "ignored")
+
+	if err = k.Enable("docs", ""); !errors.Is(err, ErrIndexed) {
+		t.Errorf("expected ErrIndexed, got %v", err)
+	}
+	if err = k.Enable("nope", ""); !errors.Is(err, ErrNoStore) {
+		t.Errorf("expected ErrNoStore, got %v", err)
+	}
+	meta, _ := metadata.CastToMetadata(k.Meta())
+	if meta.TextIndexes["docs"].Field != "title" {
+		t.Errorf("expected index to be recorded in metadata, got %v", meta.TextIndexes)
+	}
+
+	if err = k.Disable("docs"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if k.With("docs"+Suffix) != nil {
+		t.Error("expected sidecar store to be destroyed")
+	}
+	resChan, errChan := k.With("docs").(database.TextSearcher).SearchText("hello")
+	for range resChan {
+		t.Error("expected no results")
+	}
+	if err = <-errChan; !errors.Is(err, ErrNotIndexed) {
+		t.Errorf("expected ErrNotIndexed, got %v", err)
+	}
+}
+
+func TestKeeper_BackupRestore(t *testing.T) {
+	path := t.TempDir()
+	k, err := Wrap(leveldb.OpenDB(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.WithNew("docs").Put([]byte("before"), []byte("written before the backup")); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Enable("docs", ""); err != nil {
+		t.Fatal(err)
+	}
+	bu, err := k.BackupAll(filepath.Join(t.TempDir(), "fulltext.tar.gz"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = k.With("docs").Put([]byte("after"), []byte("written after the backup")); err != nil {
+		t.Fatal(err)
+	}
+	expectSearch(t, k.With("docs"), "written", "after", "before")
+
+	if err = k.RestoreAll(bu.Path()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectSearch(t, k.With("docs"), "written", "before")
+	if err = k.SyncAndCloseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err = Wrap(leveldb.OpenDB(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = k.CloseAll()
+	})
+	if _, err = k.Discover(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectSearch(t, k.With("docs"), "backup", "before")
+}
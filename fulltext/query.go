@@ -0,0 +1,212 @@
+package fulltext
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits text in to lowercase terms at every character that isn't a letter or a digit.
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// node is a parsed query. termNode matches a single term, or a phrase when it has more than one.
+type node interface{}
+
+type (
+	termNode []string
+	notNode  struct{ child node }
+	andNode  []node
+	orNode   []node
+)
+
+type lexKind uint8
+
+const (
+	lexWord lexKind = iota
+	lexPhrase
+	lexOpen
+	lexClose
+	lexAnd
+	lexOr
+	lexNot
+)
+
+type lexeme struct {
+	kind lexKind
+	text string
+}
+
+func lex(query string) ([]lexeme, error) {
+	var out []lexeme
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			out = append(out, lexeme{kind: lexOpen})
+			i++
+		case r == ')':
+			out = append(out, lexeme{kind: lexClose})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			out = append(out, lexeme{kind: lexNot})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unterminated phrase", ErrBadQuery)
+			}
+			out = append(out, lexeme{kind: lexPhrase, text: string(runes[i+1 : end])})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch word {
+			case "AND":
+				out = append(out, lexeme{kind: lexAnd})
+			case "OR":
+				out = append(out, lexeme{kind: lexOr})
+			case "NOT":
+				out = append(out, lexeme{kind: lexNot})
+			default:
+				out = append(out, lexeme{kind: lexWord, text: word})
+			}
+			i = end
+		}
+	}
+	return out, nil
+}
+
+// parser is a recursive descent parser for the grammar:
+//
+//	or    = and { "OR" and }
+//	and   = unary { [ "AND" ] unary }
+//	unary = ( "NOT" | "-" ) unary | "(" or ")" | phrase | word
+type parser struct {
+	lexemes []lexeme
+	pos     int
+}
+
+// parse parses a query, returning an error if it is malformed or has no terms at all.
+func parse(query string) (node, error) {
+	lexemes, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{lexemes: lexemes}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lexemes) {
+		return nil, fmt.Errorf("%w: unexpected ')'", ErrBadQuery)
+	}
+	if n == nil {
+		return nil, fmt.Errorf("%w: no terms", ErrBadQuery)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() (lexKind, bool) {
+	if p.pos >= len(p.lexemes) {
+		return 0, false
+	}
+	return p.lexemes[p.pos].kind, true
+}
+
+func (p *parser) or() (node, error) {
+	var children orNode
+	for {
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			children = append(children, n)
+		}
+		if kind, ok := p.peek(); !ok || kind != lexOr {
+			break
+		}
+		p.pos++
+	}
+	switch len(children) {
+	case 0:
+		return nil, nil
+	case 1:
+		return children[0], nil
+	}
+	return children, nil
+}
+
+func (p *parser) and() (node, error) {
+	var children andNode
+	for {
+		kind, ok := p.peek()
+		if !ok || kind == lexOr || kind == lexClose {
+			break
+		}
+		if kind == lexAnd {
+			p.pos++
+			continue
+		}
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if n != nil {
+			children = append(children, n)
+		}
+	}
+	switch len(children) {
+	case 0:
+		return nil, nil
+	case 1:
+		return children[0], nil
+	}
+	return children, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.pos >= len(p.lexemes) {
+		return nil, fmt.Errorf("%w: expected a term", ErrBadQuery)
+	}
+	lx := p.lexemes[p.pos]
+	p.pos++
+	switch lx.kind {
+	case lexNot:
+		child, err := p.unary()
+		if err != nil || child == nil {
+			return nil, err
+		}
+		return notNode{child: child}, nil
+	case lexOpen:
+		child, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if kind, ok := p.peek(); !ok || kind != lexClose {
+			return nil, fmt.Errorf("%w: missing ')'", ErrBadQuery)
+		}
+		p.pos++
+		return child, nil
+	case lexWord, lexPhrase:
+		// words that are nothing but punctuation don't produce any terms and are ignored.
+		if terms := Tokenize(lx.text); len(terms) > 0 {
+			return termNode(terms), nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: expected a term", ErrBadQuery)
+}
@@ -0,0 +1,45 @@
+package fulltext
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Hello, WORLD! e-mail über_alles 42")
+	want := []string{"hello", "world", "e", "mail", "über", "alles", "42"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  node
+	}{
+		{"fox", termNode{"fox"}},
+		{"quick fox", andNode{termNode{"quick"}, termNode{"fox"}}},
+		{`"Quick Fox" OR dog`, orNode{termNode{"quick", "fox"}, termNode{"dog"}}},
+		{"e-mail", termNode{"e", "mail"}},
+		{"-(a OR b) c", andNode{notNode{orNode{termNode{"a"}, termNode{"b"}}}, termNode{"c"}}},
+		{"a AND NOT b", andNode{termNode{"a"}, notNode{termNode{"b"}}}},
+		{"a !!! b", andNode{termNode{"a"}, termNode{"b"}}},
+	} {
+		got, err := parse(tc.query)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %#v, got %#v", tc.query, tc.want, got)
+		}
+	}
+	for _, bad := range []string{"", "   ", `"open`, "(a", "a)", "NOT", "a OR (", "()"} {
+		if _, err := parse(bad); !errors.Is(err, ErrBadQuery) {
+			t.Errorf("%q: expected ErrBadQuery, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package fulltext
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/tcp-direct/database"
+)
+
+// BM25 parameters, see https://en.wikipedia.org/wiki/Okapi_BM25.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// scores maps the keys of matching documents to their scores.
+type scores map[string]float64
+
+// evaluator evaluates a parsed query against a sidecar store, caching what it reads.
+type evaluator struct {
+	side     database.Filer
+	stats    stats
+	postings map[string]map[string][]int
+	lengths  map[string]int
+	universe scores
+}
+
+// rank returns the keys of the documents matching the query, best match first.
+func (s *Store) rank(query string) ([][]byte, error) {
+	n, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	s.state.mu.RLock()
+	defer s.state.mu.RUnlock()
+	if !s.state.enabled {
+		return nil, ErrNotIndexed
+	}
+	side, err := sidecar(s.keeper, s.name, false)
+	if err != nil {
+		return nil, err
+	}
+	st, err := readStats(side)
+	if err != nil {
+		return nil, err
+	}
+	ev := &evaluator{
+		side:     side,
+		stats:    st,
+		postings: make(map[string]map[string][]int),
+		lengths:  make(map[string]int),
+	}
+	matched, err := ev.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(matched))
+	for key := range matched {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if matched[a] != matched[b] {
+			if matched[a] > matched[b] {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	ranked := make([][]byte, len(keys))
+	for i, key := range keys {
+		ranked[i] = []byte(key)
+	}
+	return ranked, nil
+}
+
+func (ev *evaluator) eval(n node) (scores, error) {
+	switch n := n.(type) {
+	case termNode:
+		return ev.phrase(n)
+	case notNode:
+		return ev.not(nil, n.child)
+	case andNode:
+		return ev.and(n)
+	case orNode:
+		res := make(scores)
+		for _, child := range n {
+			matched, err := ev.eval(child)
+			if err != nil {
+				return nil, err
+			}
+			for key, score := range matched {
+				res[key] += score
+			}
+		}
+		return res, nil
+	}
+	return nil, ErrBadQuery
+}
+
+// and intersects the documents matched by the positive children, then removes those matched by the negated ones.
+func (ev *evaluator) and(n andNode) (scores, error) {
+	var res scores
+	var negated []node
+	for _, child := range n {
+		if not, ok := child.(notNode); ok {
+			negated = append(negated, not.child)
+			continue
+		}
+		matched, err := ev.eval(child)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			res = matched
+			continue
+		}
+		for key, score := range res {
+			if other, ok := matched[key]; ok {
+				res[key] = score + other
+			} else {
+				delete(res, key)
+			}
+		}
+	}
+	var err error
+	for _, child := range negated {
+		if res, err = ev.not(res, child); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// not removes the documents matched by n from res, or from every indexed document if res is nil.
+func (ev *evaluator) not(res scores, n node) (scores, error) {
+	if res == nil {
+		all, err := ev.all()
+		if err != nil {
+			return nil, err
+		}
+		res = make(scores, len(all))
+		for key := range all {
+			res[key] = 0
+		}
+	}
+	matched, err := ev.eval(n)
+	if err != nil {
+		return nil, err
+	}
+	for key := range matched {
+		delete(res, key)
+	}
+	return res, nil
+}
+
+// all returns every indexed document.
+func (ev *evaluator) all() (scores, error) {
+	if ev.universe != nil {
+		return ev.universe, nil
+	}
+	found, err := scan(ev.side, []byte{recDoc})
+	if err != nil {
+		return nil, err
+	}
+	ev.universe = make(scores, len(found))
+	for _, keyVal := range found {
+		ev.universe[string(keyVal.Key.Bytes()[1:])] = 0
+	}
+	return ev.universe, nil
+}
+
+// term returns the positions of a term in every document that contains it.
+func (ev *evaluator) term(term string) (map[string][]int, error) {
+	if p, ok := ev.postings[term]; ok {
+		return p, nil
+	}
+	prefix := termPrefix(term)
+	found, err := scan(ev.side, prefix)
+	if err != nil {
+		return nil, err
+	}
+	p := make(map[string][]int, len(found))
+	for _, keyVal := range found {
+		positions, decErr := decodePositions(keyVal.Value.Bytes())
+		if decErr != nil {
+			return nil, decErr
+		}
+		p[string(keyVal.Key.Bytes()[len(prefix):])] = positions
+	}
+	ev.postings[term] = p
+	return p, nil
+}
+
+// phrase scores the documents in which the terms appear one after the other. A phrase of a single term
+// matches every document that contains it.
+func (ev *evaluator) phrase(terms termNode) (scores, error) {
+	postings := make([]map[string][]int, len(terms))
+	for i, term := range terms {
+		p, err := ev.term(term)
+		if err != nil {
+			return nil, err
+		}
+		postings[i] = p
+	}
+	res := make(scores)
+	for key, starts := range postings[0] {
+		count := 0
+		for _, start := range starts {
+			if followedBy(postings[1:], key, start) {
+				count++
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		for i := range terms {
+			score, err := ev.bm25(key, count, len(postings[i]))
+			if err != nil {
+				return nil, err
+			}
+			res[key] += score
+		}
+	}
+	return res, nil
+}
+
+// followedBy returns true if each of the remaining terms appears in the document right after the one before it.
+func followedBy(rest []map[string][]int, key string, start int) bool {
+	for i, p := range rest {
+		if _, found := slices.BinarySearch(p[key], start+i+1); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// bm25 scores a document in which a term appears tf times, where the term appears in df documents.
+func (ev *evaluator) bm25(key string, tf, df int) (float64, error) {
+	length, err := ev.length(key)
+	if err != nil {
+		return 0, err
+	}
+	docs := float64(ev.stats.docs)
+	avg := 1.0
+	if ev.stats.docs > 0 && ev.stats.terms > 0 {
+		avg = float64(ev.stats.terms) / docs
+	}
+	idf := math.Log(1 + (docs-float64(df)+0.5)/(float64(df)+0.5))
+	f := float64(tf)
+	return idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(length)/avg)), nil
+}
+
+func (ev *evaluator) length(key string) (int, error) {
+	if l, ok := ev.lengths[key]; ok {
+		return l, nil
+	}
+	d, _, err := readDoc(ev.side, []byte(key))
+	if err != nil {
+		return 0, err
+	}
+	ev.lengths[key] = d.length
+	return d.length, nil
+}
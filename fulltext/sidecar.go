@@ -0,0 +1,164 @@
+package fulltext
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+// The sidecar store holds three kinds of records, told apart by their first byte:
+//
+//	'p' term key -> positions  one posting per term in each document, the term is prefixed with its length
+//	'd' key      -> doc        the length and distinct terms of each indexed document
+//	's'          -> stats      the number of indexed documents and the total number of terms in them
+const (
+	recPosting byte = 'p'
+	recDoc     byte = 'd'
+	recStats   byte = 's'
+)
+
+var statsKey = []byte{recStats}
+
+func termPrefix(term string) []byte {
+	buf := binary.AppendUvarint([]byte{recPosting}, uint64(len(term)))
+	return append(buf, term...)
+}
+
+func postingKey(term string, key []byte) []byte {
+	return append(termPrefix(term), key...)
+}
+
+func docKey(key []byte) []byte {
+	return append([]byte{recDoc}, key...)
+}
+
+// encodePositions stores the positions of a term within a document as deltas, which are never negative.
+func encodePositions(positions []int) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(positions)))
+	last := 0
+	for _, p := range positions {
+		buf = binary.AppendUvarint(buf, uint64(p-last))
+		last = p
+	}
+	return buf
+}
+
+func decodePositions(dat []byte) ([]int, error) {
+	n, read := binary.Uvarint(dat)
+	if read <= 0 || n > uint64(len(dat)) {
+		return nil, fmt.Errorf("%w: bad posting", ErrCorrupt)
+	}
+	positions := make([]int, 0, n)
+	last := 0
+	for off := read; uint64(len(positions)) < n; off += read {
+		var delta uint64
+		if delta, read = binary.Uvarint(dat[off:]); read <= 0 {
+			return nil, fmt.Errorf("%w: bad posting", ErrCorrupt)
+		}
+		last += int(delta)
+		positions = append(positions, last)
+	}
+	return positions, nil
+}
+
+// doc is the record kept for every indexed document, so that its postings can be found again
+// when it is overwritten or deleted, and so that its length is known when ranking.
+type doc struct {
+	length int
+	terms  []string
+}
+
+func (d doc) encode() []byte {
+	buf := binary.AppendUvarint(nil, uint64(d.length))
+	for _, term := range d.terms {
+		buf = binary.AppendUvarint(buf, uint64(len(term)))
+		buf = append(buf, term...)
+	}
+	return buf
+}
+
+func decodeDoc(dat []byte) (doc, error) {
+	length, read := binary.Uvarint(dat)
+	if read <= 0 {
+		return doc{}, fmt.Errorf("%w: bad document record", ErrCorrupt)
+	}
+	d := doc{length: int(length)}
+	for off := read; off < len(dat); {
+		l, n := binary.Uvarint(dat[off:])
+		if n <= 0 || uint64(len(dat)-off-n) < l {
+			return doc{}, fmt.Errorf("%w: bad document record", ErrCorrupt)
+		}
+		off += n
+		d.terms = append(d.terms, string(dat[off:off+int(l)]))
+		off += int(l)
+	}
+	return d, nil
+}
+
+type stats struct {
+	docs  uint64
+	terms uint64
+}
+
+func (st stats) encode() []byte {
+	return binary.AppendUvarint(binary.AppendUvarint(nil, st.docs), st.terms)
+}
+
+// readStats returns the sidecar's statistics, which are zero before the first document is indexed.
+func readStats(side database.Filer) (stats, error) {
+	dat, err := side.Get(statsKey)
+	if kv.IsNonExistentKey(err) {
+		return stats{}, nil
+	}
+	if err != nil {
+		return stats{}, err
+	}
+	var st stats
+	var n, m int
+	st.docs, n = binary.Uvarint(dat)
+	if n > 0 {
+		st.terms, m = binary.Uvarint(dat[n:])
+	}
+	if n <= 0 || m <= 0 {
+		return stats{}, fmt.Errorf("%w: bad statistics", ErrCorrupt)
+	}
+	return st, nil
+}
+
+// readDoc returns the record of an indexed document, and false if the key isn't indexed.
+func readDoc(side database.Filer, key []byte) (doc, bool, error) {
+	dat, err := side.Get(docKey(key))
+	if kv.IsNonExistentKey(err) {
+		return doc{}, false, nil
+	}
+	if err != nil {
+		return doc{}, false, err
+	}
+	d, err := decodeDoc(dat)
+	return d, err == nil, err
+}
+
+// analyze returns the positions of each distinct term in tokens.
+func analyze(tokens []string) map[string][]int {
+	positions := make(map[string][]int)
+	for i, term := range tokens {
+		positions[term] = append(positions[term], i)
+	}
+	return positions
+}
+
+// scan returns every record in the sidecar store that starts with prefix.
+func scan(side database.Filer, prefix []byte) ([]kv.KeyValue, error) {
+	searcher, ok := side.(database.Searcher)
+	if !ok {
+		return nil, ErrNotSearcher
+	}
+	resChan, errChan := searcher.PrefixScan(string(prefix))
+	var found []kv.KeyValue
+	for keyVal := range resChan {
+		found = append(found, keyVal)
+	}
+	return found, <-errChan
+}
@@ -0,0 +1,217 @@
+package fulltext
+
+import (
+	"context"
+	"slices"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.TextSearcher = (*Store)(nil)
+
+// Store wraps a Filer of a [Keeper], keeping its full-text index up to date as it is written to.
+// It implements [database.TextSearcher], passing the methods of [database.Searcher] through to the
+// underlying Filer. Other backend specific methods are reached through [Store.Unwrap].
+type Store struct {
+	wrap.Filer
+	keeper database.Keeper
+	name   string
+	state  *state
+}
+
+// Unwrap returns the underlying Filer. Writes made to it directly are not indexed.
+func (s *Store) Unwrap() database.Filer {
+	return s.Filer.Filer
+}
+
+// indexOps returns the sidecar writes that index a document made up of tokens, replacing the postings
+// of old, the document's previous record, if it was already indexed.
+func indexOps(key []byte, tokens []string, old *doc) []journal.Op {
+	positions := analyze(tokens)
+	var ops []journal.Op
+	if old != nil {
+		for _, term := range old.terms {
+			if _, ok := positions[term]; !ok {
+				ops = append(ops, journal.Op{Key: postingKey(term, key), Delete: true})
+			}
+		}
+	}
+	terms := make([]string, 0, len(positions))
+	for term := range positions {
+		terms = append(terms, term)
+	}
+	slices.Sort(terms)
+	for _, term := range terms {
+		ops = append(ops, journal.Op{Key: postingKey(term, key), Value: encodePositions(positions[term])})
+	}
+	return append(ops, journal.Op{Key: docKey(key), Value: doc{length: len(tokens), terms: terms}.encode()})
+}
+
+// removeOps returns the sidecar writes that remove an indexed document.
+func removeOps(key []byte, old doc) []journal.Op {
+	ops := make([]journal.Op, 0, len(old.terms)+1)
+	for _, term := range old.terms {
+		ops = append(ops, journal.Op{Key: postingKey(term, key), Delete: true})
+	}
+	return append(ops, journal.Op{Key: docKey(key), Delete: true})
+}
+
+// write applies a Put, or a Delete if del is true, to the store together with the matching index updates.
+func (s *Store) write(key []byte, value []byte, del bool) error {
+	side, err := sidecar(s.keeper, s.name, false)
+	if err != nil {
+		return err
+	}
+	old, indexed, err := readDoc(side, key)
+	if err != nil {
+		return err
+	}
+	st, err := readStats(side)
+	if err != nil {
+		return err
+	}
+	var ops []journal.Op
+	if indexed {
+		st.docs--
+		st.terms -= uint64(old.length)
+	}
+	switch {
+	case del && indexed:
+		ops = removeOps(key, old)
+	case !del:
+		tokens := s.state.tokens(key, value)
+		var prev *doc
+		if indexed {
+			prev = &old
+		}
+		ops = indexOps(key, tokens, prev)
+		st.docs++
+		st.terms += uint64(len(tokens))
+	}
+	ops = append(ops, journal.Op{Key: statsKey, Value: st.encode()})
+	for i := range ops {
+		ops[i].Key = routeKey(routeSide, ops[i].Key)
+	}
+	ops = append(ops, journal.Op{Key: routeKey(routeData, key), Value: value, Delete: del})
+	return journal.New(s.state.journalPath, &target{data: s.Filer, side: side}).Apply(ops)
+}
+
+// Put inserts the value at the given key and indexes it.
+func (s *Store) Put(key []byte, value []byte) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if !s.state.enabled {
+		return s.Filer.Put(key, value)
+	}
+	return s.write(key, value, false)
+}
+
+// Delete deletes the given key and removes it from the index.
+func (s *Store) Delete(key []byte) error {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+	if !s.state.enabled {
+		return s.Filer.Delete(key)
+	}
+	return s.write(key, nil, true)
+}
+
+// Sync syncs the store and its sidecar store, if it has one.
+func (s *Store) Sync() error {
+	if err := s.Filer.Sync(); err != nil {
+		return err
+	}
+	if side := s.keeper.With(s.name + Suffix); side != nil {
+		return side.Sync()
+	}
+	return nil
+}
+
+// SearchText streams the key/value pairs matching the full-text query, best match first.
+func (s *Store) SearchText(query string) (<-chan kv.KeyValue, chan error) {
+	return s.SearchTextContext(context.Background(), query)
+}
+
+// SearchTextContext is like SearchText, but stops once ctx is done.
+// The query is evaluated against the index before anything is sent, values are read as they are sent.
+func (s *Store) SearchTextContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		keys, err := s.rank(query)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, key := range keys {
+			value, getErr := s.Filer.Get(key)
+			if kv.IsNonExistentKey(getErr) {
+				continue
+			}
+			if getErr != nil {
+				errChan <- getErr
+				return
+			}
+			if !kv.Send(ctx, resChan, kv.NewKeyValueFromBytes(key, value)) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return resChan, errChan
+}
+
+const (
+	routeData byte = iota
+	routeSide
+)
+
+func routeKey(route byte, key []byte) []byte {
+	return append([]byte{route}, key...)
+}
+
+// target lets a single journal cover a store and its sidecar store, the first byte of each key picks which.
+type target struct {
+	data database.Filer
+	side database.Filer
+}
+
+func (t *target) route(key []byte) (database.Filer, []byte) {
+	if key[0] == routeSide {
+		return t.side, key[1:]
+	}
+	return t.data, key[1:]
+}
+
+func (t *target) Get(key []byte) ([]byte, error) {
+	f, k := t.route(key)
+	v, err := f.Get(k)
+	if err != nil && !f.Has(k) {
+		return nil, kv.RegularizeKVError(k, nil, nil)
+	}
+	return v, err
+}
+
+func (t *target) Put(key []byte, value []byte) error {
+	f, k := t.route(key)
+	return f.Put(k, value)
+}
+
+func (t *target) Delete(key []byte) error {
+	f, k := t.route(key)
+	return f.Delete(k)
+}
+
+func (t *target) Sync() error {
+	if err := t.side.Sync(); err != nil {
+		return err
+	}
+	return t.data.Sync()
+}
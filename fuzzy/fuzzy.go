@@ -0,0 +1,174 @@
+// Package fuzzy finds the keys of a store that are similar to a misspelled or partial one.
+//
+// Keys are compared with a query by their Levenshtein distance, the number of single character edits that
+// turn one in to the other, and by their trigram score, the share of the query's trigrams that the key
+// also contains. The distance catches typos, the score catches partial keys such as the start of a hostname.
+//
+// [Find] compares every key of a store by default. Stores handed out by an [index.Keeper] on which
+// [Enable] has been called keep a trigram index in their companion store instead, and only the keys sharing
+// at least one trigram with the query are compared.
+package fuzzy
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/index"
+)
+
+// DefaultMaxDistance is used as [Options.MaxDistance] when neither it nor [Options.MinScore] is set.
+const DefaultMaxDistance = 2
+
+// ErrEmptyQuery is returned by [Find] when the query is empty.
+var ErrEmptyQuery = errors.New("empty fuzzy query")
+
+// Options sets which keys [Find] returns. A key is returned if it is within MaxDistance or reaches MinScore.
+type Options struct {
+	// MaxDistance is the largest Levenshtein distance between a returned key and the query, zero to not
+	// match keys by distance.
+	MaxDistance int
+	// MinScore is the smallest trigram score, between 0 and 1, of a returned key, zero to not match keys by score.
+	MinScore float64
+	// Limit stops Find after this many of the best keys, zero or less means no limit.
+	Limit int
+	// FoldCase compares keys and the query without regard to case.
+	FoldCase bool
+}
+
+// Match is a key returned by [Find], along with how close it is to the query.
+type Match struct {
+	Key      []byte
+	Distance int
+	Score    float64
+}
+
+// Find returns the keys of f similar to query, best first: by score, then by distance, then in byte order.
+func Find(f database.Filer, query string, opts Options) ([]Match, error) {
+	if query == "" {
+		return nil, ErrEmptyQuery
+	}
+	if opts.MaxDistance <= 0 && opts.MinScore <= 0 {
+		opts.MaxDistance = DefaultMaxDistance
+	}
+	keys, err := candidates(f, query)
+	if err != nil {
+		return nil, err
+	}
+	fold := func(s string) string {
+		if opts.FoldCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	q := fold(query)
+	queryTrigrams := trigrams(query, opts.FoldCase)
+	matches := make([]Match, 0)
+	for _, key := range keys {
+		k := fold(string(key))
+		m := Match{Key: key, Distance: Levenshtein(q, k), Score: score(queryTrigrams, trigrams(k, opts.FoldCase))}
+		if (opts.MaxDistance > 0 && m.Distance <= opts.MaxDistance) || (opts.MinScore > 0 && m.Score >= opts.MinScore) {
+			matches = append(matches, m)
+		}
+	}
+	slices.SortFunc(matches, func(a, b Match) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		if a.Distance != b.Distance {
+			return a.Distance - b.Distance
+		}
+		return strings.Compare(string(a.Key), string(b.Key))
+	})
+	if opts.Limit > 0 && len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+	return matches, nil
+}
+
+// candidates returns the keys of f sharing a trigram with query if f keeps a trigram index, or every key otherwise.
+// The index holds lower cased trigrams, so the keys sharing one regardless of case are returned.
+func candidates(f database.Filer, query string) ([][]byte, error) {
+	ix, ok := f.(database.Indexer)
+	if !ok {
+		return f.Keys(), nil
+	}
+	seen := make(map[string]struct{})
+	var keys [][]byte
+	for _, tri := range Trigrams(query) {
+		found, err := ix.Lookup(IndexName, []byte(tri))
+		if errors.Is(err, index.ErrNoIndex) {
+			return f.Keys(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range found {
+			if _, dup := seen[string(key)]; !dup {
+				seen[string(key)] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Levenshtein returns the number of single character insertions, deletions and substitutions that turn a in to b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Trigrams returns the distinct, sorted runs of three characters in s, lower cased. s is padded with two spaces
+// at the start and one at the end, so that short strings have trigrams and the ends of s weigh more.
+func Trigrams(s string) []string {
+	return trigrams(s, true)
+}
+
+// trigrams returns the trigrams of s like [Trigrams], only lower casing s if fold is true.
+func trigrams(s string, fold bool) []string {
+	if fold {
+		s = strings.ToLower(s)
+	}
+	r := []rune("  " + s + " ")
+	tris := make([]string, 0, len(r)-2)
+	for i := 0; i+3 <= len(r); i++ {
+		tris = append(tris, string(r[i:i+3]))
+	}
+	slices.Sort(tris)
+	return slices.Compact(tris)
+}
+
+// score returns the share of the query's trigrams that are also in the key's, both being sorted.
+func score(query, key []string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	shared := 0
+	for _, tri := range query {
+		if _, found := slices.BinarySearch(key, tri); found {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(query))
+}
@@ -0,0 +1,166 @@
+package fuzzy
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/index"
+	"github.com/tcp-direct/database/memory"
+)
+
+var hosts = []string{
+	"web01.example.com",
+	"web02.example.com",
+	"db01.example.com",
+	"mail.example.org",
+	"alice",
+	"Alicia",
+	"bob",
+}
+
+func matchKeys(matches []Match) []string {
+	keys := make([]string, 0, len(matches))
+	for _, m := range matches {
+		keys = append(keys, string(m.Key))
+	}
+	return keys
+}
+
+func fill(t *testing.T, st database.Filer) {
+	t.Helper()
+	for _, host := range hosts {
+		if err := st.Put([]byte(host), []byte("up")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"héllo", "hello", 1},
+		{"same", "same", 0},
+	} {
+		if got := Levenshtein(tc.a, tc.b); got != tc.want {
+			t.Errorf("%q, %q: expected %d, got %d", tc.a, tc.b, tc.want, got)
+		}
+		if got := Levenshtein(tc.b, tc.a); got != tc.want {
+			t.Errorf("%q, %q: expected distance to be symmetric, got %d", tc.b, tc.a, got)
+		}
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	if got, want := Trigrams("Abab"), []string{"  a", " ab", "ab ", "aba", "bab"}; !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got, want := trigrams("Abab", false), []string{"  A", " Ab", "Aba", "ab ", "bab"}; !slices.Equal(got, want) {
+		t.Errorf("expected %q without folding case, got %q", want, got)
+	}
+	if got := Trigrams(""); !slices.Equal(got, []string{"   "}) {
+		t.Errorf("expected the padding trigram, got %q", got)
+	}
+}
+
+func TestFind(t *testing.T) {
+	st := memory.OpenDB(t.TempDir()).WithNew("hosts")
+	fill(t, st)
+
+	for _, tc := range []struct {
+		query string
+		opts  Options
+		want  []string
+	}{
+		{"web01.exmaple.com", Options{}, []string{"web01.example.com"}},
+		{"web01.exmaple.com", Options{MaxDistance: 3}, []string{"web01.example.com", "web02.example.com"}},
+		{"web01.exmaple.com", Options{MaxDistance: 3, Limit: 1}, []string{"web01.example.com"}},
+		{"web0", Options{MinScore: 0.8}, []string{"web01.example.com", "web02.example.com"}},
+		{"alicia", Options{}, []string{"Alicia", "alice"}},
+		{"ALICE", Options{MaxDistance: 1}, []string{}},
+		{"ALICE", Options{MaxDistance: 1, FoldCase: true}, []string{"alice"}},
+		{"ALICE", Options{MinScore: 0.5}, []string{}},
+		{"ALICE", Options{MinScore: 0.5, FoldCase: true}, []string{"alice", "Alicia"}},
+		{"zzz", Options{MaxDistance: 1}, []string{}},
+	} {
+		matches, err := Find(st, tc.query, tc.opts)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tc.query, err)
+		}
+		if got := matchKeys(matches); !slices.Equal(got, tc.want) {
+			t.Errorf("%s %+v: expected %q, got %q", tc.query, tc.opts, tc.want, got)
+		}
+	}
+
+	matches, err := Find(st, "bob", Options{})
+	if err != nil || len(matches) == 0 || matches[0].Distance != 0 || matches[0].Score != 1 {
+		t.Errorf("expected an exact match first, got %+v (%v)", matches, err)
+	}
+	if _, err = Find(st, "", Options{}); !errors.Is(err, ErrEmptyQuery) {
+		t.Errorf("expected ErrEmptyQuery, got %v", err)
+	}
+}
+
+func TestEnable(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("hosts")
+	k, err := index.Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := k.With("hosts")
+	fill(t, st)
+
+	if err = Enable(k, "hosts"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	keys, err := st.(database.Indexer).Lookup(IndexName, []byte("web"))
+	if err != nil || !slices.Equal(matchKeysSorted(keys), []string{"web01.example.com", "web02.example.com"}) {
+		t.Errorf("expected keys indexed by trigram, got %q (%v)", keys, err)
+	}
+
+	if err = st.Put([]byte("web03.example.com"), []byte("up")); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Delete([]byte("web02.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	matches, err := Find(st, "web0", Options{MinScore: 0.8})
+	if want := []string{"web01.example.com", "web03.example.com"}; err != nil || !slices.Equal(matchKeys(matches), want) {
+		t.Errorf("expected %q, got %q (%v)", want, matchKeys(matches), err)
+	}
+	// the index is lower cased, but the candidates it offers are still compared with regard to case.
+	if matches, err = Find(st, "ALICE", Options{MinScore: 0.5}); err != nil || len(matches) != 0 {
+		t.Errorf("expected no match without folding case, got %q (%v)", matchKeys(matches), err)
+	}
+	if matches, err = Find(st, "ALICE", Options{MinScore: 0.5, FoldCase: true}); err != nil || len(matches) != 2 {
+		t.Errorf("expected matches when folding case, got %q (%v)", matchKeys(matches), err)
+	}
+	// "bob" shares no trigram with "xyz", so the index doesn't offer it as a candidate.
+	if matches, err = Find(st, "xyz", Options{MaxDistance: 3}); err != nil || slices.Contains(matchKeys(matches), "bob") {
+		t.Errorf("expected only keys sharing a trigram, got %q (%v)", matchKeys(matches), err)
+	}
+
+	if err = Disable(k, "hosts"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if matches, err = Find(st, "xyz", Options{MaxDistance: 3}); err != nil || !slices.Contains(matchKeys(matches), "bob") {
+		t.Errorf("expected every key to be compared without the index, got %q (%v)", matchKeys(matches), err)
+	}
+}
+
+func matchKeysSorted(keys [][]byte) []string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, string(k))
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,39 @@
+package fuzzy
+
+import (
+	"github.com/tcp-direct/database/index"
+	"github.com/tcp-direct/database/metadata"
+)
+
+const (
+	// IndexName is the name of the trigram index that [Enable] defines.
+	IndexName = "fuzzy_trigrams"
+	// ExtractorName is the name that the trigram [index.Extractor] is registered under.
+	ExtractorName = "fuzzy.trigrams"
+)
+
+func init() {
+	index.RegisterExtractor(ExtractorName, KeyTrigrams)
+}
+
+// KeyTrigrams is an [index.Extractor] that indexes a key/value pair under the [Trigrams] of its key.
+func KeyTrigrams(key, _ []byte) ([][]byte, error) {
+	tris := Trigrams(string(key))
+	values := make([][]byte, len(tris))
+	for i, tri := range tris {
+		values[i] = []byte(tri)
+	}
+	return values, nil
+}
+
+// Enable defines a trigram index on the given store of k, so that [Find] only compares the keys of the
+// store's [index.Store] that share a trigram with the query rather than every key. Keys sharing no trigram
+// with the query, which are only possible within a distance of the query's length, are no longer found.
+func Enable(k *index.Keeper, store string) error {
+	return k.Define(store, metadata.Index{Name: IndexName, Extractor: ExtractorName})
+}
+
+// Disable drops the trigram index from the given store of k.
+func Disable(k *index.Keeper, store string) error {
+	return k.Drop(store, IndexName)
+}
@@ -1,24 +1,43 @@
 module github.com/tcp-direct/database
 
-go 1.21
+go 1.23.0
 
-toolchain go1.22.4
+toolchain go1.23.4
 
 require (
 	git.mills.io/prologic/bitcask v1.0.2
 	git.tcp.direct/kayos/common v0.9.9
 	github.com/akrylysov/pogreb v0.10.2
 	github.com/davecgh/go-spew v1.1.1
+	github.com/klauspost/compress v1.17.9
+	github.com/plar/go-adaptive-radix-tree v1.0.4
+	github.com/syndtr/goleveldb v1.0.1-0.20220721030215-126854af5e6d
+	github.com/ulikunitz/xz v0.5.12
+	go.etcd.io/bbolt v1.3.10
+	golang.org/x/crypto v0.24.0
+	modernc.org/sqlite v1.29.10
 )
 
 require (
 	github.com/abcum/lcp v0.0.0-20201209214815-7a3f3840be81 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
 	github.com/gofrs/flock v0.8.0 // indirect
+	github.com/golang/snappy v0.0.4 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/plar/go-adaptive-radix-tree v1.0.4 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	github.com/sirupsen/logrus v1.8.1 // indirect
-	golang.org/x/exp v0.0.0-20200228211341-fcea875c7e85 // indirect
+	golang.org/x/exp v0.0.0-20231108232855-2478ac86f678 // indirect
 	golang.org/x/sys v0.21.0 // indirect
+	modernc.org/gc/v3 v3.0.0-20240107210532-573471604cb6 // indirect
+	modernc.org/libc v1.49.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
+	modernc.org/strutil v1.2.0 // indirect
+	modernc.org/token v1.1.0 // indirect
 	nullprogram.com/x/rng v1.1.0 // indirect
 )
 
@@ -0,0 +1,82 @@
+package index
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Extractor returns the values that a key/value pair is indexed under. A pair may be indexed under any number
+// of values, including none.
+type Extractor func(key, value []byte) ([][]byte, error)
+
+var (
+	extractors = make(map[string]Extractor)
+	extractMu  = &sync.RWMutex{}
+)
+
+// RegisterExtractor registers fn under the given name so that indexes can refer to it with
+// [metadata.Index.Extractor]. Extractors must be registered again each time the program starts,
+// before the indexes using them are reattached.
+func RegisterExtractor(name string, fn Extractor) {
+	extractMu.Lock()
+	extractors[name] = fn
+	extractMu.Unlock()
+}
+
+func getExtractor(name string) Extractor {
+	extractMu.RLock()
+	fn := extractors[name]
+	extractMu.RUnlock()
+	return fn
+}
+
+// JSONField returns an Extractor for the JSON field at the given dot separated path, e.g. "user.email".
+// Arrays along the path are descended in to element by element, so a pair is indexed under every value found.
+// Strings are indexed as their contents, numbers and booleans as their JSON text. Values that aren't JSON
+// documents, or that don't have the field, aren't indexed.
+func JSONField(path string) Extractor {
+	fields := strings.Split(path, ".")
+	return func(_, value []byte) ([][]byte, error) {
+		var doc any
+		dec := json.NewDecoder(bytes.NewReader(value))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, nil
+		}
+		var found [][]byte
+		collect(doc, fields, &found)
+		return found, nil
+	}
+}
+
+func collect(node any, fields []string, found *[][]byte) {
+	switch n := node.(type) {
+	case []any:
+		for _, elem := range n {
+			collect(elem, fields, found)
+		}
+		return
+	case map[string]any:
+		if len(fields) == 0 {
+			return
+		}
+		if next, ok := n[fields[0]]; ok {
+			collect(next, fields[1:], found)
+		}
+		return
+	}
+	if len(fields) > 0 {
+		return
+	}
+	switch n := node.(type) {
+	case string:
+		*found = append(*found, []byte(n))
+	case json.Number:
+		*found = append(*found, []byte(n.String()))
+	case bool:
+		*found = append(*found, []byte(strconv.FormatBool(n)))
+	}
+}
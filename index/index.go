@@ -0,0 +1,389 @@
+// Package index maintains secondary indexes over the values of any [database.Keeper]'s stores.
+//
+// An index maps the values produced by a JSON field or a registered [Extractor] back to the keys they were
+// produced from. Entries are kept in a companion store named after the indexed store with [Suffix] appended,
+// within the same Keeper, and are updated whenever Put or Delete is called through the Filers handed out
+// by [Keeper]. Index definitions are recorded in the Keeper's [metadata.Metadata], so that [Keeper.Discover]
+// can reattach them, or rebuild them if their companion store is missing.
+//
+// Entries for a new value are written before the value itself and stale entries are removed after it,
+// so an index may briefly hold entries that no longer match, but never misses one. [Store.Lookup] checks
+// every candidate against the stored value and discards stale entries as it finds them.
+//
+// Writes made directly to the Keeper's stores are not indexed, [Keeper.Rebuild] brings an index back in line
+// with its store afterwards.
+package index
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+)
+
+// Suffix is appended to the name of an indexed store to name its companion store.
+const Suffix = "_index"
+
+var (
+	// ErrNoStore is returned when an index is defined on a store that isn't open.
+	ErrNoStore = wrap.ErrNoStore
+	// ErrBadIndex is returned when an index definition doesn't have a name, or doesn't have exactly one of
+	// a field or an extractor.
+	ErrBadIndex = errors.New("index needs a name and either a field or an extractor")
+	// ErrIndexExists is returned when defining an index with the same name as an existing one on the store.
+	ErrIndexExists = errors.New("index already exists")
+	// ErrNoIndex is returned when referring to an index that isn't defined on the store.
+	ErrNoIndex = errors.New("index not found")
+	// ErrUnknownExtractor is returned when an index refers to an extractor that hasn't been registered.
+	ErrUnknownExtractor = errors.New("extractor not registered")
+	// ErrNoMetadata is returned when defining an index on a Keeper without [metadata.Metadata] to record it in.
+	ErrNoMetadata = wrap.ErrNoMetadata
+	// ErrNotOrdered is returned when looking up values by prefix in an index that isn't ordered.
+	ErrNotOrdered = errors.New("index is not ordered")
+	// ErrNotSearcher is returned when a store that needs to be scanned doesn't implement [database.Searcher].
+	ErrNotSearcher = database.ErrNotSearcher
+	// ErrNotKeySearcher is returned when a store that needs to be scanned by key doesn't implement
+	// [database.KeySearcher].
+	ErrNotKeySearcher = errors.New("store does not implement database.KeySearcher")
+)
+
+// Keeper wraps a [database.Keeper], maintaining the indexes defined on its stores.
+// Filers returned by With, WithNew and AllStores are [Store]s. Discover reattaches or rebuilds the indexes
+// recorded in the metadata, and Destroy removes a store's companion store and index definitions along with it.
+type Keeper struct {
+	wrap.Keeper
+	// mu guards indexes and changes to the index definitions in the Keeper's metadata.
+	mu      sync.Mutex
+	indexes map[string]*indexes
+}
+
+// indexes holds the attached indexes of a single store. Writes to the store hold mu exclusively,
+// lookups hold it shared.
+type indexes struct {
+	mu   sync.RWMutex
+	defs []index
+}
+
+type index struct {
+	metadata.Index
+	extract Extractor
+}
+
+func (st *indexes) find(name string) *index {
+	for i := range st.defs {
+		if st.defs[i].Name == name {
+			return &st.defs[i]
+		}
+	}
+	return nil
+}
+
+// entries returns the companion store keys of every index entry for the key/value pair.
+func (st *indexes) entries(key, value []byte) (map[string]struct{}, error) {
+	found := make(map[string]struct{})
+	for _, idx := range st.defs {
+		values, err := idx.extract(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("index %s: %w", idx.Name, err)
+		}
+		for _, v := range values {
+			found[string(idx.entryKey(v, key))] = struct{}{}
+		}
+	}
+	return found, nil
+}
+
+// Wrap adds secondary indexes to the given Keeper, reattaching any indexes recorded in its metadata
+// for the stores that are already open.
+func Wrap(keeper database.Keeper) (*Keeper, error) {
+	k := &Keeper{indexes: make(map[string]*indexes)}
+	k.Keeper = wrap.NewKeeper(keeper, wrap.Hooks{Wrap: k.wrap, Attach: k.attach, Forget: k.forget})
+	if err := k.attach(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// state returns the indexes of the given store, caller must hold k.mu.
+func (k *Keeper) state(store string) *indexes {
+	st, ok := k.indexes[store]
+	if !ok {
+		st = &indexes{}
+		k.indexes[store] = st
+	}
+	return st
+}
+
+func (k *Keeper) wrap(name string, f database.Filer) database.Filer {
+	k.mu.Lock()
+	st := k.state(name)
+	k.mu.Unlock()
+	return &Store{Filer: wrap.Filer{Filer: f}, keeper: k.Unwrap(), name: name, indexes: st}
+}
+
+// attach resolves the indexes recorded in the Keeper's metadata for every open store,
+// rebuilding those whose companion store doesn't exist.
+func (k *Keeper) attach() error {
+	meta, ok := wrap.Recorded(k.Unwrap())
+	if !ok {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var errs []error
+	for store, defs := range meta.Indexes {
+		data := k.Unwrap().With(store)
+		if data == nil {
+			continue
+		}
+		st := k.state(store)
+		st.mu.Lock()
+		rebuild := k.Unwrap().With(store+Suffix) == nil
+		for _, def := range defs {
+			if st.find(def.Name) != nil {
+				continue
+			}
+			idx, resolveErr := resolve(def)
+			if resolveErr != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", store, resolveErr))
+				continue
+			}
+			st.defs = append(st.defs, idx)
+		}
+		if rebuild {
+			if err := build(k.Unwrap(), store, data, st.defs...); err != nil {
+				errs = append(errs, fmt.Errorf("%s: failed to rebuild indexes: %w", store, err))
+			}
+		}
+		st.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func resolve(def metadata.Index) (index, error) {
+	if def.Name == "" || (def.Field == "") == (def.Extractor == "") {
+		return index{}, ErrBadIndex
+	}
+	if def.Field != "" {
+		return index{Index: def, extract: JSONField(def.Field)}, nil
+	}
+	fn := getExtractor(def.Extractor)
+	if fn == nil {
+		return index{}, fmt.Errorf("%w: %s", ErrUnknownExtractor, def.Extractor)
+	}
+	return index{Index: def, extract: fn}, nil
+}
+
+// Define adds an index to the given store, records it in the Keeper's metadata and indexes the store's
+// existing keys.
+func (k *Keeper) Define(store string, def metadata.Index) error {
+	meta, err := wrap.Metadata(k.Unwrap())
+	if err != nil {
+		return err
+	}
+	idx, err := resolve(def)
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data := k.Unwrap().With(store)
+	if data == nil {
+		return fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.find(def.Name) != nil {
+		return fmt.Errorf("%w: %s", ErrIndexExists, def.Name)
+	}
+	if err = build(k.Unwrap(), store, data, idx); err != nil {
+		return err
+	}
+	st.defs = append(st.defs, idx)
+	meta.AddIndex(store, def)
+	return k.Unwrap().SyncAll()
+}
+
+// Drop removes the named index from the given store, along with its entries and its definition.
+func (k *Keeper) Drop(store, name string) error {
+	meta, err := wrap.Metadata(k.Unwrap())
+	if err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.find(name) == nil {
+		return fmt.Errorf("%w: %s", ErrNoIndex, name)
+	}
+	if c := k.Unwrap().With(store + Suffix); c != nil {
+		if err = clearPrefix(c, indexPrefix(name)); err != nil {
+			return err
+		}
+	}
+	for i := range st.defs {
+		if st.defs[i].Name == name {
+			st.defs = append(st.defs[:i], st.defs[i+1:]...)
+			break
+		}
+	}
+	meta.RemoveIndex(store, name)
+	return k.Unwrap().SyncAll()
+}
+
+// Rebuild discards the entries of every index on the given store and indexes the store's keys again.
+func (k *Keeper) Rebuild(store string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	data := k.Unwrap().With(store)
+	if data == nil {
+		return fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	st := k.state(store)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return build(k.Unwrap(), store, data, st.defs...)
+}
+
+// build clears the given indexes and indexes every key in data under them, creating the companion store if needed.
+func build(keeper database.Keeper, store string, data database.Filer, defs ...index) error {
+	if len(defs) == 0 {
+		return nil
+	}
+	c, err := companion(keeper, store, true)
+	if err != nil {
+		return err
+	}
+	st := &indexes{defs: defs}
+	for _, idx := range defs {
+		if err = clearPrefix(c, indexPrefix(idx.Name)); err != nil {
+			return err
+		}
+	}
+	for _, key := range data.Keys() {
+		value, getErr := data.Get(key)
+		if kv.IsNonExistentKey(getErr) {
+			continue
+		}
+		if getErr != nil {
+			return getErr
+		}
+		entries, entErr := st.entries(key, value)
+		if entErr != nil {
+			return entErr
+		}
+		for entry := range entries {
+			if err = c.Put([]byte(entry), key); err != nil {
+				return err
+			}
+		}
+	}
+	return c.Sync()
+}
+
+// companion returns the companion store of the given store, initializing it if create is true.
+func companion(keeper database.Keeper, store string, create bool) (database.Filer, error) {
+	return wrap.Sidecar(keeper, store+Suffix, create)
+}
+
+// scan returns every entry in the companion store that starts with prefix.
+func scan(c database.Filer, prefix []byte) ([]kv.KeyValue, error) {
+	searcher, ok := c.(database.Searcher)
+	if !ok {
+		return nil, ErrNotSearcher
+	}
+	resChan, errChan := searcher.PrefixScan(string(prefix))
+	var found []kv.KeyValue
+	for keyVal := range resChan {
+		found = append(found, keyVal)
+	}
+	return found, <-errChan
+}
+
+func clearPrefix(c database.Filer, prefix []byte) error {
+	found, err := scan(c, prefix)
+	if err != nil {
+		return err
+	}
+	for _, keyVal := range found {
+		if err = c.Delete(keyVal.Key.Bytes()); err != nil && !kv.IsNonExistentKey(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexPrefix returns the prefix shared by every entry of the named index, the index name prefixed with its length.
+func indexPrefix(name string) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(name)))
+	return append(buf, name...)
+}
+
+// valuePrefix returns the prefix shared by every entry of the named index for the given value.
+func valuePrefix(name string, value []byte) []byte {
+	buf := binary.AppendUvarint(indexPrefix(name), uint64(len(value)))
+	return append(buf, value...)
+}
+
+// scanPrefix returns the prefix shared by the entries of the index for the given value. For ordered indexes
+// it is also shared by the entries for every value that starts with it.
+func (idx *index) scanPrefix(value []byte) []byte {
+	if idx.Ordered {
+		return append(indexPrefix(idx.Name), value...)
+	}
+	return valuePrefix(idx.Name, value)
+}
+
+// entryKey returns the companion store key of an index entry. The key being indexed is also stored as
+// the entry's value, so it doesn't need to be decoded.
+//
+// Entries of ordered indexes leave out the length of the value so that they sort by it, and end with the
+// length of the key instead, which keeps the entries of different values and keys apart.
+func (idx *index) entryKey(value, key []byte) []byte {
+	if !idx.Ordered {
+		return append(valuePrefix(idx.Name, value), key...)
+	}
+	buf := append(indexPrefix(idx.Name), value...)
+	buf = append(buf, key...)
+	return binary.BigEndian.AppendUint32(buf, uint32(len(key)))
+}
+
+// entryValue returns the value of an ordered index's entry for key, or nil if the entry is malformed.
+func (idx *index) entryValue(entry, key []byte) []byte {
+	start := len(indexPrefix(idx.Name))
+	end := len(entry) - 4 - len(key)
+	if end < start || binary.BigEndian.Uint32(entry[len(entry)-4:]) != uint32(len(key)) ||
+		!bytes.Equal(entry[end:len(entry)-4], key) {
+		return nil
+	}
+	return entry[start:end]
+}
+
+// forget removes the companion store and index definitions of a destroyed store.
+func (k *Keeper) forget(name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	st, ok := k.indexes[name]
+	if !ok || len(st.defs) == 0 {
+		return nil
+	}
+	delete(k.indexes, name)
+	var errs []error
+	if k.Unwrap().With(name+Suffix) != nil {
+		errs = append(errs, k.Unwrap().Destroy(name+Suffix))
+	}
+	if meta, err := wrap.Metadata(k.Unwrap()); err == nil {
+		delete(meta.Indexes, name)
+		errs = append(errs, k.Unwrap().SyncAll())
+	}
+	return errors.Join(errs...)
+}
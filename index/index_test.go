@@ -0,0 +1,336 @@
+package index
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/bitcask"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/memory"
+	"github.com/tcp-direct/database/metadata"
+)
+
+var emailIndex = metadata.Index{Name: "email", Field: "user.email"}
+
+func sortedKeys(keys [][]byte) []string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, string(k))
+	}
+	slices.Sort(names)
+	return names
+}
+
+func expectLookup(t *testing.T, st database.Filer, index, value string, want ...string) {
+	t.Helper()
+	keys, err := st.(database.Indexer).Lookup(index, []byte(value))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := sortedKeys(keys); !slices.Equal(got, want) {
+		t.Errorf("%s=%s: expected keys %v, got %v", index, value, want, got)
+	}
+}
+
+func newMemoryKeeper(t *testing.T) *Keeper {
+	t.Helper()
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("users")
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeeper_Define(t *testing.T) {
+	k := newMemoryKeeper(t)
+	st := k.With("users")
+	if err := st.Put([]byte("alice"), []byte(`{"user":{"email":"alice@example.com"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := k.Define("users", emailIndex); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectLookup(t, st, "email", "alice@example.com", "alice")
+
+	if err := k.Define("users", emailIndex); !errors.Is(err, ErrIndexExists) {
+		t.Errorf("expected ErrIndexExists, got %v", err)
+	}
+	if err := k.Define("nope", emailIndex); !errors.Is(err, ErrNoStore) {
+		t.Errorf("expected ErrNoStore, got %v", err)
+	}
+	if err := k.Define("users", metadata.Index{Name: "bad"}); !errors.Is(err, ErrBadIndex) {
+		t.Errorf("expected ErrBadIndex, got %v", err)
+	}
+	if err := k.Define("users", metadata.Index{Name: "fn", Extractor: "missing"}); !errors.Is(err, ErrUnknownExtractor) {
+		t.Errorf("expected ErrUnknownExtractor, got %v", err)
+	}
+	meta, _ := metadata.CastToMetadata(k.Meta())
+	if len(meta.Indexes["users"]) != 1 {
+		t.Errorf("expected index to be recorded in metadata, got %v", meta.Indexes)
+	}
+}
+
+func TestStore_PutDelete(t *testing.T) {
+	k := newMemoryKeeper(t)
+	st := k.With("users")
+	if err := k.Define("users", emailIndex); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range map[string]string{
+		"alice": `{"user":{"email":"alice@example.com"}}`,
+		"bob":   `{"user":{"email":"shared@example.com"}}`,
+		"carol": `{"user":[{"email":"shared@example.com"},{"email":"carol@example.com"}]}`,
+		"blob":  "not json",
+	} {
+		if err := st.Put([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectLookup(t, st, "email", "shared@example.com", "bob", "carol")
+	expectLookup(t, st, "email", "carol@example.com", "carol")
+
+	if err := st.Put([]byte("alice"), []byte(`{"user":{"email":"new@example.com"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	expectLookup(t, st, "email", "alice@example.com")
+	expectLookup(t, st, "email", "new@example.com", "alice")
+
+	if err := st.Delete([]byte("bob")); err != nil {
+		t.Fatal(err)
+	}
+	expectLookup(t, st, "email", "shared@example.com", "carol")
+
+	if _, err := st.(database.Indexer).Lookup("nope", nil); !errors.Is(err, ErrNoIndex) {
+		t.Errorf("expected ErrNoIndex, got %v", err)
+	}
+}
+
+func TestStore_LookupStale(t *testing.T) {
+	k := newMemoryKeeper(t)
+	st := k.With("users").(*Store)
+	if err := k.Define("users", emailIndex); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put([]byte("alice"), []byte(`{"user":{"email":"alice@example.com"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	// bypass the index, leaving a stale entry behind.
+	if err := st.Unwrap().Put([]byte("alice"), []byte(`{"user":{"email":"other@example.com"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	expectLookup(t, st, "email", "alice@example.com")
+	if k.With("users"+Suffix).Len() != 0 {
+		t.Error("expected stale entry to be removed")
+	}
+
+	if err := k.Rebuild("users"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectLookup(t, st, "email", "other@example.com", "alice")
+}
+
+func TestKeeper_Extractor(t *testing.T) {
+	RegisterExtractor("test-lower", func(_, value []byte) ([][]byte, error) {
+		return [][]byte{bytes.ToLower(value)}, nil
+	})
+	k := newMemoryKeeper(t)
+	st := k.With("users")
+	if err := k.Define("users", metadata.Index{Name: "lower", Extractor: "test-lower"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put([]byte("alice"), []byte("Alice")); err != nil {
+		t.Fatal(err)
+	}
+	expectLookup(t, st, "lower", "alice", "alice")
+
+	if err := k.Drop("users", "lower"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := st.(database.Indexer).Lookup("lower", []byte("alice")); !errors.Is(err, ErrNoIndex) {
+		t.Errorf("expected ErrNoIndex after drop, got %v", err)
+	}
+	if k.With("users"+Suffix).Len() != 0 {
+		t.Error("expected entries of dropped index to be removed")
+	}
+}
+
+func TestKeeper_Discover(t *testing.T) {
+	path := t.TempDir()
+	db := bitcask.OpenDB(path)
+	if err := db.Init("users"); err != nil {
+		t.Fatal(err)
+	}
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Define("users", emailIndex); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.With("users").Put([]byte("alice"), []byte(`{"user":{"email":"alice@example.com"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.SyncAndCloseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopen := func() *Keeper {
+		t.Helper()
+		k, err := Wrap(bitcask.OpenDB(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = k.Discover(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return k
+	}
+
+	t.Run("reattach", func(t *testing.T) {
+		k := reopen()
+		expectLookup(t, k.With("users"), "email", "alice@example.com", "alice")
+		if err := k.Destroy("users" + Suffix); err != nil {
+			t.Fatal(err)
+		}
+		if err := k.SyncAndCloseAll(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("rebuild", func(t *testing.T) {
+		k := reopen()
+		t.Cleanup(func() {
+			_ = k.CloseAll()
+		})
+		expectLookup(t, k.With("users"), "email", "alice@example.com", "alice")
+	})
+}
+
+func TestStore_LookupPrefix(t *testing.T) {
+	k := newMemoryKeeper(t)
+	st := k.With("users").(*Store)
+	for key, email := range map[string]string{"alice": "alice@example.com", "bob": "bob@example.org", "al": "al@example.com"} {
+		if err := st.Put([]byte(key), []byte(`{"user":{"email":"`+email+`"}}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := st.LookupPrefix("nope", nil); !errors.Is(err, ErrNoIndex) {
+		t.Errorf("expected ErrNoIndex, got %v", err)
+	}
+	if err := k.Define("users", emailIndex); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.LookupPrefix("email", []byte("al")); !errors.Is(err, ErrNotOrdered) {
+		t.Errorf("expected ErrNotOrdered, got %v", err)
+	}
+
+	ordered := metadata.Index{Name: "email_ordered", Field: "user.email", Ordered: true}
+	if err := k.Define("users", ordered); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := st.LookupPrefix("email_ordered", []byte("al"))
+	if err != nil || !slices.Equal(sortedKeys(keys), []string{"al", "alice"}) {
+		t.Errorf("expected [al alice], got %q (%v)", keys, err)
+	}
+	// exact lookups on an ordered index don't match longer values sharing the prefix.
+	expectLookup(t, st, "email_ordered", "al@example.com", "al")
+	if keys, err = st.LookupPrefix("email_ordered", nil); err != nil || len(keys) != 3 {
+		t.Errorf("expected every key, got %q (%v)", keys, err)
+	}
+}
+
+func TestStore_SuffixScan(t *testing.T) {
+	k := newMemoryKeeper(t)
+	st := k.With("users").(*Store)
+	collectKeys := func(resChan <-chan kv.KeyValue, errChan chan error) []string {
+		var keys []string
+		for keyVal := range resChan {
+			keys = append(keys, keyVal.Key.String())
+		}
+		if err := <-errChan; err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		slices.Sort(keys)
+		return keys
+	}
+	for _, host := range []string{"www.example.com", "mail.example.com", "example.org"} {
+		if err := st.Put([]byte(host), []byte("up")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"mail.example.com", "www.example.com"}
+	// passed through to the memory store before suffix scans are enabled.
+	if got := collectKeys(st.SuffixScan(".example.com")); !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	if err := k.EnableSuffixScans("users"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if keys, err := st.LookupPrefix(SuffixIndex, []byte("gro.")); err != nil || !slices.Equal(sortedKeys(keys), []string{"example.org"}) {
+		t.Errorf("expected keys indexed reversed, got %q (%v)", keys, err)
+	}
+	if got := collectKeys(st.SuffixScan(".example.com")); !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if err := st.Delete([]byte("www.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put([]byte("ftp.example.com"), []byte("up")); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"ftp.example.com", "mail.example.com"}
+	if got := collectKeys(st.SuffixScan(".example.com")); !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := collectKeys(st.KeyContains("mail")); !slices.Equal(got, []string{"mail.example.com"}) {
+		t.Errorf("expected [mail.example.com], got %q", got)
+	}
+
+	if err := k.DisableSuffixScans("users"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := collectKeys(st.SuffixScan(".org")); !slices.Equal(got, []string{"example.org"}) {
+		t.Errorf("expected [example.org], got %q", got)
+	}
+}
+
+func TestStore_SuffixScanCollisions(t *testing.T) {
+	k := newMemoryKeeper(t)
+	st := k.With("users").(*Store)
+	if err := k.EnableSuffixScans("users"); err != nil {
+		t.Fatal(err)
+	}
+	// the entries of short keys start with bytes that also begin the reversed suffixes of longer ones.
+	for _, key := range []string{"a", "ab", "aa", "xaa", "abba"} {
+		if err := st.Put([]byte(key), []byte("up")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for suffix, want := range map[string][]string{
+		"abba": {"abba"},
+		"aa":   {"aa", "xaa"},
+		"ba":   {"abba"},
+		"a":    {"a", "aa", "abba", "xaa"},
+	} {
+		var got []string
+		resChan, errChan := st.SuffixScan(suffix)
+		for keyVal := range resChan {
+			got = append(got, keyVal.Key.String())
+		}
+		if err := <-errChan; err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: expected %q, got %q", suffix, want, got)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/kv"
+)
+
+var (
+	_ database.Indexer = (*Store)(nil)
+	_ database.Store   = (*Store)(nil)
+)
+
+// Store wraps a Filer of an indexed [Keeper], keeping its indexes up to date as it is written to.
+// It implements [database.Indexer], and [database.Searcher] by passing searches through to the
+// underlying Filer. Other backend specific methods are reached through [Store.Unwrap].
+type Store struct {
+	wrap.Filer
+	keeper  database.Keeper
+	name    string
+	indexes *indexes
+}
+
+// Unwrap returns the underlying Filer. Writes made to it directly are not indexed.
+func (s *Store) Unwrap() database.Filer {
+	return s.Filer.Filer
+}
+
+// current returns the index entries of the value currently stored at key, or nil if there is none.
+func (s *Store) current(key []byte) (map[string]struct{}, error) {
+	old, err := s.Filer.Get(key)
+	if kv.IsNonExistentKey(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// entries that can't be computed for the old value are left to be discarded by Lookup.
+	entries, _ := s.indexes.entries(key, old)
+	return entries, nil
+}
+
+// Put inserts the value at the given key and updates the store's indexes to match.
+func (s *Store) Put(key []byte, value []byte) error {
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+	if len(s.indexes.defs) == 0 {
+		return s.Filer.Put(key, value)
+	}
+	c, err := companion(s.keeper, s.name, false)
+	if err != nil {
+		return err
+	}
+	added, err := s.indexes.entries(key, value)
+	if err != nil {
+		return err
+	}
+	removed, err := s.current(key)
+	if err != nil {
+		return err
+	}
+	for entry := range added {
+		if _, ok := removed[entry]; ok {
+			continue
+		}
+		if err = c.Put([]byte(entry), key); err != nil {
+			return err
+		}
+	}
+	if err = s.Filer.Put(key, value); err != nil {
+		return err
+	}
+	for entry := range removed {
+		if _, ok := added[entry]; ok {
+			continue
+		}
+		// a stale entry that fails to be removed here is discarded by Lookup instead.
+		_ = c.Delete([]byte(entry))
+	}
+	return nil
+}
+
+// Delete deletes the given key and its index entries.
+func (s *Store) Delete(key []byte) error {
+	s.indexes.mu.Lock()
+	defer s.indexes.mu.Unlock()
+	if len(s.indexes.defs) == 0 {
+		return s.Filer.Delete(key)
+	}
+	c, err := companion(s.keeper, s.name, false)
+	if err != nil {
+		return err
+	}
+	removed, err := s.current(key)
+	if err != nil {
+		return err
+	}
+	if err = s.Filer.Delete(key); err != nil {
+		return err
+	}
+	for entry := range removed {
+		_ = c.Delete([]byte(entry))
+	}
+	return nil
+}
+
+// Lookup returns the keys whose values produce the given value in the named index.
+// Entries that no longer match the stored value are removed from the index as they are found.
+func (s *Store) Lookup(index string, value []byte) ([][]byte, error) {
+	return s.lookup(index, value, false)
+}
+
+// LookupPrefix returns the keys whose values produce a value starting with prefix in the named index,
+// which must be [metadata.Index.Ordered]. Keys are returned in the order of their values.
+func (s *Store) LookupPrefix(index string, prefix []byte) ([][]byte, error) {
+	return s.lookup(index, prefix, true)
+}
+
+func (s *Store) lookup(index string, value []byte, prefix bool) ([][]byte, error) {
+	s.indexes.mu.RLock()
+	defer s.indexes.mu.RUnlock()
+	idx := s.indexes.find(index)
+	if idx == nil {
+		return nil, ErrNoIndex
+	}
+	if prefix && !idx.Ordered {
+		return nil, fmt.Errorf("%w: %s", ErrNotOrdered, index)
+	}
+	c, err := companion(s.keeper, s.name, false)
+	if err != nil {
+		return nil, err
+	}
+	found, err := scan(c, idx.scanPrefix(value))
+	if err != nil {
+		return nil, err
+	}
+	if idx.Ordered {
+		// not every Searcher scans in key order.
+		slices.SortFunc(found, func(a, b kv.KeyValue) int {
+			return bytes.Compare(a.Key.Bytes(), b.Key.Bytes())
+		})
+	}
+	keys := make([][]byte, 0, len(found))
+	seen := make(map[string]struct{}, len(found))
+	for _, entry := range found {
+		key := entry.Value.Bytes()
+		want := value
+		if idx.Ordered {
+			if want = idx.entryValue(entry.Key.Bytes(), key); want == nil ||
+				(!prefix && !bytes.Equal(want, value)) || (prefix && !bytes.HasPrefix(want, value)) {
+				continue
+			}
+		}
+		ok, matchErr := s.matches(idx, key, want)
+		if matchErr != nil {
+			return keys, matchErr
+		}
+		if !ok {
+			_ = c.Delete(entry.Key.Bytes())
+			continue
+		}
+		// a key producing several values with the prefix has an entry for each.
+		if _, dup := seen[string(key)]; !dup {
+			seen[string(key)] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// matches returns true if the value currently stored at key still produces want in the index.
+func (s *Store) matches(idx *index, key []byte, want []byte) (bool, error) {
+	value, err := s.Filer.Get(key)
+	if kv.IsNonExistentKey(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	values, err := idx.extract(key, value)
+	if err != nil {
+		return false, nil
+	}
+	for _, v := range values {
+		if bytes.Equal(v, want) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Sync syncs the store and its companion store, if it has one.
+func (s *Store) Sync() error {
+	if err := s.Filer.Sync(); err != nil {
+		return err
+	}
+	if c := s.keeper.With(s.name + Suffix); c != nil {
+		return c.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/internal/wrap"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+const (
+	// SuffixIndex is the name of the ordered index of reversed keys defined by [Keeper.EnableSuffixScans].
+	SuffixIndex = "reversed_keys"
+	// ReversedKeyExtractor is the name that [ReversedKey] is registered under.
+	ReversedKeyExtractor = "reversed_key"
+)
+
+func init() {
+	RegisterExtractor(ReversedKeyExtractor, ReversedKey)
+}
+
+// ReversedKey is an [Extractor] that indexes a key/value pair under its key, reversed byte by byte.
+// In an ordered index, the keys ending with a suffix are then the entries starting with the reversed suffix.
+func ReversedKey(key, _ []byte) ([][]byte, error) {
+	reversed := slices.Clone(key)
+	slices.Reverse(reversed)
+	return [][]byte{reversed}, nil
+}
+
+// EnableSuffixScans defines an ordered index of the reversed keys of the given store, which
+// [Store.SuffixScan] reads instead of walking every key of the store.
+func (k *Keeper) EnableSuffixScans(store string) error {
+	return k.Define(store, metadata.Index{Name: SuffixIndex, Extractor: ReversedKeyExtractor, Ordered: true})
+}
+
+// DisableSuffixScans drops the index defined by [Keeper.EnableSuffixScans] from the given store.
+func (k *Keeper) DisableSuffixScans(store string) error {
+	return k.Drop(store, SuffixIndex)
+}
+
+func (s *Store) keySearcher() (database.KeySearcher, bool) {
+	searcher, ok := s.Filer.Filer.(database.KeySearcher)
+	return searcher, ok
+}
+
+// SuffixScan streams every key/value pair whose key ends with suffix. If suffix scans are enabled on the store,
+// the matching keys are read from its index of reversed keys, otherwise the scan is passed through to the
+// underlying Filer.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	reversed, _ := ReversedKey([]byte(suffix), nil)
+	keys, err := s.LookupPrefix(SuffixIndex, reversed[0])
+	if errors.Is(err, ErrNoIndex) {
+		searcher, ok := s.keySearcher()
+		if !ok {
+			return wrap.Unsupported(ErrNotKeySearcher)
+		}
+		if cs, isContext := searcher.(interface {
+			SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error)
+		}); isContext {
+			return cs.SuffixScanContext(ctx, suffix)
+		}
+		return searcher.SuffixScan(suffix)
+	}
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		if err != nil {
+			errChan <- err
+			return
+		}
+		for _, key := range keys {
+			value, getErr := s.Filer.Get(key)
+			if kv.IsNonExistentKey(getErr) {
+				continue
+			}
+			if getErr != nil {
+				errChan <- getErr
+				return
+			}
+			if !kv.Send(ctx, resChan, kv.NewKeyValueFromBytes(key, value)) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return resChan, errChan
+}
+
+// KeyContains passes the scan through to the underlying Filer.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	if searcher, ok := s.keySearcher(); ok {
+		return searcher.KeyContains(substr)
+	}
+	return wrap.Unsupported(ErrNotKeySearcher)
+}
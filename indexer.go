@@ -0,0 +1,9 @@
+package database
+
+// Indexer is a Filer that maintains secondary indexes over its values.
+type Indexer interface {
+	Filer
+	// Lookup should return the keys whose values produce the given value in the named index,
+	// without scanning the whole store.
+	Lookup(index string, value []byte) ([][]byte, error)
+}
@@ -0,0 +1,18 @@
+package database
+
+// InMemoryKeeper is a Keeper whose stores only live in memory.
+type InMemoryKeeper interface {
+	Keeper
+	// InMemory should return true if the Keeper's stores only live in memory, so that nothing kept on disk
+	// alongside them could be recovered after a crash or outlive them.
+	InMemory() bool
+}
+
+// IsInMemory returns true if the stores of keeper only live in memory, either because it implements
+// [InMemoryKeeper] and says so, or because it has no path to keep anything on disk under.
+func IsInMemory(keeper Keeper) bool {
+	if m, ok := keeper.(InMemoryKeeper); ok && m.InMemory() {
+		return true
+	}
+	return keeper.Path() == ""
+}
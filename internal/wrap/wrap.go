@@ -0,0 +1,177 @@
+// Package wrap holds the plumbing shared by the packages that wrap a [database.Keeper] to maintain something
+// alongside its stores, such as secondary indexes, full-text indexes or bloom filters.
+//
+// Those packages embed a [Keeper], which hands out the underlying Keeper's stores wrapped by their [Hooks], and
+// wrap the stores around a [Filer], which passes [database.Searcher] through to the underlying store.
+package wrap
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+)
+
+var (
+	// ErrNoStore is returned when referring to a store that isn't open.
+	ErrNoStore = errors.New("store not found")
+	// ErrNoMetadata is returned when a Keeper doesn't have [metadata.Metadata] to record settings in.
+	ErrNoMetadata = errors.New("keeper does not have metadata to record settings in")
+)
+
+// Hooks are the parts of a wrapping Keeper that are specific to what it maintains.
+type Hooks struct {
+	// Wrap wraps a store of the underlying Keeper.
+	Wrap func(name string, f database.Filer) database.Filer
+	// Attach sets up what was recorded in the metadata for the stores once they have been discovered.
+	Attach func() error
+	// Forget cleans up after a store has been destroyed.
+	Forget func(name string) error
+}
+
+// Keeper embeds a [database.Keeper], handing out its stores wrapped by [Hooks.Wrap].
+type Keeper struct {
+	database.Keeper
+	hooks Hooks
+}
+
+// NewKeeper returns a Keeper wrapping the stores of keeper with hooks.
+func NewKeeper(keeper database.Keeper, hooks Hooks) Keeper {
+	return Keeper{Keeper: keeper, hooks: hooks}
+}
+
+// Unwrap returns the underlying Keeper, whose stores are not wrapped.
+func (k Keeper) Unwrap() database.Keeper {
+	return k.Keeper
+}
+
+// InMemory reports whether the underlying Keeper's stores only live in memory, see [database.IsInMemory].
+func (k Keeper) InMemory() bool {
+	return database.IsInMemory(k.Keeper)
+}
+
+// With returns the given store wrapped, or nil if it isn't open.
+func (k Keeper) With(name string) database.Filer {
+	f := k.Keeper.With(name)
+	if f == nil {
+		return nil
+	}
+	return k.hooks.Wrap(name, f)
+}
+
+// WithNew returns the given store wrapped, initializing it if needed.
+func (k Keeper) WithNew(name string, options ...any) database.Filer {
+	f := k.Keeper.WithNew(name, options...)
+	if f == nil {
+		return nil
+	}
+	return k.hooks.Wrap(name, f)
+}
+
+// AllStores returns every store of the underlying Keeper wrapped.
+func (k Keeper) AllStores() map[string]database.Filer {
+	stores := k.Keeper.AllStores()
+	for name, f := range stores {
+		stores[name] = k.hooks.Wrap(name, f)
+	}
+	return stores
+}
+
+// Discover discovers the underlying Keeper's stores, then runs [Hooks.Attach].
+func (k Keeper) Discover() ([]string, error) {
+	stores, err := k.Keeper.Discover()
+	if err != nil {
+		return stores, err
+	}
+	return stores, k.hooks.Attach()
+}
+
+// Destroy removes the given store, then runs [Hooks.Forget].
+func (k Keeper) Destroy(name string) error {
+	if err := k.Keeper.Destroy(name); err != nil {
+		return err
+	}
+	return k.hooks.Forget(name)
+}
+
+// Metadata returns the canonical metadata of keeper, or [ErrNoMetadata] if it doesn't have any.
+func Metadata(keeper database.Keeper) (*metadata.Metadata, error) {
+	meta, err := metadata.CastToMetadata(keeper.Meta())
+	// some keepers hand out a nil *metadata.Metadata before they are initialized.
+	if err != nil || meta == nil {
+		return nil, ErrNoMetadata
+	}
+	return meta, nil
+}
+
+// Recorded returns the canonical metadata of keeper to set up what was recorded in it, and false if there is
+// nothing to set up. Keepers only have canonical metadata once initialized, before which nothing can have
+// been recorded.
+func Recorded(keeper database.Keeper) (*metadata.Metadata, bool) {
+	meta, err := Metadata(keeper)
+	return meta, err == nil
+}
+
+// Sidecar returns the named store of keeper that is kept alongside one of its other stores,
+// initializing it if create is true.
+func Sidecar(keeper database.Keeper, name string, create bool) (database.Filer, error) {
+	if f := keeper.With(name); f != nil {
+		return f, nil
+	}
+	if !create {
+		return nil, fmt.Errorf("%w: %s", ErrNoStore, name)
+	}
+	if err := keeper.Init(name); err != nil {
+		return nil, fmt.Errorf("failed to create store %s: %w", name, err)
+	}
+	if f := keeper.With(name); f != nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoStore, name)
+}
+
+// Filer embeds a [database.Filer], passing the methods of [database.Searcher] through to it.
+type Filer struct {
+	database.Filer
+}
+
+func (f Filer) searcher() (database.Searcher, bool) {
+	searcher, ok := f.Filer.(database.Searcher)
+	return searcher, ok
+}
+
+// Unsupported returns closed channels carrying only err, for searches the underlying store can't answer.
+func Unsupported(err error) (<-chan kv.KeyValue, chan error) {
+	resChan := make(chan kv.KeyValue)
+	errChan := make(chan error, 1)
+	errChan <- err
+	close(resChan)
+	close(errChan)
+	return resChan, errChan
+}
+
+// PrefixScan passes the scan through to the underlying Filer.
+func (f Filer) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
+	if searcher, ok := f.searcher(); ok {
+		return searcher.PrefixScan(prefix)
+	}
+	return Unsupported(database.ErrNotSearcher)
+}
+
+// Search passes the search through to the underlying Filer.
+func (f Filer) Search(query string) (<-chan kv.KeyValue, chan error) {
+	if searcher, ok := f.searcher(); ok {
+		return searcher.Search(query)
+	}
+	return Unsupported(database.ErrNotSearcher)
+}
+
+// ValueExists passes the search through to the underlying Filer.
+func (f Filer) ValueExists(value []byte) (key []byte, ok bool) {
+	if searcher, isSearcher := f.searcher(); isSearcher {
+		return searcher.ValueExists(value)
+	}
+	return nil, false
+}
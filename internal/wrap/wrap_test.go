@@ -0,0 +1,97 @@
+package wrap
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/memory"
+)
+
+type wrapped struct {
+	Filer
+	name string
+}
+
+func TestKeeper(t *testing.T) {
+	var (
+		attached int
+		forgot   []string
+	)
+	k := NewKeeper(memory.OpenDB(t.TempDir()), Hooks{
+		Wrap: func(name string, f database.Filer) database.Filer {
+			return &wrapped{Filer: Filer{Filer: f}, name: name}
+		},
+		Attach: func() error {
+			attached++
+			return nil
+		},
+		Forget: func(name string) error {
+			forgot = append(forgot, name)
+			return nil
+		},
+	})
+
+	if k.With("yeet") != nil {
+		t.Error("expected nil for a store that isn't open")
+	}
+	st, ok := k.WithNew("yeet").(*wrapped)
+	if !ok || st.name != "yeet" {
+		t.Fatalf("expected the new store to be wrapped, got %T", k.WithNew("yeet"))
+	}
+	if _, ok = k.Unwrap().With("yeet").(*wrapped); ok {
+		t.Error("expected the underlying Keeper's stores to not be wrapped")
+	}
+	if err := st.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	for name, f := range k.AllStores() {
+		if w, isWrapped := f.(*wrapped); !isWrapped || w.name != name {
+			t.Errorf("expected %s to be wrapped, got %T", name, f)
+		}
+	}
+
+	resChan, errChan := st.PrefixScan("k")
+	var keys []string
+	for keyVal := range resChan {
+		keys = append(keys, keyVal.Key.String())
+	}
+	if err := <-errChan; err != nil || !slices.Equal(keys, []string{"key"}) {
+		t.Errorf("expected the scan to be passed through, got %q (%v)", keys, err)
+	}
+	if key, found := st.ValueExists([]byte("value")); !found || string(key) != "key" {
+		t.Errorf("expected ValueExists to be passed through, got %q", key)
+	}
+	resChan, errChan = (Filer{Filer: struct{ database.Filer }{}}).Search("value")
+	for range resChan {
+		t.Error("expected no results from a store that can't be searched")
+	}
+	if err := <-errChan; !errors.Is(err, database.ErrNotSearcher) {
+		t.Errorf("expected ErrNotSearcher, got %v", err)
+	}
+
+	if _, err := k.Discover(); err != nil || attached != 1 {
+		t.Errorf("expected Discover to attach once, got %d (%v)", attached, err)
+	}
+	if err := k.Destroy("yeet"); err != nil || !slices.Equal(forgot, []string{"yeet"}) {
+		t.Errorf("expected Destroy to forget the store, got %q (%v)", forgot, err)
+	}
+}
+
+func TestSidecar(t *testing.T) {
+	k := memory.OpenDB(t.TempDir())
+	if _, err := Sidecar(k, "yeet_side", false); !errors.Is(err, ErrNoStore) {
+		t.Errorf("expected ErrNoStore, got %v", err)
+	}
+	side, err := Sidecar(k, "yeet_side", true)
+	if err != nil || side == nil {
+		t.Fatalf("expected the store to be created, got %v", err)
+	}
+	if again, _ := Sidecar(k, "yeet_side", false); again == nil {
+		t.Error("expected the existing store to be returned")
+	}
+	if _, ok := Recorded(k); !ok {
+		t.Error("expected an initialized keeper to have metadata")
+	}
+}
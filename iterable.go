@@ -0,0 +1,49 @@
+package database
+
+import "iter"
+
+// RangeOptions changes how [Iterable.Range] walks the keys in a range.
+type RangeOptions struct {
+	// Reverse walks the range from the largest key to the smallest.
+	Reverse bool
+	// Limit stops the iterator after this many keys, zero or less means no limit.
+	Limit int
+}
+
+// Iterator walks key/value pairs in key order. It must be closed once it is no longer needed.
+type Iterator interface {
+	// Next should advance to the next key/value pair, returning false once there are none left or an error occurs.
+	Next() bool
+	// Key should return the key at the current position. It is only valid until the next call to Next.
+	Key() []byte
+	// Value should return the value at the current position. It is only valid until the next call to Next.
+	Value() []byte
+	// Err should return the error that stopped the iterator, if any. It remains valid after Close.
+	Err() error
+	// Close should release any resources held by the iterator.
+	Close() error
+}
+
+// Iterable is a Filer that can walk a range of its keys in byte order without loading all of them at once.
+type Iterable interface {
+	Filer
+	// Range should return an [Iterator] over the keys from start, inclusive, to end, exclusive.
+	// A nil start begins at the first key in the store and a nil end continues to the last.
+	// Errors, such as the store being closed, are reported by the iterator's Err method.
+	Range(start, end []byte, opts RangeOptions) Iterator
+}
+
+// Seq adapts an [Iterator] for use with range-over-func loops. The iterator is closed once the loop ends,
+// after which its Err method should be checked.
+func Seq(it Iterator) iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		defer func() {
+			_ = it.Close()
+		}()
+		for it.Next() {
+			if !yield(it.Key(), it.Value()) {
+				return
+			}
+		}
+	}
+}
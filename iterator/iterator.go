@@ -0,0 +1,204 @@
+// Package iterator implements [database.Iterator] for the stores in this module.
+//
+// Stores with native ordering read their range a page at a time with [Paged], so that no cursor or transaction
+// is held open between calls to Next. Stores without native ordering collect the keys within the range and
+// sort them with [Sorted], reading values only as the iterator reaches them.
+package iterator
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+// PageSize is the number of key/value pairs that a [Paged] iterator fetches at a time.
+const PageSize = 256
+
+// Fetch returns up to n key/value pairs that come after the given key in the iterator's direction.
+// after is nil when fetching the first page. Returning fewer than n pairs ends the iteration,
+// any pairs returned alongside an error are still yielded before the error is reported.
+type Fetch func(after []byte, n int) (keys [][]byte, values [][]byte, err error)
+
+type paged struct {
+	fetch     Fetch
+	remaining int
+	keys      [][]byte
+	values    [][]byte
+	pos       int
+	after     []byte
+	exhausted bool
+	pending   error
+	err       error
+	closed    bool
+}
+
+// Paged returns an iterator that reads its range a page at a time with fetch, stopping after limit
+// pairs if limit is greater than zero.
+func Paged(fetch Fetch, limit int) database.Iterator {
+	if limit <= 0 {
+		limit = -1
+	}
+	return &paged{fetch: fetch, remaining: limit, pos: -1}
+}
+
+// Error returns an iterator that yields nothing and reports err.
+func Error(err error) database.Iterator {
+	return &paged{err: err, closed: true}
+}
+
+func (p *paged) Next() bool {
+	if p.closed || p.err != nil || p.remaining == 0 {
+		return false
+	}
+	p.pos++
+	if p.pos >= len(p.keys) && !p.nextPage() {
+		return false
+	}
+	p.after = p.keys[p.pos]
+	if p.after == nil {
+		p.after = []byte{}
+	}
+	if p.remaining > 0 {
+		p.remaining--
+	}
+	return true
+}
+
+func (p *paged) nextPage() bool {
+	p.keys, p.values, p.pos = nil, nil, 0
+	if p.exhausted {
+		p.err = p.pending
+		return false
+	}
+	n := PageSize
+	if p.remaining > 0 && p.remaining < n {
+		n = p.remaining
+	}
+	p.keys, p.values, p.pending = p.fetch(p.after, n)
+	if p.pending != nil || len(p.keys) < n {
+		p.exhausted = true
+	}
+	if len(p.keys) == 0 {
+		p.err = p.pending
+		return false
+	}
+	return true
+}
+
+func (p *paged) Key() []byte {
+	if p.pos < 0 || p.pos >= len(p.keys) {
+		return nil
+	}
+	return p.keys[p.pos]
+}
+
+func (p *paged) Value() []byte {
+	if p.pos < 0 || p.pos >= len(p.values) {
+		return nil
+	}
+	return p.values[p.pos]
+}
+
+func (p *paged) Err() error {
+	return p.err
+}
+
+func (p *paged) Close() error {
+	p.closed = true
+	p.keys, p.values = nil, nil
+	return nil
+}
+
+// InRange returns true if key is within start, inclusive, and end, exclusive. A nil start or end is unbounded.
+func InRange(key, start, end []byte) bool {
+	return (start == nil || bytes.Compare(key, start) >= 0) && (end == nil || bytes.Compare(key, end) < 0)
+}
+
+// Sorted returns an iterator over the keys that fall within the range, in the order given by opts.
+// Values are read with get as the iterator reaches them, and keys that no longer exist by then are skipped.
+// The keys slice is reordered in place.
+func Sorted(keys [][]byte, start, end []byte, opts database.RangeOptions, get func(key []byte) ([]byte, error)) database.Iterator {
+	keys = slices.DeleteFunc(keys, func(key []byte) bool {
+		return !InRange(key, start, end)
+	})
+	slices.SortFunc(keys, bytes.Compare)
+	if opts.Reverse {
+		slices.Reverse(keys)
+	}
+	return Paged(func(_ []byte, n int) ([][]byte, [][]byte, error) {
+		page := make([][]byte, 0, n)
+		values := make([][]byte, 0, n)
+		for len(page) < n && len(keys) > 0 {
+			key := keys[0]
+			keys = keys[1:]
+			v, err := get(key)
+			if kv.IsNonExistentKey(err) {
+				continue
+			}
+			if err != nil {
+				return page, values, err
+			}
+			page = append(page, key)
+			values = append(values, v)
+		}
+		return page, values, nil
+	}, opts.Limit)
+}
+
+// Cursor is a native ordered cursor, such as a goleveldb iterator.
+// The positioning methods return false when the cursor moves past either end of the store.
+type Cursor interface {
+	// Seek should move to the first key that is greater than or equal to key.
+	Seek(key []byte) bool
+	First() bool
+	Last() bool
+	Next() bool
+	Prev() bool
+	Key() []byte
+	Value() []byte
+}
+
+// Walk reads the next page of a range from c, for use in a [Fetch] function.
+// Keys and values are copied, so the cursor may be released afterwards.
+func Walk(c Cursor, start, end, after []byte, reverse bool, n int) (keys [][]byte, values [][]byte) {
+	var ok bool
+	switch {
+	case !reverse && after != nil:
+		ok = c.Seek(after)
+		if ok && bytes.Equal(c.Key(), after) {
+			ok = c.Next()
+		}
+	case !reverse && start != nil:
+		ok = c.Seek(start)
+	case !reverse:
+		ok = c.First()
+	default:
+		// moving back from the first key at or past the upper bound lands on the last key before it.
+		bound := after
+		if bound == nil {
+			bound = end
+		}
+		if bound != nil && c.Seek(bound) {
+			ok = c.Prev()
+		} else {
+			ok = c.Last()
+		}
+	}
+	for ; ok && len(keys) < n; ok = next(c, reverse) {
+		if !InRange(c.Key(), start, end) {
+			break
+		}
+		keys = append(keys, slices.Clone(c.Key()))
+		values = append(values, slices.Clone(c.Value()))
+	}
+	return keys, values
+}
+
+func next(c Cursor, reverse bool) bool {
+	if reverse {
+		return c.Prev()
+	}
+	return c.Next()
+}
@@ -0,0 +1,199 @@
+package iterator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+// sliceCursor is an in-memory [Cursor] over sorted keys.
+type sliceCursor struct {
+	keys []string
+	pos  int
+}
+
+func (c *sliceCursor) valid() bool {
+	return c.pos >= 0 && c.pos < len(c.keys)
+}
+
+func (c *sliceCursor) Seek(key []byte) bool {
+	c.pos = sort.SearchStrings(c.keys, string(key))
+	return c.valid()
+}
+
+func (c *sliceCursor) First() bool   { c.pos = 0; return c.valid() }
+func (c *sliceCursor) Last() bool    { c.pos = len(c.keys) - 1; return c.valid() }
+func (c *sliceCursor) Next() bool    { c.pos++; return c.valid() }
+func (c *sliceCursor) Prev() bool    { c.pos--; return c.valid() }
+func (c *sliceCursor) Key() []byte   { return []byte(c.keys[c.pos]) }
+func (c *sliceCursor) Value() []byte { return []byte("v" + c.keys[c.pos]) }
+
+func collect(t *testing.T, it database.Iterator) []string {
+	t.Helper()
+	var got []string
+	for k := range database.Seq(it) {
+		got = append(got, string(k))
+	}
+	return got
+}
+
+func TestWalk(t *testing.T) {
+	keys := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("%04d", i))
+	}
+	for _, tc := range []struct {
+		start, end string
+		opts       database.RangeOptions
+		want       []string
+	}{
+		{"", "", database.RangeOptions{}, keys},
+		{"", "", database.RangeOptions{Reverse: true}, reversed(keys)},
+		{"0100", "0600", database.RangeOptions{}, keys[100:600]},
+		{"0100", "0600", database.RangeOptions{Reverse: true}, reversed(keys[100:600])},
+		{"0100x", "", database.RangeOptions{Limit: 300}, keys[101:401]},
+		{"", "0999x", database.RangeOptions{Reverse: true, Limit: 2}, []string{"0999", "0998"}},
+		{"2", "3", database.RangeOptions{}, nil},
+	} {
+		var start, end []byte
+		if tc.start != "" {
+			start = []byte(tc.start)
+		}
+		if tc.end != "" {
+			end = []byte(tc.end)
+		}
+		c := &sliceCursor{keys: keys}
+		it := Paged(func(after []byte, n int) ([][]byte, [][]byte, error) {
+			k, v := Walk(c, start, end, after, tc.opts.Reverse, n)
+			return k, v, nil
+		}, tc.opts.Limit)
+		if got := collect(t, it); !slices.Equal(got, tc.want) {
+			t.Errorf("Walk(%q, %q, %+v): expected %d keys from %v, got %d from %v",
+				tc.start, tc.end, tc.opts, len(tc.want), first(tc.want), len(got), first(got))
+		}
+	}
+}
+
+func reversed(keys []string) []string {
+	r := slices.Clone(keys)
+	slices.Reverse(r)
+	return r
+}
+
+func first(keys []string) any {
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[0]
+}
+
+func TestSorted(t *testing.T) {
+	data := map[string]string{"c": "3", "a": "1", "b": "2", "d": "4"}
+	keys := [][]byte{[]byte("c"), []byte("a"), []byte("gone"), []byte("d"), []byte("b")}
+	get := func(key []byte) ([]byte, error) {
+		v, ok := data[string(key)]
+		if !ok {
+			return nil, kv.RegularizeKVError(key, nil, nil)
+		}
+		return []byte(v), nil
+	}
+	it := Sorted(keys, []byte("b"), nil, database.RangeOptions{Reverse: true}, get)
+	if got := collect(t, it); !slices.Equal(got, []string{"d", "c", "b"}) {
+		t.Errorf("expected missing keys to be skipped in reverse order, got %q", got)
+	}
+
+	errBoom := errors.New("boom")
+	keys = [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	it = Sorted(keys, nil, nil, database.RangeOptions{}, func(key []byte) ([]byte, error) {
+		if string(key) == "c" {
+			return nil, errBoom
+		}
+		return get(key)
+	})
+	if got := collect(t, it); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("expected keys read before the error to be yielded, got %q", got)
+	}
+	if !errors.Is(it.Err(), errBoom) {
+		t.Errorf("expected errBoom, got %v", it.Err())
+	}
+}
+
+func TestError(t *testing.T) {
+	errBoom := errors.New("boom")
+	it := Error(errBoom)
+	if it.Next() || !errors.Is(it.Err(), errBoom) || it.Key() != nil {
+		t.Error("expected empty iterator reporting errBoom")
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	for prefix, want := range map[string][]byte{
+		"":         nil,
+		"ab":       []byte("ac"),
+		"a\xff":    []byte("b"),
+		"\xff\xff": nil,
+	} {
+		if got := PrefixEnd([]byte(prefix)); !slices.Equal(got, want) {
+			t.Errorf("%q: expected %q, got %q", prefix, want, got)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	keys := []string{"a", "b", "b\x00", "c", "d"}
+	reads := 0
+	rng := func(start, end []byte, opts database.RangeOptions) database.Iterator {
+		raw := make([][]byte, 0, len(keys))
+		for _, key := range keys {
+			raw = append(raw, []byte(key))
+		}
+		return Sorted(raw, start, end, opts, func(key []byte) ([]byte, error) {
+			reads++
+			return append([]byte("v"), key...), nil
+		})
+	}
+	pageKeys := func(page []kv.KeyValue) []string {
+		var got []string
+		for _, keyVal := range page {
+			got = append(got, keyVal.Key.String())
+		}
+		return got
+	}
+
+	page, cursor, err := Page(rng, nil, nil, "", 2, nil)
+	if err != nil || !slices.Equal(pageKeys(page), []string{"a", "b"}) || cursor != database.EncodeCursor([]byte("b")) {
+		t.Fatalf("expected first page, got %q (%q, %v)", pageKeys(page), cursor, err)
+	}
+	if reads != 3 {
+		t.Errorf("expected only the page and the pair after it to be read, got %d reads", reads)
+	}
+	// the smallest key after the cursor's is still included.
+	page, cursor, err = Page(rng, nil, nil, cursor, 2, nil)
+	if err != nil || !slices.Equal(pageKeys(page), []string{"b\x00", "c"}) || cursor == "" {
+		t.Fatalf("expected second page, got %q (%q, %v)", pageKeys(page), cursor, err)
+	}
+	// an exactly full last page has no cursor.
+	page, cursor, err = Page(rng, nil, nil, cursor, 1, nil)
+	if err != nil || !slices.Equal(pageKeys(page), []string{"d"}) || cursor != "" {
+		t.Fatalf("expected last page, got %q (%q, %v)", pageKeys(page), cursor, err)
+	}
+
+	// a cursor before the start of the range starts from the beginning.
+	page, _, err = Page(rng, []byte("c"), nil, database.EncodeCursor([]byte("a")), 0, func(_, value []byte) bool {
+		return string(value) != "vc"
+	})
+	if err != nil || !slices.Equal(pageKeys(page), []string{"d"}) {
+		t.Errorf("expected filtered page, got %q (%v)", pageKeys(page), err)
+	}
+
+	for _, bad := range []string{"!", "AA", "Ag"} {
+		if _, _, err = Page(rng, nil, nil, bad, 1, nil); !errors.Is(err, database.ErrBadCursor) {
+			t.Errorf("%q: expected ErrBadCursor, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package iterator
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+// PrefixEnd returns the smallest key that is greater than every key with the given prefix,
+// or nil if there is no such key (e.g. the prefix is empty or made up entirely of 0xff bytes).
+func PrefixEnd(prefix []byte) []byte {
+	end := slices.Clone(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// Range is the Range method of a [database.Iterable].
+type Range func(start, end []byte, opts database.RangeOptions) database.Iterator
+
+// Page reads a page of up to limit key/value pairs accepted by match from the keys between start, inclusive,
+// and end, exclusive, resuming after the key recorded in cursor. A nil match accepts every pair and a limit
+// of zero or less reads every remaining pair. It implements the paging of [database.Pager] on top of rng,
+// returning the cursor for the next page, or an empty one if no accepted pairs are left.
+//
+// Each page is a new range starting after the cursor's key, so rng should be able to start reading from
+// anywhere in its order without walking the keys before it, as stores with native ordering can.
+func Page(rng Range, start, end []byte, cursor string, limit int, match func(key, value []byte) bool) ([]kv.KeyValue, string, error) {
+	after, err := database.DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	if start == nil {
+		start = []byte{}
+	}
+	// the smallest key greater than the cursor's.
+	if after != nil && bytes.Compare(after, start) >= 0 {
+		start = append(slices.Clone(after), 0)
+	}
+	var opts database.RangeOptions
+	if match == nil && limit > 0 {
+		// every pair is accepted, so only one past the end of the page has to be read.
+		opts.Limit = limit + 1
+	}
+	it := rng(start, end, opts)
+	defer func() {
+		_ = it.Close()
+	}()
+	page := make([]kv.KeyValue, 0)
+	next := ""
+	for it.Next() {
+		if match != nil && !match(it.Key(), it.Value()) {
+			continue
+		}
+		// an accepted pair past the end of the page is only read to know whether there is another page.
+		if limit > 0 && len(page) == limit {
+			next = database.EncodeCursor(page[len(page)-1].Key.Bytes())
+			break
+		}
+		page = append(page, kv.NewKeyValueFromBytes(slices.Clone(it.Key()), slices.Clone(it.Value())))
+	}
+	if err = it.Err(); err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
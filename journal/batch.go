@@ -0,0 +1,38 @@
+package journal
+
+import (
+	"slices"
+)
+
+// Batch queues writes in memory until they are committed, it implements [database.Batch].
+type Batch struct {
+	ops    []Op
+	commit func(ops []Op) error
+}
+
+// NewBatch returns an empty Batch that hands its writes to commit, usually wrapping [Journal.Apply].
+func NewBatch(commit func(ops []Op) error) *Batch {
+	return &Batch{commit: commit}
+}
+
+// Put queues the value to be inserted at the given key.
+func (b *Batch) Put(key []byte, value []byte) {
+	b.ops = append(b.ops, Op{Key: slices.Clone(key), Value: slices.Clone(value)})
+}
+
+// Delete queues the key to be deleted.
+func (b *Batch) Delete(key []byte) {
+	b.ops = append(b.ops, Op{Key: slices.Clone(key), Delete: true})
+}
+
+// Len returns the number of writes queued in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Commit applies all queued writes together and empties the batch, whether or not it succeeds.
+func (b *Batch) Commit() error {
+	ops := b.ops
+	b.ops = nil
+	return b.commit(ops)
+}
@@ -0,0 +1,278 @@
+// Package journal makes batches of writes atomic for stores that can't apply them atomically on their own.
+//
+// Before a batch is applied, the values it is about to overwrite are recorded in an undo journal kept with the
+// store. The journal is removed once the batch has been applied and synced, which is the point at
+// which the batch is committed. If the process dies before then, [Journal.Recover] rolls the store back to the
+// state it was in before the batch, so that either all of a batch's writes take effect or none of them do.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// File is the name of the journal file kept with a store while a batch is being applied.
+const File = "batch.journal"
+
+var magic = []byte("tcpdjnl1")
+
+var errIncomplete = errors.New("incomplete journal")
+
+// Target is the store that a journal applies writes to and rolls back.
+// Get must return a [kv.NonExistentKeyError] for keys that don't exist.
+type Target interface {
+	Get(key []byte) ([]byte, error)
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+	Sync() error
+}
+
+// Op is a single write in a batch.
+type Op struct {
+	Key    []byte
+	Value  []byte
+	Delete bool
+}
+
+// undo records the state of a key before a batch was applied.
+type undo struct {
+	key     []byte
+	old     []byte
+	existed bool
+}
+
+// Journal applies batches of writes to a single target.
+// Callers are responsible for making sure that only one batch is applied to a target at a time.
+type Journal struct {
+	path   string
+	target Target
+}
+
+// New returns a Journal that keeps its file at path and writes to target.
+// An empty path keeps the undo records in memory only, which still rolls back batches that fail
+// but can't recover from a crash. This is meant for targets that don't persist anything themselves.
+func New(path string, target Target) *Journal {
+	return &Journal{path: path, target: target}
+}
+
+func encode(undos []undo) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(magic)
+	var scratch []byte
+	scratch = binary.AppendUvarint(scratch, uint64(len(undos)))
+	for _, u := range undos {
+		scratch = binary.AppendUvarint(scratch, uint64(len(u.key)))
+		scratch = append(scratch, u.key...)
+		if u.existed {
+			scratch = append(scratch, 1)
+		} else {
+			scratch = append(scratch, 0)
+		}
+		scratch = binary.AppendUvarint(scratch, uint64(len(u.old)))
+		scratch = append(scratch, u.old...)
+	}
+	buf.Write(scratch)
+	buf.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(buf.Bytes())))
+	return buf.Bytes()
+}
+
+func readChunk(r *bytes.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(r.Len()) {
+		return nil, errIncomplete
+	}
+	chunk := make([]byte, l)
+	_, err = io.ReadFull(r, chunk)
+	return chunk, err
+}
+
+func decode(dat []byte) ([]undo, error) {
+	if len(dat) < len(magic)+4 || !bytes.Equal(dat[:len(magic)], magic) {
+		return nil, errIncomplete
+	}
+	body, sum := dat[:len(dat)-4], dat[len(dat)-4:]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(sum) {
+		return nil, errIncomplete
+	}
+	r := bytes.NewReader(body[len(magic):])
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errIncomplete
+	}
+	undos := make([]undo, 0, n)
+	for i := uint64(0); i < n; i++ {
+		var u undo
+		if u.key, err = readChunk(r); err != nil {
+			return nil, errIncomplete
+		}
+		existed, err := r.ReadByte()
+		if err != nil {
+			return nil, errIncomplete
+		}
+		u.existed = existed == 1
+		if u.old, err = readChunk(r); err != nil {
+			return nil, errIncomplete
+		}
+		undos = append(undos, u)
+	}
+	return undos, nil
+}
+
+// write durably stores the undo records before any of the batch is applied.
+func (j *Journal) write(undos []undo) error {
+	if j.path == "" {
+		return nil
+	}
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if _, err = w.Write(encode(undos)); err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, j.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(j.path))
+	return nil
+}
+
+// syncDir makes a rename or removal within dir durable, where the platform supports it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+// rollback restores every key to the state it was in before the batch, most recent write first.
+func (j *Journal) rollback(undos []undo) error {
+	var errs []error
+	for i := len(undos) - 1; i >= 0; i-- {
+		u := undos[i]
+		var err error
+		if u.existed {
+			err = j.target.Put(u.key, u.old)
+		} else {
+			err = j.target.Delete(u.key)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", u.key, err))
+		}
+	}
+	errs = append(errs, j.target.Sync())
+	return errors.Join(errs...)
+}
+
+// finish removes the journal, committing or finishing the rollback of a batch.
+func (j *Journal) finish() error {
+	if j.path == "" {
+		return nil
+	}
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	syncDir(filepath.Dir(j.path))
+	return nil
+}
+
+// Recover rolls back a batch that was interrupted part way through being applied, returning true if there was one.
+// A journal that was itself only partially written is discarded, as nothing had been applied yet.
+// This must be called when the target is opened, before it is used.
+func (j *Journal) Recover() (bool, error) {
+	if j.path == "" {
+		return false, nil
+	}
+	_ = os.Remove(j.path + ".tmp")
+	dat, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	undos, err := decode(dat)
+	if err != nil {
+		return false, j.finish()
+	}
+	if err = j.rollback(undos); err != nil {
+		return true, fmt.Errorf("failed to roll back interrupted batch: %w", err)
+	}
+	return true, j.finish()
+}
+
+// Apply performs all the given writes in order such that either all of them take effect or none do.
+// If a write fails the batch is rolled back before returning the error.
+func (j *Journal) Apply(ops []Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	undos := make([]undo, 0, len(ops))
+	for _, op := range ops {
+		old, err := j.target.Get(op.Key)
+		switch {
+		case err == nil:
+			undos = append(undos, undo{key: op.Key, old: old, existed: true})
+		case kv.IsNonExistentKey(err):
+			undos = append(undos, undo{key: op.Key})
+		default:
+			return err
+		}
+	}
+	if err := j.write(undos); err != nil {
+		return fmt.Errorf("failed to write batch journal: %w", err)
+	}
+
+	var err error
+	for _, op := range ops {
+		if op.Delete {
+			err = j.target.Delete(op.Key)
+		} else {
+			err = j.target.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", op.Key, err)
+			break
+		}
+	}
+	if err == nil {
+		err = j.target.Sync()
+	}
+	if err != nil {
+		if rbErr := j.rollback(undos); rbErr != nil {
+			// the journal is left in place so the rollback can be retried by Recover.
+			return fmt.Errorf("batch failed (%w) and could not be rolled back: %w", err, rbErr)
+		}
+		return errors.Join(fmt.Errorf("batch rolled back: %w", err), j.finish())
+	}
+	if err = j.finish(); err != nil {
+		return fmt.Errorf("batch applied but journal could not be removed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+var errBoom = errors.New("boom")
+
+// mapTarget is an in-memory Target that can be told to fail a single write.
+type mapTarget struct {
+	data   map[string][]byte
+	failAt int
+	writes int
+}
+
+func newMapTarget() *mapTarget {
+	return &mapTarget{data: make(map[string][]byte), failAt: -1}
+}
+
+func (m *mapTarget) write() error {
+	if m.writes == m.failAt {
+		m.failAt = -1
+		return errBoom
+	}
+	m.writes++
+	return nil
+}
+
+func (m *mapTarget) Get(key []byte) ([]byte, error) {
+	v, ok := m.data[string(key)]
+	if !ok {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
+	return v, nil
+}
+
+func (m *mapTarget) Put(key []byte, value []byte) error {
+	if err := m.write(); err != nil {
+		return err
+	}
+	m.data[string(key)] = value
+	return nil
+}
+
+func (m *mapTarget) Delete(key []byte) error {
+	if err := m.write(); err != nil {
+		return err
+	}
+	delete(m.data, string(key))
+	return nil
+}
+
+func (m *mapTarget) Sync() error {
+	return nil
+}
+
+func (m *mapTarget) assert(t *testing.T, want map[string]string) {
+	t.Helper()
+	if len(m.data) != len(want) {
+		t.Errorf("expected %d keys, got %d: %v", len(want), len(m.data), m.data)
+	}
+	for k, v := range want {
+		if string(m.data[k]) != v {
+			t.Errorf("expected %s=%s, got %s", k, v, m.data[k])
+		}
+	}
+}
+
+func TestJournal_Apply(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	target := newMapTarget()
+	target.data["keep"] = []byte("me")
+	target.data["gone"] = []byte("soon")
+	j := New(path, target)
+
+	b := NewBatch(j.Apply)
+	b.Put([]byte("yeet"), []byte("yeeterson"))
+	b.Put([]byte("keep"), []byte("changed"))
+	b.Delete([]byte("gone"))
+	if b.Len() != 3 {
+		t.Fatalf("expected 3 queued writes, got %d", b.Len())
+	}
+	if err := b.Commit(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if b.Len() != 0 {
+		t.Errorf("expected batch to be empty after commit")
+	}
+	target.assert(t, map[string]string{"yeet": "yeeterson", "keep": "changed"})
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected journal to be removed after commit, got %v", err)
+	}
+}
+
+func TestJournal_RollbackOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	target := newMapTarget()
+	target.data["keep"] = []byte("me")
+	target.data["gone"] = []byte("soon")
+	target.failAt = 2
+	j := New(path, target)
+
+	err := j.Apply([]Op{
+		{Key: []byte("yeet"), Value: []byte("yeeterson")},
+		{Key: []byte("keep"), Value: []byte("changed")},
+		{Key: []byte("keep"), Value: []byte("twice")},
+		{Key: []byte("gone"), Delete: true},
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("expected errBoom, got %v", err)
+	}
+	target.assert(t, map[string]string{"keep": "me", "gone": "soon"})
+}
+
+func TestJournal_Recover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), File)
+	target := newMapTarget()
+	target.data["keep"] = []byte("me")
+	j := New(path, target)
+
+	// simulate a crash after the journal was written and part of the batch was applied.
+	undos := []undo{{key: []byte("keep"), old: []byte("me"), existed: true}, {key: []byte("yeet")}}
+	if err := j.write(undos); err != nil {
+		t.Fatal(err)
+	}
+	target.data["keep"] = []byte("changed")
+	target.data["yeet"] = []byte("yeeterson")
+
+	recovered, err := New(path, target).Recover()
+	if err != nil || !recovered {
+		t.Fatalf("expected batch to be recovered, got %t (err: %v)", recovered, err)
+	}
+	target.assert(t, map[string]string{"keep": "me"})
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected journal to be removed after recovery, got %v", err)
+	}
+
+	// a journal that was only partially written means nothing was applied yet.
+	dat := encode(undos)
+	if err = os.WriteFile(path, dat[:len(dat)-3], 0600); err != nil {
+		t.Fatal(err)
+	}
+	target.data["keep"] = []byte("later")
+	if recovered, err = j.Recover(); err != nil || recovered {
+		t.Fatalf("expected partial journal to be discarded, got %t (err: %v)", recovered, err)
+	}
+	target.assert(t, map[string]string{"keep": "later"})
+	if _, err = os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected partial journal to be removed, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"github.com/tcp-direct/database/backup"
 	"github.com/tcp-direct/database/models"
 )
 
@@ -28,10 +29,14 @@ type Keeper interface {
 	AllStores() map[string]Filer
 
 	// BackupAll should create a backup of all [Filer] instances in the [Keeper].
-	BackupAll(archivePath string) (models.Backup, error)
-
-	// RestoreAll should restore all [Filer] instances from the given archive.
-	RestoreAll(archivePath string) error
+	// The archive format should follow the extension of archivePath: .tar.gz, .tar.zst, .tar.xz, .tar or .zip,
+	// defaulting to .tar.gz. Compression levels can be set with backup.WithLevel.
+	// The archive is written with opts, such as backup.WithKeys to encrypt it.
+	BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error)
+
+	// RestoreAll should restore all [Filer] instances from the given archive, in any of the formats BackupAll writes.
+	// The archive is read with opts, such as backup.WithKeys to decrypt it.
+	RestoreAll(archivePath string, opts ...backup.Option) error
 
 	Meta() models.Metadata
 
@@ -0,0 +1,13 @@
+package database
+
+import "github.com/tcp-direct/database/kv"
+
+// KeySearcher is a Searcher that can also match keys by their suffix, or by a substring anywhere in them.
+// Results are streamed with the same contract as [Searcher.PrefixScan].
+type KeySearcher interface {
+	Searcher
+	// SuffixScan should stream every key/value pair whose key ends with suffix.
+	SuffixScan(suffix string) (<-chan kv.KeyValue, chan error)
+	// KeyContains should stream every key/value pair whose key contains substr.
+	KeyContains(substr string) (<-chan kv.KeyValue, chan error)
+}
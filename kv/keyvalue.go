@@ -14,6 +14,21 @@ func NewKeyValueFromBytes(key []byte, value []byte) KeyValue {
 	return KeyValue{Key: NewKey(key), Value: NewValue(value)}
 }
 
+// StoreKeyValue is a KeyValue tagged with the name of the store it was found in.
+type StoreKeyValue struct {
+	Store string
+	KeyValue
+}
+
+// NewStoreKeyValue tags a KeyValue with the name of the store it was found in.
+func NewStoreKeyValue(store string, keyVal KeyValue) StoreKeyValue {
+	return StoreKeyValue{Store: store, KeyValue: keyVal}
+}
+
+func (skv StoreKeyValue) String() string {
+	return skv.Store + "/" + skv.KeyValue.String()
+}
+
 // Key represents a key in a key/value store.
 type Key struct {
 	b []byte
@@ -0,0 +1,14 @@
+package kv
+
+import "context"
+
+// Send delivers keyVal to ch unless ctx is done first, returning false if it was.
+// It keeps the goroutines behind streaming searches from blocking forever once nobody is reading.
+func Send(ctx context.Context, ch chan<- KeyValue, keyVal KeyValue) bool {
+	select {
+	case ch <- keyVal:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
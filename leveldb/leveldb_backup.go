@@ -0,0 +1,178 @@
+package leveldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/models"
+)
+
+// snapshotBatchSize is the number of records copied per write when staging a store for backup.
+const snapshotBatchSize = 1000
+
+// copySnapshot writes a consistent, point in time copy of the store to a fresh leveldb at dest,
+// stopping once ctx is done.
+func (s *Store) copySnapshot(ctx context.Context, dest string) error {
+	snap, err := s.DB.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	out, err := leveldb.OpenFile(dest, nil)
+	if err != nil {
+		return err
+	}
+
+	iter := snap.NewIterator(nil, nil)
+	batch := new(leveldb.Batch)
+	for iter.Next() {
+		batch.Put(iter.Key(), iter.Value())
+		if batch.Len() < snapshotBatchSize {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if err = out.Write(batch, nil); err != nil {
+			break
+		}
+		batch.Reset()
+	}
+	iter.Release()
+
+	if err == nil {
+		err = iter.Error()
+	}
+	if err == nil && batch.Len() > 0 {
+		err = out.Write(batch, nil)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// BackupAll creates an archive of all leveldb stores and the keeper's metadata.
+// Each store is copied from a snapshot, so the stores remain open and usable during and after the backup.
+func (db *DB) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	return db.BackupAllContext(context.Background(), archivePath, opts...)
+}
+
+// BackupAllContext is like BackupAll, but writes the archive with opts and abandons the backup once ctx is done.
+func (db *DB) BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error) {
+	if err := db.init(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	storeNames, err := db.discover()
+	if err != nil {
+		return nil, err
+	}
+	if err = db.syncAll(); err != nil && !errors.Is(err, ErrNoStores) {
+		return nil, err
+	}
+
+	staging, err := os.MkdirTemp("", "leveldb-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	metaDat, err := os.ReadFile(filepath.Join(db.path, "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(filepath.Join(staging, "meta.json"), metaDat, 0600); err != nil {
+		return nil, err
+	}
+
+	for _, name := range storeNames {
+		if err = db.store[name].copySnapshot(ctx, filepath.Join(staging, name)); err != nil {
+			return nil, namedErr(name, err)
+		}
+	}
+
+	b, err := backup.NewBackuper(backup.FormatFromPath(archivePath), opts...)
+	if err != nil {
+		return nil, err
+	}
+	bu, err := b.Backup(ctx, staging, archivePath, storeNames)
+	if err != nil {
+		return nil, err
+	}
+	if db.meta.Backups == nil {
+		db.meta.Backups = make(map[string]any)
+	}
+	db.meta.Backups[bu.FilePath] = bu
+	err = db.meta.Sync()
+	return bu, err
+}
+
+// RestoreAll replaces all leveldb stores with the contents of the given archive.
+// A backup of the existing stores is taken first and referenced in any errors.
+func (db *DB) RestoreAll(archivePath string, opts ...backup.Option) error {
+	if err := db.init(); err != nil {
+		return err
+	}
+
+	var preBackupPath string
+
+	db.mu.RLock()
+	hasStores := len(db.store) > 0
+	db.mu.RUnlock()
+
+	if hasStores {
+		preBu, err := db.BackupAll(filepath.Join(os.TempDir(), "pre-restore-"+time.Now().Format(time.RFC3339)+".tar.gz"))
+		if err != nil {
+			return fmt.Errorf("failed to create pre-restore backup: %w", err)
+		}
+		preBackupPath = fmt.Sprintf(" (backup: %s)", preBu.Path())
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for name, st := range db.store {
+		_ = st.Close()
+		delete(db.store, name)
+		if err := os.RemoveAll(filepath.Join(db.path, name)); err != nil {
+			return fmt.Errorf("failed to remove existing store %s%s: %w", name, preBackupPath, err)
+		}
+	}
+
+	// release the handle on the current meta.json before it is overwritten by the archive's copy.
+	db.meta.WithWriter(nil)
+	db.initialized.Store(false)
+
+	opts = append([]backup.Option{backup.WithKnown(backup.KnownBackups(db.meta)...)}, opts...)
+	if err := backup.RestoreBackup(archivePath, db.path, opts...); err != nil {
+		return fmt.Errorf("failed to restore archive%s: %w", preBackupPath, err)
+	}
+
+	if err := db._init(); err != nil {
+		return fmt.Errorf("failed to re-init db after restore%s: %w", preBackupPath, err)
+	}
+
+	if _, err := db.discover(); err != nil {
+		return fmt.Errorf("failed during discover call after restore%s: %w", preBackupPath, err)
+	}
+
+	if err := db.meta.Sync(); err != nil {
+		return fmt.Errorf("failed to sync meta after restore%s: %w", preBackupPath, err)
+	}
+
+	return nil
+}
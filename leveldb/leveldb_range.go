@@ -0,0 +1,26 @@
+package leveldb
+
+import (
+	"io/fs"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+)
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Each page of results is read with its own native iterator, so none are left open if the
+// returned iterator is abandoned.
+func (s *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if s.closed.Load() {
+		return iterator.Error(fs.ErrClosed)
+	}
+	return iterator.Paged(func(after []byte, n int) ([][]byte, [][]byte, error) {
+		if s.closed.Load() {
+			return nil, nil, fs.ErrClosed
+		}
+		iter := s.DB.NewIterator(nil, nil)
+		defer iter.Release()
+		keys, values := iterator.Walk(iter, start, end, after, opts.Reverse, n)
+		return keys, values, iter.Error()
+	}, opts.Limit)
+}
@@ -0,0 +1,88 @@
+package leveldb
+
+import (
+	"bytes"
+	"context"
+	"slices"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// stream sends every key/value pair yielded by the iterator that satisfies match to the returned channel,
+// stopping once ctx is done.
+func stream(ctx context.Context, iter iterator.Iterator, match func(k, v []byte) bool) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			iter.Release()
+			close(resChan)
+			close(errChan)
+		}()
+		for iter.Next() {
+			if ctx.Err() != nil {
+				errChan <- ctx.Err()
+				return
+			}
+			if !match(iter.Key(), iter.Value()) {
+				continue
+			}
+			if !kv.Send(ctx, resChan, kv.NewKeyValueFromBytes(slices.Clone(iter.Key()), slices.Clone(iter.Value()))) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+		if err := iter.Error(); err != nil {
+			errChan <- err
+		}
+	}()
+	return resChan, errChan
+}
+
+// Search will search for a given string within all values inside of a Store.
+// Note, type casting will be necessary. (e.g: []byte or string)
+func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+func (s *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(query)
+	return stream(ctx, s.DB.NewIterator(nil, nil), func(_, v []byte) bool {
+		return bytes.Contains(v, needle)
+	})
+}
+
+// ValueExists will check for the existence of a Value anywhere within the keyspace;
+// returning the first Key found, true if found || nil and false if not found.
+func (s *Store) ValueExists(value []byte) (key []byte, ok bool) {
+	iter := s.DB.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if bytes.Equal(iter.Value(), value) {
+			return slices.Clone(iter.Key()), true
+		}
+	}
+	return nil, false
+}
+
+// PrefixScan will scan a Store for all keys that have a matching prefix of the given string
+// and stream the matching key/value pairs, in byte order, to the returned channel.
+// The underlying iterator seeks directly to the prefix rather than walking the whole table.
+func (s *Store) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
+	return s.PrefixScanContext(context.Background(), prefix)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+func (s *Store) PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error) {
+	var rng *util.Range
+	if prefix != "" {
+		rng = util.BytesPrefix([]byte(prefix))
+	}
+	return stream(ctx, s.DB.NewIterator(rng, nil), func(_, _ []byte) bool {
+		return true
+	})
+}
@@ -0,0 +1,38 @@
+package leveldb
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// SuffixScan will stream every key/value pair whose key ends with suffix, in byte order.
+// Keys are sorted from their start, so the whole table is walked.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(suffix)
+	return stream(ctx, s.DB.NewIterator(nil, nil), func(k, _ []byte) bool {
+		return bytes.HasSuffix(k, needle)
+	})
+}
+
+// KeyContains will stream every key/value pair whose key contains substr, in byte order.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return s.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (s *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(substr)
+	return stream(ctx, s.DB.NewIterator(nil, nil), func(k, _ []byte) bool {
+		return bytes.Contains(k, needle)
+	})
+}
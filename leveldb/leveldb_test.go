@@ -0,0 +1,132 @@
+package leveldb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	c "git.tcp.direct/kayos/common/entropy"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/loader"
+)
+
+func TestStore_Basic(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	if err := db.Init("test"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := db.Init("test"); !errors.Is(err, ErrStoreExists) {
+		t.Fatalf("expected ErrStoreExists, got %v", err)
+	}
+	if err := db.Init("bad", "yeet"); !errors.Is(err, ErrBadOptions) {
+		t.Fatalf("expected ErrBadOptions, got %v", err)
+	}
+	st := db.With("test")
+	if err := st.Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	ret, err := st.Get([]byte("yeet"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s", ret)
+	}
+	if !st.Has([]byte("yeet")) || st.Len() != 1 || len(st.Keys()) != 1 {
+		t.Errorf("expected exactly one key in store")
+	}
+	if err = st.Delete([]byte("yeet")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !kv.IsNonExistentKey(err) {
+		t.Errorf("expected NonExistentKeyError, got %v", err)
+	}
+	if err = db.Close("test"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+}
+
+func TestStore_PrefixScanOrdered(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	st := db.WithNew("test").(database.Store)
+	for i := 0; i < 100; i++ {
+		if err := st.Put([]byte(fmt.Sprintf("key_%03d", i)), []byte(c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.Put([]byte(c.RandStr(10)), []byte("needle"+c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resChan, errChan := st.PrefixScan("key_0")
+	var last []byte
+	n := 0
+	for res := range resChan {
+		if last != nil && bytes.Compare(last, res.Key.Bytes()) >= 0 {
+			t.Errorf("expected keys in order, got %s after %s", res.Key.String(), last)
+		}
+		last = res.Key.Bytes()
+		n++
+	}
+	for err := range errChan {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("expected 100 results, got %d", n)
+	}
+
+	resChan, errChan = st.Search("needle")
+	n = 0
+	for range resChan {
+		n++
+	}
+	for err := range errChan {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 100 {
+		t.Errorf("expected 100 search results, got %d", n)
+	}
+}
+
+func TestDB_BackupKeepsStoresOpen(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	if err := db.WithNew("test").Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatal(err)
+	}
+	bu, err := db.BackupAll(filepath.Join(t.TempDir(), "leveldb.tar.gz"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = db.With("test").Put([]byte("after"), []byte("backup")); err != nil {
+		t.Fatalf("expected store to be usable after backup, got %v", err)
+	}
+	if err = db.RestoreAll(bu.Path()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if db.With("test").Has([]byte("after")) {
+		t.Error("expected key written after the backup to be gone after restore")
+	}
+	if !db.With("test").Has([]byte("yeet")) {
+		t.Error("expected key written before the backup to exist after restore")
+	}
+	if err = db.SyncAndCloseAll(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	keeper, err := loader.OpenKeeper(path)
+	if err != nil {
+		t.Fatalf("expected loader to open leveldb keeper, got %v", err)
+	}
+	if ret, getErr := keeper.With("test").Get([]byte("yeet")); getErr != nil || string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s (err: %v)", ret, getErr)
+	}
+	_ = keeper.CloseAll()
+}
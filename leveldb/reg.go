@@ -0,0 +1,21 @@
+package leveldb
+
+import (
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/registry"
+)
+
+func init() {
+	registry.RegisterKeeper("leveldb", func(path string, opts ...any) (database.Keeper, error) {
+		if len(opts) > 0 {
+			casted, err := castOptions(opts...)
+			if err != nil {
+				return nil, err
+			}
+			SetDefaultLevelDBOptions(casted)
+		}
+		db := OpenDB(path)
+		err := db.init()
+		return db, err
+	})
+}
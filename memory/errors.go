@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"errors"
+)
+
+//goland:noinspection GoExportedElementShouldHaveComment
+var (
+	ErrBogusStore  = errors.New("bogus store backend")
+	ErrStoreExists = errors.New("store name already exists")
+	ErrNoStores    = errors.New("no stores initialized")
+	ErrEmptyKey    = errors.New("empty key")
+	ErrBadDump     = errors.New("malformed memory store dump")
+)
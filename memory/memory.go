@@ -0,0 +1,349 @@
+package memory
+
+import (
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+// Store is an implementation of a Filer and a Searcher that lives entirely in RAM.
+type Store struct {
+	data   map[string][]byte
+	mu     *sync.RWMutex
+	closed *atomic.Bool
+}
+
+func newStore() *Store {
+	aclosed := &atomic.Bool{}
+	aclosed.Store(false)
+	return &Store{
+		data:   make(map[string][]byte),
+		mu:     &sync.RWMutex{},
+		closed: aclosed,
+	}
+}
+
+// Backend returns the underlying map of the memory store.
+func (s *Store) Backend() any {
+	return s.data
+}
+
+// Has returns true if the given key has an associated value.
+func (s *Store) Has(key []byte) bool {
+	if s.closed.Load() {
+		return false
+	}
+	s.mu.RLock()
+	_, ok := s.data[string(key)]
+	s.mu.RUnlock()
+	return ok
+}
+
+// Get retrieves a copy of the value associated with the given key.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	if s.closed.Load() {
+		return nil, fs.ErrClosed
+	}
+	s.mu.RLock()
+	raw, ok := s.data[string(key)]
+	s.mu.RUnlock()
+	var ret []byte
+	if ok {
+		ret = slices.Clone(raw)
+		if ret == nil {
+			ret = []byte{}
+		}
+	}
+	return ret, kv.RegularizeKVError(key, ret, nil)
+}
+
+// Put stores a copy of the given value under the given key.
+func (s *Store) Put(key []byte, value []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	s.mu.Lock()
+	s.data[string(key)] = slices.Clone(value)
+	s.mu.Unlock()
+	return nil
+}
+
+// Delete removes the given key and its value from the store.
+func (s *Store) Delete(key []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	s.mu.Lock()
+	delete(s.data, string(key))
+	s.mu.Unlock()
+	return nil
+}
+
+// Close marks the store as closed. The data is retained by the [DB] so the store can be reopened.
+func (s *Store) Close() error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	s.closed.Store(true)
+	return nil
+}
+
+// Sync is a no-op for memory stores.
+func (s *Store) Sync() error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	return nil
+}
+
+// Keys returns all keys in the store as a slice of byte slices.
+func (s *Store) Keys() [][]byte {
+	s.mu.RLock()
+	keys := make([][]byte, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, []byte(k))
+	}
+	s.mu.RUnlock()
+	return keys
+}
+
+// Len returns the number of keys in the store.
+func (s *Store) Len() int {
+	s.mu.RLock()
+	l := len(s.data)
+	s.mu.RUnlock()
+	return l
+}
+
+// reopen returns a fresh handle to the same underlying data.
+func (s *Store) reopen() *Store {
+	aclosed := &atomic.Bool{}
+	aclosed.Store(false)
+	return &Store{data: s.data, mu: s.mu, closed: aclosed}
+}
+
+// DB is a mapper of a Filer and Searcher implementation that keeps everything in memory.
+type DB struct {
+	store map[string]*Store
+	// dormant holds the data of closed stores so that they can be reopened with [DB.WithNew] or [DB.Init].
+	dormant map[string]*Store
+	path    string
+	mu      *sync.RWMutex
+	meta    *metadata.Metadata
+}
+
+// OpenDB creates a new set of memory datastores. The path is only used as a reference point and is never written to.
+func OpenDB(path string) *DB {
+	return &DB{
+		store:   make(map[string]*Store),
+		dormant: make(map[string]*Store),
+		path:    path,
+		mu:      &sync.RWMutex{},
+		meta:    metadata.NewMeta(metadata.KeeperType("memory")),
+	}
+}
+
+// Path returns the path that was given to [OpenDB].
+func (db *DB) Path() string {
+	return db.path
+}
+
+// Meta returns the [models.Metadata] implementation of the memory keeper.
+func (db *DB) Meta() models.Metadata {
+	db.mu.Lock()
+	db.addAllStoresToMeta()
+	m := db.meta
+	db.mu.Unlock()
+	return m
+}
+
+func (db *DB) addAllStoresToMeta() {
+	storeNames := make([]string, 0, len(db.store))
+	for name := range db.store {
+		storeNames = append(storeNames, name)
+	}
+	slices.Sort(storeNames)
+	db.meta = db.meta.WithStores(storeNames...)
+}
+
+func (db *DB) allStores() map[string]database.Filer {
+	var stores = make(map[string]database.Filer)
+	for n, s := range db.store {
+		stores[n] = s
+	}
+	return stores
+}
+
+// AllStores returns a map of the names of all open memory datastores and the corresponding Filers.
+func (db *DB) AllStores() map[string]database.Filer {
+	db.mu.RLock()
+	ast := db.allStores()
+	db.mu.RUnlock()
+	return ast
+}
+
+// initStore is a helper function to initialize a memory store, caller must hold keeper's lock.
+func (db *DB) initStore(storeName string) error {
+	if _, ok := db.store[storeName]; ok {
+		return ErrStoreExists
+	}
+	if st, ok := db.dormant[storeName]; ok {
+		db.store[storeName] = st.reopen()
+		delete(db.dormant, storeName)
+		return nil
+	}
+	db.store[storeName] = newStore()
+	return nil
+}
+
+// Init creates a memory store to be referenced by storeName.
+// Memory stores have no options, any that are given are ignored.
+func (db *DB) Init(storeName string, _ ...any) error {
+	db.mu.Lock()
+	err := db.initStore(storeName)
+	db.mu.Unlock()
+	return err
+}
+
+// With returns the memory store by the given name, or nil if it is not open.
+func (db *DB) With(storeName string) database.Filer {
+	db.mu.RLock()
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		db.mu.RUnlock()
+		return d
+	}
+	db.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	// the store may have been reopened or removed while the lock was dropped.
+	switch cur, ok := db.store[storeName]; {
+	case ok && !cur.closed.Load():
+		return cur
+	case ok && cur == d:
+		delete(db.store, storeName)
+		db.dormant[storeName] = d
+	}
+	return nil
+}
+
+// WithNew returns the memory store by the given name, creating or reopening it if needed.
+func (db *DB) WithNew(storeName string, _ ...any) database.Filer {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		return d
+	}
+	if ok {
+		delete(db.store, storeName)
+		db.dormant[storeName] = d
+	}
+	_ = db.initStore(storeName)
+	return db.store[storeName]
+}
+
+// Destroy removes the memory store and all of its data.
+func (db *DB) Destroy(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	_, dormant := db.dormant[storeName]
+	if !ok && !dormant {
+		return ErrBogusStore
+	}
+	if ok {
+		_ = st.Close()
+	}
+	delete(db.store, storeName)
+	delete(db.dormant, storeName)
+	return nil
+}
+
+// Discover returns the names of all memory stores that are currently open.
+func (db *DB) Discover() ([]string, error) {
+	db.mu.RLock()
+	stores := make([]string, 0, len(db.store))
+	for name := range db.store {
+		stores = append(stores, name)
+	}
+	db.mu.RUnlock()
+	return stores, nil
+}
+
+// Close closes the memory store by the given name, it can be reopened with [DB.WithNew].
+func (db *DB) Close(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	if err := st.Close(); err != nil {
+		return err
+	}
+	delete(db.store, storeName)
+	db.dormant[storeName] = st
+	return nil
+}
+
+func (db *DB) closeAll() error {
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	for name, st := range db.store {
+		_ = st.Close()
+		delete(db.store, name)
+		db.dormant[name] = st
+	}
+	return nil
+}
+
+// CloseAll closes all memory stores.
+func (db *DB) CloseAll() error {
+	db.mu.Lock()
+	err := db.closeAll()
+	db.mu.Unlock()
+	return err
+}
+
+// SyncAll only refreshes the keeper's metadata, as there is nothing to persist.
+func (db *DB) SyncAll() error {
+	db.mu.Lock()
+	db.addAllStoresToMeta()
+	db.mu.Unlock()
+	return nil
+}
+
+// SyncAndCloseAll implements the method from Keeper to sync and close all memory stores.
+func (db *DB) SyncAndCloseAll() error {
+	db.mu.Lock()
+	db.addAllStoresToMeta()
+	err := db.closeAll()
+	db.mu.Unlock()
+	return err
+}
+
+// InMemory returns true, the memory keeper's stores are gone once the process exits.
+// This is in order to implement [database.InMemoryKeeper].
+func (db *DB) InMemory() bool {
+	return true
+}
+
+// Type returns the type of keeper, in this case "memory".
+// This is in order to implement [database.Keeper].
+func (db *DB) Type() string {
+	return "memory"
+}
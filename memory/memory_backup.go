@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+// dumpFile is the name of the file each store is serialized to inside of a backup archive.
+const dumpFile = "memory.dat"
+
+// dump writes every key/value pair in the store as a pair of length-prefixed byte slices, in key order,
+// so that a store that hasn't changed dumps to the same bytes and is left out of incremental backups.
+func (s *Store) dump(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var lenBuf = make([]byte, binary.MaxVarintLen64)
+	for _, k := range slices.Sorted(maps.Keys(s.data)) {
+		for _, chunk := range [][]byte{[]byte(k), s.data[k]} {
+			n := binary.PutUvarint(lenBuf, uint64(len(chunk)))
+			if _, err := bw.Write(lenBuf[:n]); err != nil {
+				return err
+			}
+			if _, err := bw.Write(chunk); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func readChunk(br *bufio.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, err
+	}
+	chunk := make([]byte, l)
+	if _, err = io.ReadFull(br, chunk); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadDump, err)
+	}
+	return chunk, nil
+}
+
+// load reads key/value pairs written by dump into a new store.
+func load(r io.Reader) (*Store, error) {
+	st := newStore()
+	br := bufio.NewReader(r)
+	for {
+		key, err := readChunk(br)
+		if errors.Is(err, io.EOF) {
+			return st, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		value, err := readChunk(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = ErrBadDump
+			}
+			return nil, err
+		}
+		st.data[string(key)] = value
+	}
+}
+
+// stage writes all stores, open and closed alike, along with meta.json to the given directory,
+// stopping once ctx is done. caller must hold write lock.
+func (db *DB) stage(ctx context.Context, dir string) ([]string, error) {
+	all := make(map[string]*Store, len(db.store)+len(db.dormant))
+	for name, st := range db.dormant {
+		all[name] = st
+	}
+	for name, st := range db.store {
+		all[name] = st
+	}
+	storeNames := make([]string, 0, len(all))
+	for name, st := range all {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Join(dir, name), 0700); err != nil {
+			return nil, err
+		}
+		f, err := os.Create(filepath.Join(dir, name, dumpFile))
+		if err != nil {
+			return nil, err
+		}
+		err = st.dump(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing store %s: %w", name, err)
+		}
+		storeNames = append(storeNames, name)
+	}
+	slices.Sort(storeNames)
+	db.meta = db.meta.WithStores(storeNames...)
+	metaDat, err := json.Marshal(db.meta)
+	if err != nil {
+		return nil, err
+	}
+	return storeNames, os.WriteFile(filepath.Join(dir, "meta.json"), metaDat, 0600)
+}
+
+// BackupAll serializes all memory stores and writes them to an archive at archivePath.
+func (db *DB) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	return db.BackupAllContext(context.Background(), archivePath, opts...)
+}
+
+// BackupAllContext is like BackupAll, but writes the archive with opts and abandons the backup once ctx is done.
+func (db *DB) BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	staging, err := os.MkdirTemp("", "memory-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	storeNames, err := db.stage(ctx, staging)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := backup.NewBackuper(backup.FormatFromPath(archivePath), opts...)
+	if err != nil {
+		return nil, err
+	}
+	bu, err := b.Backup(ctx, staging, archivePath, storeNames)
+	if err != nil {
+		return nil, err
+	}
+	if db.meta.Backups == nil {
+		db.meta.Backups = make(map[string]any)
+	}
+	db.meta.Backups[bu.FilePath] = bu
+	return bu, nil
+}
+
+// RestoreAll replaces all memory stores with the contents of the given archive.
+func (db *DB) RestoreAll(archivePath string, opts ...backup.Option) error {
+	staging, err := os.MkdirTemp("", "memory-restore-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	opts = append([]backup.Option{backup.WithKnown(backup.KnownBackups(db.meta)...)}, opts...)
+	if err = backup.RestoreBackup(archivePath, staging, opts...); err != nil {
+		return err
+	}
+
+	meta, err := metadata.OpenMetaFile(filepath.Join(staging, "meta.json"))
+	if err != nil {
+		return fmt.Errorf("error reading meta.json from archive: %w", err)
+	}
+	if meta.Type() != db.Type() {
+		return fmt.Errorf("archive contains a %s keeper, not a memory keeper", meta.Type())
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return err
+	}
+
+	restored := make(map[string]*Store, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		f, ferr := os.Open(filepath.Join(staging, entry.Name(), dumpFile))
+		if ferr != nil {
+			return fmt.Errorf("error opening store %s from archive: %w", entry.Name(), ferr)
+		}
+		st, loadErr := load(f)
+		_ = f.Close()
+		if loadErr != nil {
+			return fmt.Errorf("error loading store %s from archive: %w", entry.Name(), loadErr)
+		}
+		restored[entry.Name()] = st
+	}
+
+	db.mu.Lock()
+	for _, st := range db.store {
+		_ = st.Close()
+	}
+	db.store = restored
+	db.dormant = make(map[string]*Store)
+	db.meta = db.meta.WithCreated(meta.Created)
+	db.addAllStoresToMeta()
+	db.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"io/fs"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+)
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Maps have no ordering, so the keys within the range are sorted up front.
+func (s *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if s.closed.Load() {
+		return iterator.Error(fs.ErrClosed)
+	}
+	s.mu.RLock()
+	keys := make([][]byte, 0)
+	for k := range s.data {
+		if iterator.InRange([]byte(k), start, end) {
+			keys = append(keys, []byte(k))
+		}
+	}
+	s.mu.RUnlock()
+	return iterator.Sorted(keys, start, end, opts, s.Get)
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// snapshot returns a point in time copy of all key/value pairs in the store.
+func (s *Store) snapshot() []kv.KeyValue {
+	s.mu.RLock()
+	kvs := make([]kv.KeyValue, 0, len(s.data))
+	for k, v := range s.data {
+		kvs = append(kvs, kv.NewKeyValueFromBytes([]byte(k), bytes.Clone(v)))
+	}
+	s.mu.RUnlock()
+	return kvs
+}
+
+// stream sends every key/value pair in a snapshot of the store that satisfies match to the returned channel,
+// stopping once ctx is done.
+func (s *Store) stream(ctx context.Context, match func(keyVal kv.KeyValue) bool) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		for _, keyVal := range s.snapshot() {
+			if !match(keyVal) {
+				continue
+			}
+			if !kv.Send(ctx, resChan, keyVal) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+	}()
+	return resChan, errChan
+}
+
+// Search will search for a given string within all values inside of a Store.
+// Note, type casting will be necessary. (e.g: []byte or string)
+func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+func (s *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, func(keyVal kv.KeyValue) bool {
+		return strings.Contains(keyVal.Value.String(), query)
+	})
+}
+
+// ValueExists will check for the existence of a Value anywhere within the keyspace;
+// returning the first Key found, true if found || nil and false if not found.
+func (s *Store) ValueExists(value []byte) (key []byte, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for k, v := range s.data {
+		if bytes.Equal(v, value) {
+			return []byte(k), true
+		}
+	}
+	return nil, false
+}
+
+// PrefixScan will scan a Store for all keys that have a matching prefix of the given string
+// and stream the matching key/value pairs to the returned channel.
+func (s *Store) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
+	return s.PrefixScanContext(context.Background(), prefix)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+func (s *Store) PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, func(keyVal kv.KeyValue) bool {
+		return strings.HasPrefix(keyVal.Key.String(), prefix)
+	})
+}
@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// SuffixScan will stream every key/value pair whose key ends with suffix.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, func(keyVal kv.KeyValue) bool {
+		return strings.HasSuffix(keyVal.Key.String(), suffix)
+	})
+}
+
+// KeyContains will stream every key/value pair whose key contains substr.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return s.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (s *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, func(keyVal kv.KeyValue) bool {
+		return strings.Contains(keyVal.Key.String(), substr)
+	})
+}
@@ -0,0 +1,210 @@
+package memory
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	c "git.tcp.direct/kayos/common/entropy"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/kv"
+)
+
+func TestStore_Basic(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	if err := db.Init("test"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := db.Init("test"); !errors.Is(err, ErrStoreExists) {
+		t.Fatalf("expected ErrStoreExists, got %v", err)
+	}
+	st := db.With("test")
+	if err := st.Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := st.Put(nil, []byte("yeeterson")); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("expected ErrEmptyKey, got %v", err)
+	}
+	ret, err := st.Get([]byte("yeet"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s", ret)
+	}
+	ret[0] = 'Y'
+	if ret, _ = st.Get([]byte("yeet")); string(ret) != "yeeterson" {
+		t.Errorf("mutating a returned value should not affect the store, got %s", ret)
+	}
+	if !st.Has([]byte("yeet")) || st.Len() != 1 || len(st.Keys()) != 1 {
+		t.Errorf("expected exactly one key in store")
+	}
+	if err = st.Delete([]byte("yeet")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !kv.IsNonExistentKey(err) {
+		t.Errorf("expected NonExistentKeyError, got %v", err)
+	}
+}
+
+func TestDB_CloseAndReopen(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	if err := db.WithNew("test").Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	st := db.With("test")
+	if err := db.Close("test"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := st.Get([]byte("yeet")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed from closed store, got %v", err)
+	}
+	if db.With("test") != nil {
+		t.Fatal("expected nil store after close")
+	}
+	ret, err := db.WithNew("test").Get([]byte("yeet"))
+	if err != nil {
+		t.Fatalf("expected data to survive close, got %v", err)
+	}
+	if string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s", ret)
+	}
+	if err = db.SyncAndCloseAll(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = db.Destroy("test"); err != nil {
+		t.Fatalf("expected no error destroying a closed store, got %v", err)
+	}
+	if db.WithNew("test").Len() != 0 {
+		t.Error("expected empty store after destroy")
+	}
+}
+
+func TestDB_WithReopenRace(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	db.WithNew("test")
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					db.With("test")
+				}
+			}
+		}()
+	}
+	defer func() {
+		close(done)
+		wg.Wait()
+	}()
+	for i := 0; i < 20; i++ {
+		st := db.WithNew("test")
+		runtime.Gosched()
+		if db.With("test") != st {
+			t.Fatalf("expected the open store to be kept, lost it after %d reopens", i)
+		}
+		if err := st.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestStore_Search(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	st := db.WithNew("test").(database.Store)
+	for i := 0; i < 100; i++ {
+		if err := st.Put([]byte(c.RandStr(10)), []byte(c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = st.Put([]byte("prefix_one"), []byte("needle_one"))
+	_ = st.Put([]byte("prefix_two"), []byte("yeet needle"))
+
+	count := func(resChan <-chan kv.KeyValue, errChan chan error) int {
+		n := 0
+		for range resChan {
+			n++
+		}
+		for err := range errChan {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return n
+	}
+
+	if n := count(st.Search("needle")); n != 2 {
+		t.Errorf("expected 2 search results, got %d", n)
+	}
+	if n := count(st.PrefixScan("prefix_")); n != 2 {
+		t.Errorf("expected 2 prefix scan results, got %d", n)
+	}
+	if key, ok := st.ValueExists([]byte("yeet needle")); !ok || string(key) != "prefix_two" {
+		t.Errorf("expected prefix_two, got %s", key)
+	}
+	if _, ok := st.ValueExists([]byte("nope")); ok {
+		t.Error("expected value to not exist")
+	}
+}
+
+func TestDB_BackupAndRestore(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	inserted := make(map[string]map[string][]byte)
+	for i := 0; i < 5; i++ {
+		name := c.RandStr(5)
+		inserted[name] = make(map[string][]byte)
+		for j := 0; j < 50; j++ {
+			key, value := []byte(c.RandStr(10)), []byte(c.RandStr(c.RNG(100)))
+			if err := db.WithNew(name).Put(key, value); err != nil {
+				t.Fatal(err)
+			}
+			inserted[name][string(key)] = value
+		}
+	}
+
+	archive := filepath.Join(t.TempDir(), "memory.tar.gz")
+	bu, err := db.BackupAll(archive)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = backup.VerifyBackup(bu.(backup.BackupMetadata)); err != nil {
+		t.Fatalf("expected valid backup, got %v", err)
+	}
+
+	restored := OpenDB(t.TempDir())
+	_ = restored.WithNew("should_be_gone").Put([]byte("yeet"), []byte("yeet"))
+	if err = restored.RestoreAll(archive); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if restored.With("should_be_gone") != nil {
+		t.Error("expected pre-existing store to be replaced by restore")
+	}
+	for name, kvs := range inserted {
+		st := restored.With(name)
+		if st == nil {
+			t.Fatalf("store %s missing after restore", name)
+		}
+		if st.Len() != len(kvs) {
+			t.Errorf("expected %d keys in %s, got %d", len(kvs), name, st.Len())
+		}
+		for k, v := range kvs {
+			ret, getErr := st.Get([]byte(k))
+			if getErr != nil {
+				t.Fatalf("expected no error, got %v", getErr)
+			}
+			if !bytes.Equal(ret, v) {
+				t.Errorf("expected %q, got %q", v, ret)
+			}
+		}
+	}
+}
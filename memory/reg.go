@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/registry"
+)
+
+func init() {
+	registry.RegisterKeeper("memory", func(path string, _ ...any) (database.Keeper, error) {
+		return OpenDB(path), nil
+	})
+}
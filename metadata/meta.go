@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 
 	"github.com/tcp-direct/database/models"
@@ -22,6 +23,9 @@ type Metadata struct {
 	Backups      map[string]any         `json:"backups,omitempty"`
 	Extra        map[string]interface{} `json:"extra,omitempty"`
 	DefStoreOpts any                    `json:"default_store_opts,omitempty"`
+	Indexes      map[string][]Index     `json:"indexes,omitempty"`
+	TextIndexes  map[string]TextIndex   `json:"text_indexes,omitempty"`
+	BloomFilters []string               `json:"bloom_filters,omitempty"`
 	w            io.WriteSeeker
 	path         string
 }
@@ -50,6 +54,115 @@ func (m *Metadata) RemoveStore(name string) {
 	m.KnownStores = newStores
 }
 
+// Index describes a secondary index on the values of one of a [Keeper]'s stores.
+// Exactly one of Field or Extractor is set.
+type Index struct {
+	Name string `json:"name"`
+	// Field is the dot separated path of the JSON field whose value is indexed, e.g. "user.email".
+	Field string `json:"field,omitempty"`
+	// Extractor is the name that the function computing the indexed values was registered under.
+	Extractor string `json:"extractor,omitempty"`
+	// Ordered lays the index's entries out in the order of their values, so that they can be looked up by prefix.
+	Ordered bool `json:"ordered,omitempty"`
+}
+
+// AddIndex records an index on the given store, replacing any existing index with the same name.
+func (m *Metadata) AddIndex(store string, idx Index) {
+	if m.Indexes == nil {
+		m.Indexes = make(map[string][]Index)
+	}
+	for i, existing := range m.Indexes[store] {
+		if existing.Name == idx.Name {
+			m.Indexes[store][i] = idx
+			return
+		}
+	}
+	m.Indexes[store] = append(m.Indexes[store], idx)
+}
+
+// RemoveIndex removes the named index from the given store's recorded indexes.
+func (m *Metadata) RemoveIndex(store, name string) {
+	var newIndexes []Index
+	for _, idx := range m.Indexes[store] {
+		if idx.Name != name {
+			newIndexes = append(newIndexes, idx)
+		}
+	}
+	if len(newIndexes) == 0 {
+		delete(m.Indexes, store)
+		return
+	}
+	m.Indexes[store] = newIndexes
+}
+
+// TextIndex describes the full-text index of one of a [Keeper]'s stores.
+type TextIndex struct {
+	// Field is the dot separated path of the JSON field whose text is indexed. The whole value is indexed if empty.
+	Field string `json:"field,omitempty"`
+}
+
+// AddTextIndex records a full-text index on the given store, replacing any existing one.
+func (m *Metadata) AddTextIndex(store string, idx TextIndex) {
+	if m.TextIndexes == nil {
+		m.TextIndexes = make(map[string]TextIndex)
+	}
+	m.TextIndexes[store] = idx
+}
+
+// RemoveTextIndex removes the given store's full-text index from the metadata.
+func (m *Metadata) RemoveTextIndex(store string) {
+	delete(m.TextIndexes, store)
+}
+
+// BloomFalsePositiveRateKey is the key of [Metadata.DefStoreOpts] holding the false positive rate of
+// bloom filters, see [Metadata.BloomFalsePositiveRate].
+const BloomFalsePositiveRateKey = "bloom_false_positive_rate"
+
+// AddBloomFilter records that the given store has a bloom filter.
+func (m *Metadata) AddBloomFilter(store string) {
+	if !slices.Contains(m.BloomFilters, store) {
+		m.BloomFilters = append(m.BloomFilters, store)
+	}
+}
+
+// RemoveBloomFilter removes the given store from the stores recorded as having a bloom filter.
+func (m *Metadata) RemoveBloomFilter(store string) {
+	m.BloomFilters = slices.DeleteFunc(m.BloomFilters, func(s string) bool { return s == store })
+}
+
+// BloomFalsePositiveRate returns the false positive rate that bloom filters are sized for, as set in
+// [Metadata.DefStoreOpts] under [BloomFalsePositiveRateKey], or zero if it isn't set.
+func (m *Metadata) BloomFalsePositiveRate() float64 {
+	data, err := json.Marshal(m.DefStoreOpts)
+	if err != nil {
+		return 0
+	}
+	var opts map[string]any
+	if err = json.Unmarshal(data, &opts); err != nil {
+		return 0
+	}
+	rate, _ := opts[BloomFalsePositiveRateKey].(float64)
+	return rate
+}
+
+// WithBloomFalsePositiveRate sets the false positive rate that bloom filters are sized for in [Metadata.DefStoreOpts].
+// Default store options that aren't already a map are replaced by their JSON encoding, decoded in to one.
+func (m *Metadata) WithBloomFalsePositiveRate(rate float64) *Metadata {
+	opts, ok := m.DefStoreOpts.(map[string]any)
+	if !ok {
+		opts = make(map[string]any)
+		if data, err := json.Marshal(m.DefStoreOpts); err == nil {
+			_ = json.Unmarshal(data, &opts)
+		}
+		if opts == nil {
+			opts = make(map[string]any)
+		}
+	}
+	opts[BloomFalsePositiveRateKey] = rate
+	m.DefStoreOpts = opts
+	return m
+}
+
 func (m *Metadata) Timestamp() time.Time {
 	return m.LastOpened
 }
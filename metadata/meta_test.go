@@ -112,6 +112,76 @@ func TestMetadata_RemoveStore(t *testing.T) {
 	}
 }
 
+func TestMetadata_AddIndex(t *testing.T) {
+	meta := NewMeta("testType")
+
+	meta.AddIndex("users", Index{Name: "email", Field: "email"})
+	meta.AddIndex("users", Index{Name: "email", Field: "user.email"})
+	meta.AddIndex("users", Index{Name: "name", Extractor: "lower"})
+
+	if len(meta.Indexes["users"]) != 2 {
+		t.Fatalf("expected 2 indexes, got %v", meta.Indexes["users"])
+	}
+	if meta.Indexes["users"][0].Field != "user.email" {
+		t.Errorf("expected index to be replaced, got %v", meta.Indexes["users"])
+	}
+}
+
+func TestMetadata_RemoveIndex(t *testing.T) {
+	meta := NewMeta("testType")
+
+	meta.AddIndex("users", Index{Name: "email", Field: "email"})
+	meta.RemoveIndex("users", "email")
+
+	if _, ok := meta.Indexes["users"]; ok {
+		t.Errorf("expected Indexes to be empty, got %v", meta.Indexes)
+	}
+}
+
+func TestMetadata_TextIndex(t *testing.T) {
+	meta := NewMeta("testType")
+
+	meta.AddTextIndex("posts", TextIndex{Field: "body"})
+	if meta.TextIndexes["posts"].Field != "body" {
+		t.Errorf("expected text index to be recorded, got %v", meta.TextIndexes)
+	}
+	meta.RemoveTextIndex("posts")
+	if len(meta.TextIndexes) != 0 {
+		t.Errorf("expected TextIndexes to be empty, got %v", meta.TextIndexes)
+	}
+}
+
+func TestMetadata_BloomFilter(t *testing.T) {
+	meta := NewMeta("testType")
+
+	meta.AddBloomFilter("hosts")
+	meta.AddBloomFilter("hosts")
+	if len(meta.BloomFilters) != 1 || meta.BloomFilters[0] != "hosts" {
+		t.Errorf("expected bloom filter to be recorded once, got %v", meta.BloomFilters)
+	}
+	meta.RemoveBloomFilter("hosts")
+	if len(meta.BloomFilters) != 0 {
+		t.Errorf("expected BloomFilters to be empty, got %v", meta.BloomFilters)
+	}
+
+	if rate := meta.BloomFalsePositiveRate(); rate != 0 {
+		t.Errorf("expected no rate, got %v", rate)
+	}
+	meta.WithDefaultStoreOpts(struct {
+		Other string `json:"other"`
+	}{"kept"}).WithBloomFalsePositiveRate(0.001)
+	if rate := meta.BloomFalsePositiveRate(); rate != 0.001 {
+		t.Errorf("expected 0.001, got %v", rate)
+	}
+	if opts := meta.DefStoreOpts.(map[string]any); opts["other"] != "kept" {
+		t.Errorf("expected the other options to be kept, got %v", opts)
+	}
+	loaded, err := LoadMeta([]byte(`{"type":"testType","default_store_opts":{"bloom_false_positive_rate":0.05}}`))
+	if err != nil || loaded.BloomFalsePositiveRate() != 0.05 {
+		t.Errorf("expected 0.05 from JSON, got %v (%v)", loaded.BloomFalsePositiveRate(), err)
+	}
+}
+
 func TestMetadata_Ping(t *testing.T) {
 	meta := NewMeta("testType")
 	timeBeforePing := time.Now()
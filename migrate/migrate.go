@@ -33,6 +33,13 @@ func NewDuplicateKeysErr(duplicates map[string][][]byte) *ErrDuplicateKeys {
 	return &ErrDuplicateKeys{Duplicates: duplicates}
 }
 
+// DefaultBatchKeys and DefaultBatchBytes bound the batches that destination stores implementing
+// [database.Batcher] are written in, see [Migrator.WithBatchSize].
+const (
+	DefaultBatchKeys  = 10000
+	DefaultBatchBytes = 16 << 20
+)
+
 type Migrator struct {
 	From database.Keeper
 	To   database.Keeper
@@ -42,6 +49,9 @@ type Migrator struct {
 	clobber      bool
 	skipExisting bool
 
+	batchKeys  int
+	batchBytes int
+
 	mu sync.Mutex
 }
 
@@ -67,6 +77,8 @@ func NewMigrator(from, to database.Keeper) (*Migrator, error) {
 		To:           to,
 		clobber:      false,
 		skipExisting: false,
+		batchKeys:    DefaultBatchKeys,
+		batchBytes:   DefaultBatchBytes,
 	}, nil
 }
 
@@ -86,7 +98,28 @@ func (m *Migrator) WithSkipExisting() *Migrator {
 	return m
 }
 
+// WithBatchSize sets the most keys, and the most bytes of keys and values, written to a destination store
+// implementing [database.Batcher] in a single batch. Limits of zero or less are left at their defaults.
+func (m *Migrator) WithBatchSize(keys int, bytes int) *Migrator {
+	m.mu.Lock()
+	if keys > 0 {
+		m.batchKeys = keys
+	}
+	if bytes > 0 {
+		m.batchBytes = bytes
+	}
+	m.mu.Unlock()
+	return m
+}
+
+// CheckDupes records the keys that exist in both the source and destination Keepers,
+// returning an [ErrDuplicateKeys] unless clobbering or skipping existing keys is enabled.
 func (m *Migrator) CheckDupes() error {
+	return m.CheckDupesContext(context.Background())
+}
+
+// CheckDupesContext is like CheckDupes, but stops once ctx is done and returns ctx's error.
+func (m *Migrator) CheckDupesContext(ctx context.Context) error {
 	fromStores := m.From.AllStores()
 	toStores := m.To.AllStores()
 
@@ -113,6 +146,9 @@ func (m *Migrator) CheckDupes() error {
 			defer wg.Done()
 			keys := existingStore.Keys()
 			for _, key := range keys {
+				if ctx.Err() != nil {
+					return
+				}
 				if store.Has(key) {
 					m.mu.Lock()
 					if _, exists := m.duplicateKeys[storeName]; !exists {
@@ -127,6 +163,10 @@ func (m *Migrator) CheckDupes() error {
 
 	wg.Wait()
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	if len(m.duplicateKeys) == 0 || m.skipExisting || m.clobber {
 		return nil
 	}
@@ -138,14 +178,24 @@ func (m *Migrator) CheckDupes() error {
 	return NewDuplicateKeysErr(mslice)
 }
 
+// Migrate copies every store in the source Keeper to the destination Keeper.
+// Destination stores that implement [database.Batcher] are written in batches bounded by [Migrator.WithBatchSize],
+// so that a store never has to be held in memory as a whole.
 func (m *Migrator) Migrate() error {
+	return m.MigrateContext(context.Background())
+}
+
+// MigrateContext is like Migrate, but stops once ctx is done and returns ctx's error.
+// The batch being filled at that point is discarded, so destination stores that implement [database.Batcher]
+// are left holding only whole batches. Other destination stores may be left holding any part of the source store.
+func (m *Migrator) MigrateContext(ctx context.Context) error {
 	fromStores := m.From.AllStores()
 
 	if len(fromStores) == 0 {
 		return ErrNoStores
 	}
 
-	if err := m.CheckDupes(); err != nil {
+	if err := m.CheckDupesContext(ctx); err != nil {
 		return err
 	}
 
@@ -153,7 +203,7 @@ func (m *Migrator) Migrate() error {
 	defer m.mu.Unlock()
 
 	errCh := make(chan error, len(fromStores))
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	wg := &sync.WaitGroup{}
@@ -164,6 +214,28 @@ func (m *Migrator) Migrate() error {
 		wg.Add(1)
 		go func(storeName string, store database.Filer) {
 			defer wg.Done()
+			dst := m.To.WithNew(storeName)
+			// when the destination supports it, each store is migrated in batches so that a failure part way
+			// through only ever leaves whole batches behind.
+			var (
+				batch     database.Batch
+				batchSize int
+			)
+			if batcher, ok := dst.(database.Batcher); ok {
+				batch = batcher.NewBatch()
+			}
+			put := func(key, value []byte) error {
+				if batch == nil {
+					return dst.Put(key, value)
+				}
+				batch.Put(key, value)
+				batchSize += len(key) + len(value)
+				if batch.Len() < m.batchKeys && batchSize < m.batchBytes {
+					return nil
+				}
+				batchSize = 0
+				return batch.Commit()
+			}
 			keys := store.Keys()
 			for _, key := range keys {
 				select {
@@ -184,15 +256,24 @@ func (m *Migrator) Migrate() error {
 						errCh <- NewDuplicateKeysErr(mapMaptoMapSlice(m.duplicateKeys))
 						return
 					}
-					if err = m.To.With(storeName).Put(key, srcVal); err != nil {
+					if err = put(key, srcVal); err != nil {
 						errCh <- err
 						return
 					}
 					continue
 				}
-				if err = m.To.WithNew(storeName).Put(key, srcVal); err != nil {
+				if err = put(key, srcVal); err != nil {
+					errCh <- err
+					return
+				}
+			}
+			if batch != nil && batch.Len() > 0 {
+				if ctx.Err() != nil {
 					return
 				}
+				if err := batch.Commit(); err != nil {
+					errCh <- err
+				}
 			}
 		}(srcStoreName, srcStore)
 	}
@@ -210,6 +291,16 @@ func (m *Migrator) Migrate() error {
 		return err
 	}
 
+	// a store can fail or be cancelled just as the last one finishes.
+	select {
+	case err := <-errCh:
+		return err
+	default:
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	fStores := m.From.AllStores()
 	tStores := m.To.AllStores()
 
@@ -1,9 +1,14 @@
 package migrate
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"testing"
 
+	tcpdb "github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/journal"
 	"github.com/tcp-direct/database/test"
 )
 
@@ -100,3 +105,90 @@ func TestMigrator_Success(t *testing.T) {
 		t.Error("expected key1 to be  to destination keeper")
 	}
 }
+
+func TestMigrator_MigrateContext_Cancelled(t *testing.T) {
+	from := database.NewMockKeeper("yeeeties")
+	to := database.NewMockKeeper("yooties")
+
+	if err := from.WithNew("store1").Put([]byte("key1"), []byte("value1")); err != nil {
+		t.Fatalf("error putting key1: %v", err)
+	}
+
+	migrator, err := NewMigrator(from, to)
+	if err != nil {
+		t.Fatalf("error creating migrator: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err = migrator.MigrateContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	if st := to.With("store1"); st != nil && st.Has([]byte("key1")) {
+		t.Error("expected nothing to be migrated after cancellation")
+	}
+}
+
+// batchingKeeper hands out stores that implement Batcher, recording the size of every batch committed to them.
+type batchingKeeper struct {
+	tcpdb.Keeper
+	commits *[]int
+}
+
+type batchingFiler struct {
+	tcpdb.Filer
+	commits *[]int
+}
+
+func (k batchingKeeper) WithNew(name string, options ...any) tcpdb.Filer {
+	return batchingFiler{Filer: k.Keeper.WithNew(name, options...), commits: k.commits}
+}
+
+func (f batchingFiler) NewBatch() tcpdb.Batch {
+	return journal.NewBatch(func(ops []journal.Op) error {
+		*f.commits = append(*f.commits, len(ops))
+		for _, op := range ops {
+			if err := f.Put(op.Key, op.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func TestMigrator_BatchSize(t *testing.T) {
+	from := database.NewMockKeeper("yeeeties")
+	to := database.NewMockKeeper("yooties")
+
+	for i := 0; i < 25; i++ {
+		if err := from.WithNew("store1").Put([]byte(fmt.Sprintf("key%02d", i)), []byte("value")); err != nil {
+			t.Fatalf("error putting key%02d: %v", i, err)
+		}
+	}
+
+	var commits []int
+	migrator, err := NewMigrator(from, batchingKeeper{Keeper: to, commits: &commits})
+	if err != nil {
+		t.Fatalf("error creating migrator: %v", err)
+	}
+	if err = migrator.WithBatchSize(10, 0).Migrate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := []int{10, 10, 5}; !slices.Equal(commits, want) {
+		t.Errorf("expected batches of %v, got %v", want, commits)
+	}
+	if n := to.With("store1").Len(); n != 25 {
+		t.Errorf("expected 25 keys to be migrated, got %d", n)
+	}
+
+	// each key and value takes 10 bytes.
+	commits = nil
+	if err = migrator.WithBatchSize(100, 45).WithClobber().Migrate(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if want := []int{5, 5, 5, 5, 5}; !slices.Equal(commits, want) {
+		t.Errorf("expected batches of %v, got %v", want, commits)
+	}
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// ErrBadCursor is returned by [DecodeCursor] and the methods of [Pager] when a cursor wasn't made by them.
+var ErrBadCursor = errors.New("malformed cursor")
+
+// cursorVersion leads every encoded cursor, leaving room to change the format later.
+const cursorVersion byte = 1
+
+// Pager is a Searcher whose scans can be read a page at a time, resuming from an opaque cursor.
+// A cursor records the last key of the page it came with, so it stays valid when keys are added
+// or removed between pages: the next page simply continues with the first matching key after it.
+type Pager interface {
+	Searcher
+	// PrefixScanPage should return up to limit key/value pairs whose keys have the given prefix, in key order,
+	// starting after cursor, or from the beginning if cursor is empty. The returned cursor should be empty
+	// once there are no more pairs. A limit of zero or less means no limit.
+	PrefixScanPage(prefix string, cursor string, limit int) ([]kv.KeyValue, string, error)
+	// SearchPage should behave like PrefixScanPage, for the pairs whose values contain query.
+	SearchPage(query string, cursor string, limit int) ([]kv.KeyValue, string, error)
+}
+
+// EncodeCursor returns the cursor that resumes a paginated scan after key.
+func EncodeCursor(key []byte) string {
+	return base64.RawURLEncoding.EncodeToString(append([]byte{cursorVersion}, key...))
+}
+
+// DecodeCursor returns the key that a cursor made by [EncodeCursor] resumes after.
+// An empty cursor decodes to a nil key.
+func DecodeCursor(cursor string) ([]byte, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) == 0 || raw[0] != cursorVersion {
+		return nil, ErrBadCursor
+	}
+	return raw[1:], nil
+}
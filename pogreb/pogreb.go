@@ -12,30 +12,44 @@ import (
 	"github.com/akrylysov/pogreb"
 
 	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/expiry"
+	"github.com/tcp-direct/database/journal"
 	"github.com/tcp-direct/database/kv"
 	"github.com/tcp-direct/database/metadata"
 	"github.com/tcp-direct/database/models"
 )
 
 func (pstore *Store) Len() int {
-	return int(pstore.DB.Count())
+	pstore.batches.RLock()
+	defer pstore.batches.RUnlock()
+	n := int(pstore.DB.Count())
+	for _, key := range pstore.ttl.Expiring() {
+		if ok, _ := pstore.DB.Has(key); ok {
+			n--
+		}
+	}
+	return n
 }
 
 func (pstore *Store) Keys() [][]byte {
+	pstore.batches.RLock()
+	defer pstore.batches.RUnlock()
 	iter := pstore.DB.Items()
 	ks := make([][]byte, 0, pstore.DB.Count())
 	for k, _, _ := iter.Next(); k != nil; k, _, _ = iter.Next() {
 		ks = append(ks, k)
 	}
-	return ks
+	return pstore.ttl.Live(ks)
 }
 
 func (pstore *Store) Has(key []byte) bool {
-	ok, err := pstore.DB.Has(key)
-	if err != nil {
-		_, _ = os.Stderr.WriteString("error checking pogreb store for key: " + err.Error())
+	pstore.batches.RLock()
+	defer pstore.batches.RUnlock()
+	if pstore.expire(key) {
+		return false
 	}
-	return ok
+	ok, err := pstore.DB.Has(key)
+	return ok && err == nil
 }
 
 type Metrics struct {
@@ -51,6 +65,11 @@ type Store struct {
 	opts    *WrappedOptions
 	closed  *atomic.Bool
 	metrics *pogreb.Metrics
+	ttl     *expiry.Tracker
+	journal *journal.Journal
+	// batches is held for writing while a batch is applied, and for reading by reads of the store,
+	// so that reads see either all of a batch's writes or none of them.
+	batches *sync.RWMutex
 }
 
 var nilBackend = &pogreb.DB{}
@@ -71,6 +90,11 @@ func (pstore *Store) Get(key []byte) ([]byte, error) {
 	if pstore.closed.Load() {
 		return nil, fs.ErrClosed
 	}
+	pstore.batches.RLock()
+	defer pstore.batches.RUnlock()
+	if pstore.expire(key) {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
 	ret, err := pstore.DB.Get(key)
 	if err = kv.RegularizeKVError(key, ret, err); err != nil {
 		return nil, err
@@ -85,7 +109,11 @@ func (pstore *Store) Close() error {
 	}
 	pstore.closed.Store(true)
 	pstore.metrics = pstore.DB.Metrics()
-	return pstore.DB.Close()
+	ttlErr := pstore.ttl.Close()
+	if err := pstore.DB.Close(); err != nil {
+		return err
+	}
+	return ttlErr
 }
 
 // DB is a mapper of a Filer and Searcher implementation using pogreb.
@@ -96,6 +124,7 @@ type DB struct {
 	meta  *metadata.Metadata
 
 	initialized *atomic.Bool
+	sweeper     *expiry.Sweeper
 }
 
 type CombinedMetrics struct {
@@ -194,6 +223,7 @@ func OpenDB(path string) *DB {
 
 		initialized: ainit,
 	}
+	db.sweeper = expiry.NewSweeper(expiry.DefaultSweepInterval, db.sweep)
 	return db
 }
 func (db *DB) _init() error {
@@ -285,9 +315,21 @@ func (db *DB) initStore(storeName string, pogrebOpts *WrappedOptions) error {
 	if e != nil {
 		return e
 	}
+	// roll back any batch that was interrupted the last time the store was open.
+	jrnl := journal.New(filepath.Join(path, storeName, journal.File), rawStore{c})
+	if _, e = jrnl.Recover(); e != nil {
+		_ = c.Close()
+		return e
+	}
+	tracker, e := expiry.Open(filepath.Join(path, storeName, expiry.LogFile))
+	if e != nil {
+		_ = c.Close()
+		return e
+	}
 	aclosed := &atomic.Bool{}
 	aclosed.Store(false)
-	db.store[storeName] = &Store{DB: c, closed: aclosed, opts: pogrebOpts}
+	db.store[storeName] = &Store{DB: c, closed: aclosed, opts: pogrebOpts, ttl: tracker, journal: jrnl, batches: &sync.RWMutex{}}
+	db.sweeper.Start()
 	return nil
 }
 
@@ -397,7 +439,7 @@ func (db *DB) Sync(storeName string) error {
 	if _, ok := db.store[storeName]; !ok {
 		return ErrBogusStore
 	}
-	return db.store[storeName].Backend().(*pogreb.DB).Sync()
+	return db.store[storeName].Sync()
 }
 
 // withAllAction
@@ -464,6 +506,7 @@ func (db *DB) syncAndCloseAll() error {
 
 // SyncAndCloseAll implements the method from Keeper to sync and close all pogreb stores.
 func (db *DB) SyncAndCloseAll() error {
+	db.sweeper.Stop()
 	db.mu.Lock()
 	err := db.syncAndCloseAll()
 	db.mu.Unlock()
@@ -476,6 +519,7 @@ func (db *DB) closeAll() error {
 
 // CloseAll closes all pogreb datastores.
 func (db *DB) CloseAll() error {
+	db.sweeper.Stop()
 	db.mu.Lock()
 	err := db.closeAll()
 	db.mu.Unlock()
@@ -566,11 +610,6 @@ func (db *DB) discover(force ...bool) ([]string, error) {
 			continue
 		}
 		stores = append(stores, name)
-		aclosed := &atomic.Bool{}
-		aclosed.Store(false)
-		defOptMu.RLock()
-		db.store[name] = &Store{DB: db.store[name].DB, closed: aclosed, opts: defOpt}
-		defOptMu.RUnlock()
 	}
 
 	for _, e := range errs {
@@ -1,6 +1,7 @@
 package pogreb
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -12,7 +13,17 @@ import (
 	"github.com/tcp-direct/database/models"
 )
 
-func (db *DB) BackupAll(archivePath string) (models.Backup, error) {
+// BackupAll syncs and closes all pogreb stores, then writes them and the keeper's metadata to an archive.
+func (db *DB) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	return db.BackupAllContext(context.Background(), archivePath, opts...)
+}
+
+// BackupAllContext is like BackupAll, but writes the archive with opts and abandons the backup once ctx is done.
+// Unless ctx is already done when it is called, the stores are left closed whether or not the backup completes.
+func (db *DB) BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	// calling write lock should stop any other operations on the stores while we backup. shouldn't need to close.
 	db.mu.Lock()
 	defer db.mu.Unlock()
@@ -47,7 +58,11 @@ func (db *DB) BackupAll(archivePath string) (models.Backup, error) {
 		}
 	}
 
-	bu, err := backup.NewTarGzBackup(db.path, archivePath, storeNames)
+	b, err := backup.NewBackuper(backup.FormatFromPath(archivePath), opts...)
+	if err != nil {
+		return nil, err
+	}
+	bu, err := b.Backup(ctx, db.path, archivePath, storeNames)
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +74,7 @@ func (db *DB) BackupAll(archivePath string) (models.Backup, error) {
 	return bu, err
 }
 
-func (db *DB) RestoreAll(archivePath string) error {
+func (db *DB) RestoreAll(archivePath string, opts ...backup.Option) error {
 	var preBu models.Backup
 
 	if err := db.SyncAndCloseAll(); err != nil && !errors.Is(err, ErrNoStores) {
@@ -89,7 +104,8 @@ func (db *DB) RestoreAll(archivePath string) error {
 
 	db.initialized.Store(false)
 
-	if err := backup.RestoreTarGzBackup(archivePath, db.path); err != nil {
+	opts = append([]backup.Option{backup.WithKnown(backup.KnownBackups(db.meta)...)}, opts...)
+	if err := backup.RestoreBackup(archivePath, db.path, opts...); err != nil {
 		return err
 	}
 
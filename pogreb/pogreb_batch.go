@@ -0,0 +1,49 @@
+package pogreb
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/akrylysov/pogreb"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+)
+
+// rawStore gives the batch journal direct access to the pogreb instance, bypassing the expiry tracker.
+type rawStore struct {
+	*pogreb.DB
+}
+
+func (r rawStore) Get(key []byte) ([]byte, error) {
+	ret, err := r.DB.Get(key)
+	if err = kv.RegularizeKVError(key, ret, err); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// NewBatch returns an empty batch of writes that will be applied to the store together.
+// Pogreb has no native batches, so a journal in the store's directory is used to roll back
+// any batch that is interrupted part way through, and reads of the store wait while a batch is applied.
+func (pstore *Store) NewBatch() database.Batch {
+	return journal.NewBatch(pstore.commit)
+}
+
+func (pstore *Store) commit(ops []journal.Op) error {
+	if pstore.closed.Load() {
+		return fs.ErrClosed
+	}
+	keys := make([][]byte, 0, len(ops))
+	for _, op := range ops {
+		keys = append(keys, op.Key)
+	}
+	// reads wait for the whole batch to be applied, and holding the expiry tracker's lock keeps other writes
+	// and sweeps from interleaving with it.
+	pstore.batches.Lock()
+	defer pstore.batches.Unlock()
+	return pstore.ttl.SetAll(keys, time.Time{}, func() error {
+		return pstore.journal.Apply(ops)
+	})
+}
@@ -0,0 +1,20 @@
+package pogreb
+
+import "context"
+
+// GetContext is like Get, but returns ctx's error instead if it is already done.
+// pogreb has no way of interrupting a single read or write, so ctx is only checked before it starts.
+func (pstore *Store) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return pstore.Get(key)
+}
+
+// PutContext is like Put, but returns ctx's error instead if it is already done.
+func (pstore *Store) PutContext(ctx context.Context, key []byte, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return pstore.Put(key, value)
+}
@@ -0,0 +1,70 @@
+package pogreb
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+
+	"github.com/akrylysov/pogreb"
+
+	"github.com/tcp-direct/database"
+)
+
+var _ database.Counter = (*Store)(nil)
+
+// each calls fn with every live key/value pair, walking pogreb's index in hash order.
+func (pstore *Store) each(fn func(key, value []byte)) error {
+	if pstore.closed.Load() {
+		return fs.ErrClosed
+	}
+	pstore.batches.RLock()
+	defer pstore.batches.RUnlock()
+	iter := pstore.DB.Items()
+	for {
+		k, v, err := iter.Next()
+		if errors.Is(err, pogreb.ErrIterationDone) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !pstore.ttl.Expired(k) {
+			fn(k, v)
+		}
+	}
+}
+
+// CountPrefix returns the number of keys with the given prefix. Pogreb's index is ordered by hash, so every
+// key is visited, but no results are kept or streamed. Pogreb can't iterate over its keys alone either, so the
+// value of every key is read along with it, making this as costly as CountMatches.
+func (pstore *Store) CountPrefix(prefix string) (int, error) {
+	n := 0
+	err := pstore.each(func(key, _ []byte) {
+		if bytes.HasPrefix(key, []byte(prefix)) {
+			n++
+		}
+	})
+	return n, err
+}
+
+// CountMatches returns the number of values that contain query, without keeping or streaming any of them.
+func (pstore *Store) CountMatches(query string) (int, error) {
+	n := 0
+	err := pstore.each(func(_, value []byte) {
+		if bytes.Contains(value, []byte(query)) {
+			n++
+		}
+	})
+	return n, err
+}
+
+// SizeStats summarizes the sizes of the store's keys and values in a single walk of pogreb's index.
+// Pogreb doesn't expose the sizes recorded in its index, so every value is read along with its key: this costs
+// as much as reading the whole store, and is too costly to poll frequently, such as to keep a dashboard current.
+func (pstore *Store) SizeStats() (database.SizeStats, error) {
+	var stats database.SizeStats
+	err := pstore.each(func(key, value []byte) {
+		stats.Add(len(key), len(value))
+	})
+	return stats, err
+}
@@ -0,0 +1,57 @@
+package pogreb
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStore_Count(t *testing.T) {
+	db := setupTest("count", t)
+	st := db.With("count").(*Store)
+	for key, value := range map[string]string{
+		"user/1":  "alice",
+		"user/2":  "bob",
+		"user/10": strings.Repeat("x", 300),
+		"order/1": "a",
+	} {
+		if err := st.Put([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for prefix, want := range map[string]int{"user/": 3, "user/1": 2, "order/": 1, "": 4, "nope": 0} {
+		if n, err := st.CountPrefix(prefix); err != nil || n != want {
+			t.Errorf("CountPrefix(%q): expected %d, got %d (%v)", prefix, want, n, err)
+		}
+	}
+	for query, want := range map[string]int{"b": 1, "xx": 1, "a": 2, "zzz": 0} {
+		if n, err := st.CountMatches(query); err != nil || n != want {
+			t.Errorf("CountMatches(%q): expected %d, got %d (%v)", query, want, n, err)
+		}
+	}
+
+	stats, err := st.SizeStats()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stats.Keys != 4 || stats.KeyBytes != 6+6+7+7 || stats.ValueBytes != 5+3+300+1 {
+		t.Errorf("expected sizes of 4 keys, got %+v", stats)
+	}
+	// 1 byte, 3 and 5 bytes, then 300 bytes in the 256-511 bucket.
+	if want := []int{0, 1, 1, 1, 0, 0, 0, 0, 0, 1}; !slices.Equal(stats.Histogram, want) {
+		t.Errorf("expected histogram %v, got %v", want, stats.Histogram)
+	}
+
+	if err = db.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.CountPrefix(""); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+	if _, err = st.SizeStats(); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package pogreb
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/akrylysov/pogreb"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+)
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Pogreb stores keys in hash order, so the keys within the range are collected and sorted up front.
+// Values are only read as the iterator reaches them.
+func (pstore *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if pstore.closed.Load() {
+		return iterator.Error(fs.ErrClosed)
+	}
+	pstore.batches.RLock()
+	defer pstore.batches.RUnlock()
+	iter := pstore.DB.Items()
+	keys := make([][]byte, 0)
+	for {
+		k, _, err := iter.Next()
+		if errors.Is(err, pogreb.ErrIterationDone) {
+			break
+		}
+		if err != nil {
+			return iterator.Error(err)
+		}
+		if iterator.InRange(k, start, end) {
+			keys = append(keys, k)
+		}
+	}
+	return iterator.Sorted(pstore.ttl.Live(keys), start, end, opts, pstore.Get)
+}
@@ -0,0 +1,51 @@
+package pogreb
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+// SearchRegex will stream every key/value pair whose value matches re.
+func (pstore *Store) SearchRegex(re *regexp.Regexp) (<-chan kv.KeyValue, chan error) {
+	return pstore.MatchRegex(re, database.MatchValues)
+}
+
+// KeyRegex will stream every key/value pair whose key matches re.
+// Values are only read for the keys that match.
+func (pstore *Store) KeyRegex(re *regexp.Regexp) (<-chan kv.KeyValue, chan error) {
+	return pstore.MatchRegex(re, database.MatchKeys)
+}
+
+// KeyGlob will stream every key/value pair whose whole key matches the glob pattern, see [database.CompileGlob].
+// A malformed pattern is sent to the error channel.
+func (pstore *Store) KeyGlob(pattern string) (<-chan kv.KeyValue, chan error) {
+	re, err := database.CompileGlob(pattern)
+	if err != nil {
+		errChan := make(chan error, 1)
+		resChan := make(chan kv.KeyValue)
+		errChan <- err
+		close(errChan)
+		close(resChan)
+		return resChan, errChan
+	}
+	return pstore.MatchRegex(re, database.MatchKeys)
+}
+
+// MatchRegex will stream every key/value pair where the key, the value or either of them, as chosen by in, matches re.
+func (pstore *Store) MatchRegex(re *regexp.Regexp, in database.Match) (<-chan kv.KeyValue, chan error) {
+	return pstore.MatchRegexContext(context.Background(), re, in)
+}
+
+// MatchRegexContext is like MatchRegex, but stops once ctx is done.
+func (pstore *Store) MatchRegexContext(ctx context.Context, re *regexp.Regexp, in database.Match) (<-chan kv.KeyValue, chan error) {
+	wantKey := anyKey
+	if in == database.MatchKeys {
+		wantKey = re.Match
+	}
+	return pstore.scan(ctx, wantKey, func(key, value []byte) bool {
+		return in.Matches(re, key, value)
+	})
+}
@@ -0,0 +1,82 @@
+package pogreb
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+func collectKeys(t *testing.T, resChan <-chan kv.KeyValue, errChan chan error) []string {
+	t.Helper()
+	var keys []string
+	for keyVal := range resChan {
+		keys = append(keys, keyVal.Key.String())
+	}
+	if err := <-errChan; err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func TestStore_Regex(t *testing.T) {
+	db := setupTest("regex", t)
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	st := db.With("regex").(*Store)
+	data := map[string]string{
+		"user/1":    "alice@example.com",
+		"user/2":    "bob@example.org",
+		"user/10":   "not an email",
+		"order/1":   "user/1 bought a thing",
+		"session/x": "expired",
+	}
+	for k, v := range data {
+		if err := st.Put([]byte(k), []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var _ database.RegexSearcher = st
+	email := regexp.MustCompile(`@example\.(com|org)$`)
+	user := regexp.MustCompile(`^user/\d$`)
+	for _, tc := range []struct {
+		name string
+		run  func() (<-chan kv.KeyValue, chan error)
+		want []string
+	}{
+		{"SearchRegex", func() (<-chan kv.KeyValue, chan error) {
+			return st.SearchRegex(email)
+		}, []string{"user/1", "user/2"}},
+		{"KeyRegex", func() (<-chan kv.KeyValue, chan error) {
+			return st.KeyRegex(user)
+		}, []string{"user/1", "user/2"}},
+		{"KeyGlob", func() (<-chan kv.KeyValue, chan error) {
+			return st.KeyGlob("user/*")
+		}, []string{"user/1", "user/10", "user/2"}},
+		{"KeyGlobClass", func() (<-chan kv.KeyValue, chan error) {
+			return st.KeyGlob("[!s]*/1")
+		}, []string{"order/1", "user/1"}},
+		{"MatchEither", func() (<-chan kv.KeyValue, chan error) {
+			return st.MatchRegex(regexp.MustCompile(`user/1\b`), database.MatchEither)
+		}, []string{"order/1", "user/1"}},
+	} {
+		resChan, errChan := tc.run()
+		if got := collectKeys(t, resChan, errChan); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+
+	resChan, errChan := st.KeyGlob("user/[")
+	for range resChan {
+		t.Error("expected no results from malformed glob")
+	}
+	if err := <-errChan; !errors.Is(err, database.ErrBadGlob) {
+		t.Errorf("expected ErrBadGlob, got %v", err)
+	}
+}
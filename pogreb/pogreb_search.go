@@ -2,6 +2,7 @@ package pogreb
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"strings"
 
@@ -13,7 +14,25 @@ import (
 // Search will search for a given string within all values inside of a Store.
 // Note, type casting will be necessary. (e.g: []byte or string)
 func (pstore *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
-	var errChan = make(chan error, 5)
+	return pstore.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+// At most one error is sent to the error channel, so it never has to be read for the search to finish.
+func (pstore *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	return pstore.scan(ctx, anyKey, func(_, value []byte) bool {
+		return strings.Contains(string(value), query)
+	})
+}
+
+func anyKey([]byte) bool { return true }
+
+func anyValue(_, _ []byte) bool { return true }
+
+// scan reads the value of every key accepted by wantKey and sends the pairs accepted by match to the returned channel,
+// stopping once ctx is done. Keys that are deleted or expire while the scan is running are skipped.
+func (pstore *Store) scan(ctx context.Context, wantKey func(key []byte) bool, match func(key, value []byte) bool) (<-chan kv.KeyValue, chan error) {
+	var errChan = make(chan error, 1)
 	var resChan = make(chan kv.KeyValue, 5)
 	go func() {
 		defer func() {
@@ -21,17 +40,30 @@ func (pstore *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
 			close(errChan)
 		}()
 		for _, key := range pstore.Keys() {
+			if ctx.Err() != nil {
+				errChan <- ctx.Err()
+				return
+			}
 			if len(key) == 0 {
 				continue
 			}
+			if !wantKey(key) {
+				continue
+			}
 			raw, err := pstore.Get(key)
+			if kv.IsNonExistentKey(err) {
+				continue
+			}
 			if err != nil {
 				errChan <- err
+				return
+			}
+			if raw == nil || !match(key, raw) {
 				continue
 			}
-			if raw != nil && strings.Contains(string(raw), query) {
-				keyVal := kv.NewKeyValue(kv.NewKey(key), kv.NewValue(raw))
-				resChan <- keyVal
+			if !kv.Send(ctx, resChan, kv.NewKeyValue(kv.NewKey(key), kv.NewValue(raw))) {
+				errChan <- ctx.Err()
+				return
 			}
 		}
 	}()
@@ -56,28 +88,45 @@ func (pstore *Store) ValueExists(value []byte) (key []byte, ok bool) {
 
 // PrefixScan will scan a Store for all keys that have a matching prefix of the given string
 // and return a map of keys and values. (map[Key]Value)
-// error channel will block, so be sure to read from it.
 func (pstore *Store) PrefixScan(prefixs string) (<-chan kv.KeyValue, chan error) {
+	return pstore.PrefixScanContext(context.Background(), prefixs)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+// At most one error is sent to the error channel, so it never has to be read for the scan to finish.
+func (pstore *Store) PrefixScanContext(ctx context.Context, prefixs string) (<-chan kv.KeyValue, chan error) {
 	prefix := []byte(prefixs)
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 	resChan := make(chan kv.KeyValue, 5)
 	go func() {
-		var err error
-		defer func(e error) {
+		defer func() {
 			close(resChan)
 			close(errChan)
-		}(err)
+		}()
 		iter := pstore.DB.Items()
-		for k, v, iterErr := iter.Next(); k != nil; k, v, iterErr = iter.Next() {
+		for {
+			if ctx.Err() != nil {
+				errChan <- ctx.Err()
+				return
+			}
+			// the lock isn't held while sending, which would keep batches from being committed until the
+			// results are read.
+			pstore.batches.RLock()
+			k, v, iterErr := iter.Next()
+			pstore.batches.RUnlock()
 			if errors.Is(iterErr, pogreb.ErrIterationDone) {
-				break
+				return
 			}
 			if iterErr != nil {
 				errChan <- iterErr
+				return
+			}
+			if !bytes.HasPrefix(k, prefix) || pstore.ttl.Expired(k) {
 				continue
 			}
-			if bytes.HasPrefix(k, prefix) {
-				resChan <- kv.NewKeyValue(kv.NewKey(k), kv.NewValue(v))
+			if !kv.Send(ctx, resChan, kv.NewKeyValue(kv.NewKey(k), kv.NewValue(v))) {
+				errChan <- ctx.Err()
+				return
 			}
 		}
 	}()
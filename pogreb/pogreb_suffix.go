@@ -0,0 +1,39 @@
+package pogreb
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// SuffixScan will stream every key/value pair whose key ends with suffix.
+// Values are only read for the keys that match.
+func (pstore *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return pstore.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (pstore *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(suffix)
+	return pstore.scan(ctx, func(key []byte) bool {
+		return bytes.HasSuffix(key, needle)
+	}, anyValue)
+}
+
+// KeyContains will stream every key/value pair whose key contains substr.
+// Values are only read for the keys that match.
+func (pstore *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return pstore.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (pstore *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	needle := []byte(substr)
+	return pstore.scan(ctx, func(key []byte) bool {
+		return bytes.Contains(key, needle)
+	}, anyValue)
+}
@@ -0,0 +1,99 @@
+package pogreb
+
+import (
+	"io/fs"
+	"time"
+
+	"github.com/tcp-direct/database/expiry"
+	"github.com/tcp-direct/database/kv"
+)
+
+// expire lazily removes the key if it has expired, returning true if it had.
+func (pstore *Store) expire(key []byte) bool {
+	expired, _ := pstore.ttl.Expire(key, pstore.DB.Delete)
+	return expired
+}
+
+// Put is a wrapper around pogreb's Put function that clears any expiration time for the key.
+func (pstore *Store) Put(key []byte, value []byte) error {
+	if pstore.closed.Load() {
+		return fs.ErrClosed
+	}
+	return pstore.ttl.Set(key, time.Time{}, func() error {
+		return pstore.DB.Put(key, value)
+	})
+}
+
+// PutWithTTL inserts the value and expires the key once ttl has elapsed.
+func (pstore *Store) PutWithTTL(key []byte, value []byte, ttl time.Duration) error {
+	if pstore.closed.Load() {
+		return fs.ErrClosed
+	}
+	at, err := expiry.Deadline(ttl)
+	if err != nil {
+		return err
+	}
+	return pstore.ttl.Set(key, at, func() error {
+		return pstore.DB.Put(key, value)
+	})
+}
+
+// TTL returns the time remaining before the given key expires, or zero if it never expires.
+func (pstore *Store) TTL(key []byte) (time.Duration, error) {
+	if pstore.closed.Load() {
+		return 0, fs.ErrClosed
+	}
+	if !pstore.Has(key) {
+		return 0, kv.RegularizeKVError(key, nil, nil)
+	}
+	ttl, ok := pstore.ttl.TTL(key)
+	if ok && ttl <= 0 {
+		return 0, kv.RegularizeKVError(key, nil, nil)
+	}
+	return ttl, nil
+}
+
+// Delete is a wrapper around pogreb's Delete function that clears any expiration time for the key.
+func (pstore *Store) Delete(key []byte) error {
+	if pstore.closed.Load() {
+		return fs.ErrClosed
+	}
+	return pstore.ttl.Set(key, time.Time{}, func() error {
+		return pstore.DB.Delete(key)
+	})
+}
+
+// Sync is a wrapper around pogreb's Sync function that also syncs the store's expiry log.
+func (pstore *Store) Sync() error {
+	if err := pstore.ttl.Sync(); err != nil {
+		return err
+	}
+	return pstore.DB.Sync()
+}
+
+// sweep removes all expired keys from the store.
+func (pstore *Store) sweep() (int, error) {
+	if pstore.DB == nil || pstore.ttl == nil || pstore.closed.Load() {
+		return 0, fs.ErrClosed
+	}
+	return pstore.ttl.Sweep(pstore.DB.Delete)
+}
+
+// sweep removes expired keys from every open store, it is run in the background by the keeper's sweeper.
+func (db *DB) sweep() {
+	db.mu.RLock()
+	stores := make([]*Store, 0, len(db.store))
+	for _, st := range db.store {
+		stores = append(stores, st)
+	}
+	db.mu.RUnlock()
+	for _, st := range stores {
+		_, _ = st.sweep()
+	}
+}
+
+// SetSweepInterval changes how often expired keys are removed from all stores in the background.
+// Expired keys are hidden from reads regardless of when they are removed.
+func (db *DB) SetSweepInterval(interval time.Duration) {
+	db.sweeper.SetInterval(interval)
+}
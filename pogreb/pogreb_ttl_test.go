@@ -0,0 +1,127 @@
+package pogreb
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/expiry"
+	"github.com/tcp-direct/database/kv"
+)
+
+func TestStore_PutWithTTL(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	st, ok := db.WithNew("test").(database.ExpiringFiler)
+	if !ok {
+		t.Fatal("expected store to implement ExpiringFiler")
+	}
+	if err := st.PutWithTTL([]byte("yeet"), []byte("yeeterson"), 0); !errors.Is(err, expiry.ErrBadTTL) {
+		t.Errorf("expected ErrBadTTL, got %v", err)
+	}
+	if err := st.PutWithTTL([]byte("short"), []byte("lived"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.PutWithTTL([]byte("shortlong"), []byte("lived"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.PutWithTTL([]byte("shortened"), []byte("lived"), 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put([]byte("shortened"), []byte("forever")); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Put([]byte("forever"), []byte("young")); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, err := st.TTL([]byte("shortlong")); err != nil || ttl <= 0 || ttl > time.Hour {
+		t.Errorf("expected ttl under an hour, got %v (err: %v)", ttl, err)
+	}
+	if ttl, err := st.TTL([]byte("forever")); err != nil || ttl != 0 {
+		t.Errorf("expected no ttl, got %v (err: %v)", ttl, err)
+	}
+	if st.Len() != 4 {
+		t.Fatalf("expected 4 keys before expiry, got %d", st.Len())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if st.Len() != 3 || len(st.Keys()) != 3 {
+		t.Errorf("expected 3 keys after expiry, got %d and %d", st.Len(), len(st.Keys()))
+	}
+	resChan, errChan := st.(database.Store).PrefixScan("short")
+	n := 0
+	for kvp := range resChan {
+		if kvp.Key.String() == "short" {
+			t.Error("expected expired key to be excluded from prefix scan")
+		}
+		n++
+	}
+	for err := range errChan {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 prefix scan results, got %d", n)
+	}
+	resChan, errChan = st.(database.Store).Search("lived")
+	n = 0
+	for range resChan {
+		n++
+	}
+	for err := range errChan {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 search result, got %d", n)
+	}
+	if st.Has([]byte("short")) {
+		t.Error("expected expired key to not exist")
+	}
+	if _, err := st.Get([]byte("short")); !kv.IsNonExistentKey(err) {
+		t.Errorf("expected NonExistentKeyError, got %v", err)
+	}
+	if _, err := st.TTL([]byte("short")); !kv.IsNonExistentKey(err) {
+		t.Errorf("expected NonExistentKeyError, got %v", err)
+	}
+	if v, err := st.Get([]byte("shortened")); err != nil || string(v) != "forever" {
+		t.Errorf("expected Put to clear ttl, got %s (err: %v)", v, err)
+	}
+}
+
+func TestDB_Sweeper(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	db.SetSweepInterval(10 * time.Millisecond)
+	st := db.WithNew("test").(database.ExpiringFiler)
+	if err := st.PutWithTTL([]byte("yeet"), []byte("yeeterson"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.PutWithTTL([]byte("later"), []byte("yeeterson"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if has, _ := st.(*Store).DB.Has([]byte("yeet")); has {
+		t.Error("expected sweeper to have removed expired key from pogreb")
+	}
+	if err := db.SyncAndCloseAll(); err != nil {
+		t.Fatal(err)
+	}
+	if db.sweeper.Running() {
+		t.Error("expected sweeper to stop when all stores are closed")
+	}
+
+	db = OpenDB(path)
+	if _, err := db.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	if ttl, err := db.With("test").(database.ExpiringFiler).TTL([]byte("later")); err != nil || ttl <= 0 {
+		t.Errorf("expected ttl to survive reopening, got %v (err: %v)", ttl, err)
+	}
+}
@@ -0,0 +1,404 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// missing is the value of a path that doesn't exist in a document.
+type missing struct{}
+
+// expr is a node of a parsed expression, evaluated against a decoded JSON document.
+type expr interface {
+	eval(doc any) any
+}
+
+// step is a single step of a path, either an object member or an array element.
+type step struct {
+	name  string
+	index int
+	elem  bool
+}
+
+// path is a JSON path such as $.user.emails[0] or $["odd key"].
+type path []step
+
+func (p path) eval(doc any) any {
+	node := doc
+	for _, st := range p {
+		switch n := node.(type) {
+		case map[string]any:
+			next, ok := n[st.name]
+			if st.elem || !ok {
+				return missing{}
+			}
+			node = next
+		case []any:
+			if !st.elem || st.index < 0 || st.index >= len(n) {
+				return missing{}
+			}
+			node = n[st.index]
+		default:
+			return missing{}
+		}
+	}
+	return node
+}
+
+type literal struct {
+	value any
+}
+
+func (l literal) eval(any) any {
+	return l.value
+}
+
+type not struct {
+	operand expr
+}
+
+func (n not) eval(doc any) any {
+	return !truthy(n.operand.eval(doc))
+}
+
+type logical struct {
+	and         bool
+	left, right expr
+}
+
+func (l logical) eval(doc any) any {
+	if truthy(l.left.eval(doc)) != l.and {
+		return !l.and
+	}
+	return truthy(l.right.eval(doc))
+}
+
+type comparison struct {
+	op          string
+	left, right expr
+}
+
+func (c comparison) eval(doc any) any {
+	return compare(c.op, c.left.eval(doc), c.right.eval(doc))
+}
+
+// truthy returns false for missing values, null, false, zero and empty strings, and true for anything else.
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case missing, nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if f, ok := number(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// compare applies a comparison operator. Numbers and strings are ordered, other values can only be tested
+// for equality. Any comparison involving a missing value is false.
+func compare(op string, a, b any) bool {
+	if _, ok := a.(missing); ok {
+		return false
+	}
+	if _, ok := b.(missing); ok {
+		return false
+	}
+	var c int
+	fa, aNum := number(a)
+	fb, bNum := number(b)
+	sa, aStr := a.(string)
+	sb, bStr := b.(string)
+	switch {
+	case aNum && bNum:
+		switch {
+		case fa < fb:
+			c = -1
+		case fa > fb:
+			c = 1
+		}
+	case aStr && bStr:
+		c = strings.Compare(sa, sb)
+	default:
+		equal := false
+		switch av := a.(type) {
+		case nil:
+			equal = b == nil
+		case bool:
+			bv, ok := b.(bool)
+			equal = ok && av == bv
+		}
+		switch op {
+		case "==":
+			return equal
+		case "!=":
+			return !equal
+		}
+		return false
+	}
+	switch op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// parser is a recursive descent parser for the grammar:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand ]
+//	operand = path | string | number | "true" | "false" | "null" | "(" or ")"
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrSyntax, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes tok if it is next, ignoring leading whitespace.
+func (p *parser) accept(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func parseExpr(src string) (expr, error) {
+	p := &parser{src: src}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return e, nil
+}
+
+func parsePath(src string) (path, error) {
+	p := &parser{src: src}
+	pa, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return pa, nil
+}
+
+func (p *parser) or() (expr, error) {
+	left, err := p.and()
+	for err == nil && p.accept("||") {
+		var right expr
+		if right, err = p.and(); err == nil {
+			left = logical{left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (expr, error) {
+	left, err := p.unary()
+	for err == nil && p.accept("&&") {
+		var right expr
+		if right, err = p.unary(); err == nil {
+			left = logical{and: true, left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.accept("!") {
+		if p.accept("=") {
+			p.pos -= 2
+			return nil, p.errorf("expected an operand")
+		}
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{operand: operand}, nil
+	}
+	return p.compare()
+}
+
+func (p *parser) compare() (expr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	// longer operators first, so that "<=" isn't read as "<".
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.accept(op) {
+			right, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			return comparison{op: op, left: left, right: right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) operand() (expr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected an operand")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '$':
+		return p.path()
+	case c == '(':
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return e, nil
+	case c == '"':
+		s, err := p.str()
+		return literal{value: s}, err
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.number()
+	}
+	for word, value := range map[string]any{"true": true, "false": false, "null": nil} {
+		if p.accept(word) {
+			return literal{value: value}, nil
+		}
+	}
+	return nil, p.errorf("expected an operand")
+}
+
+// str reads a double quoted string with JSON escapes.
+func (p *parser) str() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+				p.pos = start
+				return "", p.errorf("bad string: %v", err)
+			}
+			return s, nil
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) number() (expr, error) {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+		// a sign only belongs to the number straight after an exponent.
+		if c := p.src[p.pos]; (c == '+' || c == '-') && !strings.ContainsRune("eE", rune(p.src[p.pos-1])) {
+			break
+		}
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		p.pos = start
+		return nil, p.errorf("bad number %q", p.src[start:p.pos])
+	}
+	return literal{value: f}, nil
+}
+
+func (p *parser) path() (path, error) {
+	if !p.accept("$") {
+		return nil, p.errorf("expected a path starting with '$'")
+	}
+	pa := path{}
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '.':
+			p.pos++
+			start := p.pos
+			for p.pos < len(p.src) && isNameByte(p.src[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a field name")
+			}
+			pa = append(pa, step{name: p.src[start:p.pos]})
+		case '[':
+			p.pos++
+			p.skipSpace()
+			if p.pos < len(p.src) && p.src[p.pos] == '"' {
+				name, err := p.str()
+				if err != nil {
+					return nil, err
+				}
+				pa = append(pa, step{name: name})
+			} else {
+				start := p.pos
+				for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+					p.pos++
+				}
+				index, err := strconv.Atoi(p.src[start:p.pos])
+				if err != nil {
+					return nil, p.errorf("expected an array index or a quoted field name")
+				}
+				pa = append(pa, step{index: index, elem: true})
+			}
+			if !p.accept("]") {
+				return nil, p.errorf("expected ']'")
+			}
+		default:
+			return pa, nil
+		}
+	}
+	return pa, nil
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '-' || c >= 0x80 || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,77 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	doc := []byte(`{"name":"Ada","age":36,"active":true,"tags":["admin","ops"],"address":{"city":"London"},` +
+		`"odd key":1,"nothing":null,"zero":0,"empty":""}`)
+	for _, tc := range []struct {
+		where string
+		want  bool
+	}{
+		{"", true},
+		{`$.name == "Ada"`, true},
+		{`$.name != "Ada"`, false},
+		{`$.age > 30 && $.age <= 36`, true},
+		{`$.age >= 36.5`, false},
+		{`$.age == 3.6e1`, true},
+		{`$.age > -1`, true},
+		{`$.active`, true},
+		{`!$.active`, false},
+		{`$.active == true`, true},
+		{`$.tags[1] == "ops"`, true},
+		{`$.tags[2] == "ops"`, false},
+		{`$.address.city == "London" || $.missing`, true},
+		{`$["odd key"] == 1`, true},
+		{`$.missing`, false},
+		{`$.missing == null`, false},
+		{`$.missing != 1`, false},
+		{`!$.missing`, true},
+		{`$.nothing == null`, true},
+		{`$.nothing`, false},
+		{`$.zero || $.empty`, false},
+		{`$.name > "Ab" && $.name < "B"`, true},
+		{`$.name > 1`, false},
+		{`$.name != 1`, true},
+		{`!($.age < 30 || $.name == "Bob") && ($.tags[0] == "admin")`, true},
+		{`$.age == $.age`, true},
+		{`$.address`, true},
+	} {
+		q, err := Compile(tc.where)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", tc.where, err)
+			continue
+		}
+		if _, got := q.Match(doc); got != tc.want {
+			t.Errorf("%s: expected %t, got %t", tc.where, tc.want, got)
+		}
+	}
+
+	for _, bad := range []string{"$.", "$[", `$["x"`, "$.a ==", "($.a", "$.a)", "name", `"open`, "$.a = 1", "!= 1", "1..2"} {
+		if _, err := Compile(bad); !errors.Is(err, ErrSyntax) {
+			t.Errorf("%q: expected ErrSyntax, got %v", bad, err)
+		}
+	}
+	if _, err := Compile("", "$.a", "a"); !errors.Is(err, ErrSyntax) {
+		t.Errorf("expected ErrSyntax for a bad field, got %v", err)
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	q := MustCompile(`$.age > 30`, "$.name", "$.address.city", "$.missing")
+	got, ok := q.Match([]byte(`{"name":"Ada","age":36,"address":{"city":"London"}}`))
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if want := `{"$.address.city":"London","$.name":"Ada"}`; string(got) != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	for _, value := range []string{`{"age":20}`, `not json`, `{"age":40} trailing`, ``} {
+		if _, ok = q.Match([]byte(value)); ok {
+			t.Errorf("%s: expected no match", value)
+		}
+	}
+}
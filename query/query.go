@@ -0,0 +1,268 @@
+// Package query evaluates JSON-path expressions against the values of any [database.Searcher].
+//
+// An expression compares paths into each value with literals or other paths, for example:
+//
+//	$.status == "active" && ($.age > 30 || !$.verified)
+//
+// Paths start at the document root $ and step into objects with .name or ["name"], and into arrays
+// with [index]. Values that aren't JSON documents never match. A path that doesn't exist in a document
+// makes any comparison it takes part in false, on its own a path matches if it exists and isn't null,
+// false, zero or an empty string.
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+// ErrSyntax is returned by [Compile] when an expression or a path can't be parsed.
+var ErrSyntax = errors.New("query syntax error")
+
+// Query is a compiled expression, optionally with a projection. It is safe for concurrent use.
+type Query struct {
+	where  expr
+	fields []string
+	paths  []path
+}
+
+// Aggregate summarizes the values matched by a [Query].
+type Aggregate struct {
+	// Count is the number of values matched.
+	Count int
+	// Sum is the total of the summed field over the matched values in which it is a number.
+	Sum float64
+}
+
+// Compile parses the expression where, which may be empty to match every JSON value.
+// If fields are given, matches are projected onto them: each matched value is replaced by a JSON object
+// mapping each field, written as a path, to its value. Fields missing from a value are left out.
+func Compile(where string, fields ...string) (*Query, error) {
+	q := &Query{fields: fields}
+	if len(bytes.TrimSpace([]byte(where))) > 0 {
+		e, err := parseExpr(where)
+		if err != nil {
+			return nil, err
+		}
+		q.where = e
+	}
+	for _, field := range fields {
+		p, err := parsePath(field)
+		if err != nil {
+			return nil, err
+		}
+		q.paths = append(q.paths, p)
+	}
+	return q, nil
+}
+
+// MustCompile is like [Compile] but panics if the expression can't be parsed.
+func MustCompile(where string, fields ...string) *Query {
+	q, err := Compile(where, fields...)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// decode parses a JSON document, keeping numbers as [json.Number] so they are projected unchanged.
+func decode(value []byte) (any, bool) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, false
+	}
+	// trailing garbage means this isn't a JSON document.
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, false
+	}
+	return doc, true
+}
+
+func (q *Query) matches(doc any) bool {
+	return q.where == nil || truthy(q.where.eval(doc))
+}
+
+// project returns value, or its projection onto the query's fields if it has any.
+func (q *Query) project(value []byte, doc any) ([]byte, error) {
+	if len(q.paths) == 0 {
+		return value, nil
+	}
+	out := make(map[string]any, len(q.paths))
+	for i, p := range q.paths {
+		if v := p.eval(doc); v != (missing{}) {
+			out[q.fields[i]] = v
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Match evaluates the query against a single value, returning the value, or its projection, if it matches.
+func (q *Query) Match(value []byte) ([]byte, bool) {
+	doc, ok := decode(value)
+	if !ok || !q.matches(doc) {
+		return nil, false
+	}
+	res, err := q.project(value, doc)
+	return res, err == nil
+}
+
+// scan starts a scan of every key/value pair of s, one that stops once ctx is done if s supports it.
+func scan(ctx context.Context, s database.Searcher) (<-chan kv.KeyValue, chan error) {
+	if cs, ok := s.(database.ContextSearcher); ok {
+		return cs.PrefixScanContext(ctx, "")
+	}
+	return s.PrefixScan("")
+}
+
+// drain lets a scan we stopped reading from run to completion.
+func drain(resChan <-chan kv.KeyValue) {
+	go func() {
+		for range resChan {
+		}
+	}()
+}
+
+// each calls fn with every value of s matched by the query, along with its decoded document.
+func (q *Query) each(ctx context.Context, s database.Searcher, fn func(keyVal kv.KeyValue, doc any) error) error {
+	resChan, errChan := scan(ctx, s)
+	for keyVal := range resChan {
+		if err := ctx.Err(); err != nil {
+			drain(resChan)
+			return err
+		}
+		doc, ok := decode(keyVal.Value.Bytes())
+		if !ok || !q.matches(doc) {
+			continue
+		}
+		if err := fn(keyVal, doc); err != nil {
+			drain(resChan)
+			return err
+		}
+	}
+	if err := <-errChan; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Run streams the key/value pairs of s whose values match the query, projected if the query has fields.
+// Results are streamed with the same contract as [database.Searcher.Search].
+func (q *Query) Run(s database.Searcher) (<-chan kv.KeyValue, chan error) {
+	return q.RunContext(context.Background(), s)
+}
+
+// RunContext is like Run, but stops once ctx is done, sending the context's error.
+func (q *Query) RunContext(ctx context.Context, s database.Searcher) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		err := q.each(ctx, s, func(keyVal kv.KeyValue, doc any) error {
+			value, err := q.project(keyVal.Value.Bytes(), doc)
+			if err != nil {
+				return err
+			}
+			if !kv.Send(ctx, resChan, kv.NewKeyValueFromBytes(keyVal.Key.Bytes(), value)) {
+				return ctx.Err()
+			}
+			return nil
+		})
+		if err != nil {
+			errChan <- err
+		}
+	}()
+	return resChan, errChan
+}
+
+// Aggregate counts the values of s matched by the query and totals the numeric field sum, if it isn't empty.
+func (q *Query) Aggregate(s database.Searcher, sum string) (Aggregate, error) {
+	return q.AggregateContext(context.Background(), s, sum)
+}
+
+// AggregateContext is like Aggregate, but stops once ctx is done, returning the context's error.
+func (q *Query) AggregateContext(ctx context.Context, s database.Searcher, sum string) (Aggregate, error) {
+	groups, err := q.aggregate(ctx, s, nil, sum)
+	return groups[""], err
+}
+
+// GroupBy is like Aggregate, but aggregates separately for each value of the field by. Groups are keyed by
+// the field's value if it is a string, or its JSON encoding otherwise. Values without the field are skipped.
+func (q *Query) GroupBy(s database.Searcher, by, sum string) (map[string]Aggregate, error) {
+	return q.GroupByContext(context.Background(), s, by, sum)
+}
+
+// GroupByContext is like GroupBy, but stops once ctx is done, returning the context's error.
+func (q *Query) GroupByContext(ctx context.Context, s database.Searcher, by, sum string) (map[string]Aggregate, error) {
+	byPath, err := parsePath(by)
+	if err != nil {
+		return nil, err
+	}
+	return q.aggregate(ctx, s, byPath, sum)
+}
+
+func (q *Query) aggregate(ctx context.Context, s database.Searcher, byPath path, sum string) (map[string]Aggregate, error) {
+	var sumPath path
+	var err error
+	if sum != "" {
+		if sumPath, err = parsePath(sum); err != nil {
+			return nil, err
+		}
+	}
+	groups := make(map[string]Aggregate)
+	err = q.each(ctx, s, func(_ kv.KeyValue, doc any) error {
+		group := ""
+		if byPath != nil {
+			v := byPath.eval(doc)
+			if v == (missing{}) {
+				return nil
+			}
+			if str, ok := v.(string); ok {
+				group = str
+			} else {
+				text, jsonErr := json.Marshal(v)
+				if jsonErr != nil {
+					return jsonErr
+				}
+				group = string(text)
+			}
+		}
+		agg := groups[group]
+		agg.Count++
+		if sumPath != nil {
+			if f, ok := number(sumPath.eval(doc)); ok {
+				agg.Sum += f
+			}
+		}
+		groups[group] = agg
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Run compiles where and fields with [Compile] and runs the query against s, sending any compilation error
+// to the error channel.
+func Run(s database.Searcher, where string, fields ...string) (<-chan kv.KeyValue, chan error) {
+	q, err := Compile(where, fields...)
+	if err != nil {
+		resChan := make(chan kv.KeyValue)
+		errChan := make(chan error, 1)
+		errChan <- err
+		close(resChan)
+		close(errChan)
+		return resChan, errChan
+	}
+	return q.Run(s)
+}
@@ -0,0 +1,140 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/memory"
+)
+
+func newPeople(t *testing.T) database.Searcher {
+	t.Helper()
+	st := memory.OpenDB(t.TempDir()).WithNew("people")
+	for key, value := range map[string]string{
+		"ada":    `{"name":"Ada","status":"active","age":36,"team":"core","score":10}`,
+		"bob":    `{"name":"Bob","status":"active","age":25,"team":"core","score":2.5}`,
+		"carol":  `{"name":"Carol","status":"retired","age":61,"team":"web","score":4}`,
+		"dave":   `{"name":"Dave","status":"active","age":44,"team":"web"}`,
+		"erin":   `{"name":"Erin","status":"active","age":52,"score":"n/a"}`,
+		"binary": "\x00\x01\x02",
+	} {
+		if err := st.Put([]byte(key), []byte(value)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st.(database.Searcher)
+}
+
+func collect(t *testing.T, st database.Searcher, where string, fields ...string) map[string]string {
+	t.Helper()
+	resChan, errChan := Run(st, where, fields...)
+	got := make(map[string]string)
+	for keyVal := range resChan {
+		got[keyVal.Key.String()] = keyVal.Value.String()
+	}
+	if err := <-errChan; err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return got
+}
+
+func TestRun(t *testing.T) {
+	st := newPeople(t)
+
+	got := collect(t, st, `$.status == "active" && $.age > 30`)
+	keys := make([]string, 0, len(got))
+	for key := range got {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	if want := []string{"ada", "dave", "erin"}; !slices.Equal(keys, want) {
+		t.Errorf("expected %v, got %v", want, keys)
+	}
+	if got["ada"] != `{"name":"Ada","status":"active","age":36,"team":"core","score":10}` {
+		t.Errorf("expected the stored value, got %s", got["ada"])
+	}
+
+	got = collect(t, st, `$.team == "web"`, "$.name", "$.score")
+	if len(got) != 2 || got["carol"] != `{"$.name":"Carol","$.score":4}` || got["dave"] != `{"$.name":"Dave"}` {
+		t.Errorf("expected projected values, got %v", got)
+	}
+
+	resChan, errChan := Run(st, `$.age >`)
+	for range resChan {
+		t.Error("expected no results")
+	}
+	if err := <-errChan; !errors.Is(err, ErrSyntax) {
+		t.Errorf("expected ErrSyntax, got %v", err)
+	}
+}
+
+func TestQuery_RunContext(t *testing.T) {
+	st := memory.OpenDB(t.TempDir()).WithNew("many")
+	for i := 0; i < 100; i++ {
+		if err := st.Put([]byte(fmt.Sprintf("key%03d", i)), []byte(`{"i":1}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	resChan, errChan := MustCompile("").RunContext(ctx, st.(database.Searcher))
+	<-resChan
+	cancel()
+	for range resChan {
+	}
+	if err := <-errChan; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestQuery_Aggregate(t *testing.T) {
+	st := newPeople(t)
+
+	agg, err := MustCompile(`$.status == "active"`).Aggregate(st, "$.score")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if agg.Count != 4 || agg.Sum != 12.5 {
+		t.Errorf("expected 4 values summing to 12.5, got %+v", agg)
+	}
+	if agg, err = MustCompile(`$.age > 100`).Aggregate(st, ""); err != nil || agg.Count != 0 {
+		t.Errorf("expected no matches, got %+v (%v)", agg, err)
+	}
+
+	groups, err := MustCompile("").GroupBy(st, "$.team", "$.age")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	want := map[string]Aggregate{"core": {Count: 2, Sum: 61}, "web": {Count: 2, Sum: 105}}
+	if len(groups) != len(want) || groups["core"] != want["core"] || groups["web"] != want["web"] {
+		t.Errorf("expected %v, got %v", want, groups)
+	}
+
+	groups, err = MustCompile(`$.score`).GroupBy(st, "$.age > 40", "")
+	if !errors.Is(err, ErrSyntax) {
+		t.Errorf("expected ErrSyntax, got %v (%v)", err, groups)
+	}
+	if groups, err = MustCompile("").GroupBy(st, "$.score", ""); err != nil || groups["10"].Count != 1 || groups["n/a"].Count != 1 {
+		t.Errorf("expected groups keyed by JSON text or raw string, got %v (%v)", groups, err)
+	}
+}
+
+func TestQuery_AggregateContext(t *testing.T) {
+	st := newPeople(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := MustCompile("").AggregateContext(ctx, st, "$.age"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := MustCompile("").GroupByContext(ctx, st, "$.team", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	agg, err := MustCompile("").AggregateContext(context.Background(), st, "$.age")
+	if want, _ := MustCompile("").Aggregate(st, "$.age"); err != nil || agg != want {
+		t.Errorf("expected %+v, got %+v (%v)", want, agg, err)
+	}
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/tcp-direct/database/kv"
+)
+
+// ErrBadGlob is returned by [CompileGlob] when a pattern has an unterminated character class or escape.
+var ErrBadGlob = errors.New("malformed glob pattern")
+
+// Match selects which part of each key/value pair a pattern is matched against.
+type Match uint8
+
+const (
+	// MatchValues matches against values only, like [Searcher.Search].
+	MatchValues Match = iota
+	// MatchKeys matches against keys only.
+	MatchKeys
+	// MatchEither matches pairs where either the key or the value matches.
+	MatchEither
+)
+
+// Matches returns true if re matches the parts of the key/value pair selected by m.
+func (m Match) Matches(re *regexp.Regexp, key, value []byte) bool {
+	switch m {
+	case MatchKeys:
+		return re.Match(key)
+	case MatchEither:
+		return re.Match(key) || re.Match(value)
+	default:
+		return re.Match(value)
+	}
+}
+
+// RegexSearcher is a Searcher that can match keys and values against regular expressions and glob patterns.
+// Results are streamed with the same contract as [Searcher.Search].
+type RegexSearcher interface {
+	Searcher
+	// SearchRegex should stream every key/value pair whose value matches re.
+	SearchRegex(re *regexp.Regexp) (<-chan kv.KeyValue, chan error)
+	// KeyRegex should stream every key/value pair whose key matches re.
+	KeyRegex(re *regexp.Regexp) (<-chan kv.KeyValue, chan error)
+	// KeyGlob should stream every key/value pair whose whole key matches the glob pattern, see [CompileGlob].
+	KeyGlob(pattern string) (<-chan kv.KeyValue, chan error)
+	// MatchRegex should stream every key/value pair where the parts selected by in match re.
+	MatchRegex(re *regexp.Regexp, in Match) (<-chan kv.KeyValue, chan error)
+}
+
+// CompileGlob converts a glob pattern in to a regular expression that matches whole keys.
+// '*' matches any run of bytes, including '/', '?' matches any single character, '[abc]', '[a-z]' and
+// '[!abc]' match character classes, and '\' escapes the character that follows it.
+func CompileGlob(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString(`(?s)^`)
+	glob := []rune(pattern)
+	for i := 0; i < len(glob); i++ {
+		switch glob[i] {
+		case '*':
+			expr.WriteString(`.*`)
+		case '?':
+			expr.WriteString(`.`)
+		case '\\':
+			if i++; i == len(glob) {
+				return nil, ErrBadGlob
+			}
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case '[':
+			end := slices.Index(glob[i+1:], ']')
+			if end < 0 {
+				return nil, ErrBadGlob
+			}
+			class := glob[i+1 : i+1+end]
+			if len(class) == 0 || (len(class) == 1 && class[0] == '!') {
+				return nil, ErrBadGlob
+			}
+			expr.WriteByte('[')
+			if class[0] == '!' {
+				expr.WriteByte('^')
+				class = class[1:]
+			}
+			// only ranges keep their meaning inside of the class, everything else is literal.
+			for _, r := range class {
+				if r == '-' {
+					expr.WriteByte('-')
+					continue
+				}
+				expr.WriteString(regexp.QuoteMeta(string(r)))
+			}
+			expr.WriteByte(']')
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+		}
+	}
+	expr.WriteString(`$`)
+	return regexp.Compile(expr.String())
+}
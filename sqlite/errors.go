@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+)
+
+//goland:noinspection GoExportedElementShouldHaveComment
+var (
+	ErrBogusStore   = errors.New("bogus store backend")
+	ErrBadOptions   = errors.New("sqlite stores do not take options")
+	ErrBadStoreName = errors.New("invalid sqlite store name")
+	ErrStoreExists  = errors.New("store name already exists")
+	ErrNoStores     = errors.New("no stores initialized")
+	ErrEmptyKey     = errors.New("empty key")
+)
+
+func namedErr(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+func compoundErrors(errs []error) (err error) {
+	return errors.Join(errs...)
+}
@@ -0,0 +1,17 @@
+package sqlite
+
+import (
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/registry"
+)
+
+func init() {
+	registry.RegisterKeeper("sqlite", func(path string, opts ...any) (database.Keeper, error) {
+		if len(opts) > 0 {
+			return nil, ErrBadOptions
+		}
+		db := OpenDB(path)
+		err := db.init()
+		return db, err
+	})
+}
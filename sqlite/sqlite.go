@@ -0,0 +1,525 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	_ "modernc.org/sqlite" // pure-go sqlite driver
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/metadata"
+	"github.com/tcp-direct/database/models"
+)
+
+// dbFile is the name of the sqlite database file that holds every store's table.
+const dbFile = "sqlite.db"
+
+// tablePrefix is prepended to every store name to form its table name.
+// This keeps store names clear of sqlite's reserved "sqlite_" namespace.
+const tablePrefix = "store_"
+
+// tableName quotes a store name for use as an sqlite table name.
+func tableName(name string) string {
+	return `"` + tablePrefix + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Store is an implementation of a Filer and a Searcher using a single sqlite table.
+type Store struct {
+	db     *sql.DB
+	name   string
+	table  string
+	closed *atomic.Bool
+}
+
+func newStore(db *sql.DB, name string) *Store {
+	aclosed := &atomic.Bool{}
+	aclosed.Store(false)
+	return &Store{db: db, name: name, table: tableName(name), closed: aclosed}
+}
+
+// Backend returns the underlying [*sql.DB] shared by all stores in the keeper.
+func (s *Store) Backend() any {
+	return s.db
+}
+
+// Has returns true if the given key has an associated value.
+func (s *Store) Has(key []byte) bool {
+	if s.closed.Load() {
+		return false
+	}
+	var one int
+	err := s.db.QueryRow("SELECT 1 FROM "+s.table+" WHERE key = ?", key).Scan(&one)
+	return err == nil
+}
+
+// Get retrieves the value associated with the given key.
+func (s *Store) Get(key []byte) ([]byte, error) {
+	return s.GetContext(context.Background(), key)
+}
+
+// GetContext is like Get, but the query is interrupted once ctx is done.
+func (s *Store) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	if s.closed.Load() {
+		return nil, fs.ErrClosed
+	}
+	var ret []byte
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM "+s.table+" WHERE key = ?", key).Scan(&ret)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = nil
+	}
+	if err == nil && ret == nil {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
+	return ret, kv.RegularizeKVError(key, ret, err)
+}
+
+// Put inserts or replaces the value associated with the given key.
+func (s *Store) Put(key []byte, value []byte) error {
+	return s.PutContext(context.Background(), key, value)
+}
+
+// PutContext is like Put, but the statement is interrupted once ctx is done.
+func (s *Store) PutContext(ctx context.Context, key []byte, value []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO "+s.table+" (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+		key, value,
+	)
+	return err
+}
+
+// Delete removes the given key and its value.
+func (s *Store) Delete(key []byte) error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	_, err := s.db.Exec("DELETE FROM "+s.table+" WHERE key = ?", key)
+	return err
+}
+
+// Close marks the store as closed. The underlying connection is shared and owned by the [DB].
+func (s *Store) Close() error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	s.closed.Store(true)
+	return nil
+}
+
+// Sync checkpoints the write-ahead log into the main database file.
+func (s *Store) Sync() error {
+	if s.closed.Load() {
+		return fs.ErrClosed
+	}
+	_, err := s.db.Exec("PRAGMA wal_checkpoint(FULL)")
+	return err
+}
+
+// Keys returns all keys in the store, in byte order, as a slice of byte slices.
+func (s *Store) Keys() [][]byte {
+	rows, err := s.db.Query("SELECT key FROM " + s.table + " ORDER BY key")
+	if err != nil {
+		return nil
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	keys := make([][]byte, 0)
+	for rows.Next() {
+		var k []byte
+		if err = rows.Scan(&k); err != nil {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Len returns the number of keys in the store.
+func (s *Store) Len() int {
+	var n int
+	_ = s.db.QueryRow("SELECT COUNT(*) FROM " + s.table).Scan(&n)
+	return n
+}
+
+// DB is a mapper of a Filer and Searcher implementation using sqlite, where each store is a table named "store_<name>".
+type DB struct {
+	store       map[string]*Store
+	conn        *sql.DB
+	path        string
+	mu          *sync.RWMutex
+	meta        *metadata.Metadata
+	initialized *atomic.Bool
+}
+
+// OpenDB will either open an existing sqlite keeper at the given directory, or it will create a new one.
+func OpenDB(path string) *DB {
+	ainit := &atomic.Bool{}
+	ainit.Store(false)
+	return &DB{
+		store:       make(map[string]*Store),
+		path:        path,
+		mu:          &sync.RWMutex{},
+		meta:        nil,
+		initialized: ainit,
+	}
+}
+
+// Path returns the base path where we store our sqlite database.
+func (db *DB) Path() string {
+	return db.path
+}
+
+// Meta returns the [models.Metadata] implementation of the sqlite keeper.
+func (db *DB) Meta() models.Metadata {
+	var m models.Metadata
+	db.mu.RLock()
+	m = db.meta
+	db.mu.RUnlock()
+	if m == nil {
+		m = metadata.NewPlaceholder(db.Type())
+	}
+	return m
+}
+
+func (db *DB) _init() error {
+	if _, err := os.Stat(db.path); os.IsNotExist(err) {
+		err = os.MkdirAll(db.path, 0700)
+		if err != nil {
+			return fmt.Errorf("error creating sqlite directory: %w", err)
+		}
+	}
+	stat, err := os.Stat(filepath.Join(db.path, "meta.json"))
+	if err == nil && stat.IsDir() {
+		return errors.New("meta.json is a directory")
+	}
+	if err == nil && !stat.IsDir() {
+		if db.meta, err = metadata.OpenMetaFile(filepath.Join(db.path, "meta.json")); err != nil {
+			return fmt.Errorf("error opening meta file: %w", err)
+		}
+		if db.meta.Type() != db.Type() {
+			return fmt.Errorf("meta.json is not a sqlite meta file")
+		}
+		db.initialized.Store(true)
+		return nil
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		db.meta, err = metadata.NewMetaFile(db.Type(), filepath.Join(db.path, "meta.json"))
+		if err != nil {
+			return fmt.Errorf("error creating meta file: %w", err)
+		}
+		db.initialized.Store(true)
+		return nil
+	}
+
+	return err
+}
+
+func (db *DB) init() error {
+	if db.initialized.Load() {
+		return nil
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db._init()
+}
+
+// open opens the shared sqlite connection pool if it isn't already, caller must hold write lock.
+func (db *DB) open() error {
+	if db.conn != nil {
+		return nil
+	}
+	dsn := "file:" + filepath.Join(db.path, dbFile) +
+		"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+	conn, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return err
+	}
+	if err = conn.Ping(); err != nil {
+		_ = conn.Close()
+		return err
+	}
+	db.conn = conn
+	return nil
+}
+
+// disconnect closes the shared sqlite connection pool, caller must hold write lock.
+func (db *DB) disconnect() error {
+	if db.conn == nil {
+		return nil
+	}
+	err := db.conn.Close()
+	db.conn = nil
+	return err
+}
+
+func (db *DB) allStores() map[string]database.Filer {
+	var stores = make(map[string]database.Filer)
+	for n, s := range db.store {
+		stores[n] = s
+	}
+	return stores
+}
+
+// AllStores returns a map of the names of all sqlite datastores and the corresponding Filers.
+func (db *DB) AllStores() map[string]database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.RLock()
+	ast := db.allStores()
+	db.mu.RUnlock()
+	return ast
+}
+
+// initStore is a helper function to create a store's table, caller must hold keeper's lock.
+func (db *DB) initStore(storeName string) error {
+	if _, ok := db.store[storeName]; ok {
+		return ErrStoreExists
+	}
+	if storeName == "" {
+		return fmt.Errorf("%w: %q", ErrBadStoreName, storeName)
+	}
+	if err := db.open(); err != nil {
+		return err
+	}
+	if _, err := db.conn.Exec(
+		"CREATE TABLE IF NOT EXISTS " + tableName(storeName) + " (key BLOB PRIMARY KEY, value BLOB) WITHOUT ROWID",
+	); err != nil {
+		return err
+	}
+	db.store[storeName] = newStore(db.conn, storeName)
+	if !slices.Contains(db.meta.KnownStores, storeName) {
+		db.meta.KnownStores = append(db.meta.KnownStores, storeName)
+	}
+	return nil
+}
+
+// Init creates a sqlite table to be referenced by storeName. sqlite stores take no options.
+func (db *DB) Init(storeName string, opts ...any) error {
+	if err := db.init(); err != nil {
+		return err
+	}
+	if len(opts) > 0 {
+		return ErrBadOptions
+	}
+	db.mu.Lock()
+	err := db.initStore(storeName)
+	db.mu.Unlock()
+	return err
+}
+
+// Destroy drops the table backing the given store.
+func (db *DB) Destroy(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	_ = st.Close()
+	delete(db.store, storeName)
+	db.meta.RemoveStore(storeName)
+	if err := db.open(); err != nil {
+		return err
+	}
+	_, err := db.conn.Exec("DROP TABLE IF EXISTS " + tableName(storeName))
+	return err
+}
+
+// With returns the given sqlite store, or nil if it has not been opened.
+func (db *DB) With(storeName string) database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.RLock()
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		db.mu.RUnlock()
+		return d
+	}
+	db.mu.RUnlock()
+	if ok {
+		db.mu.Lock()
+		delete(db.store, storeName)
+		db.mu.Unlock()
+	}
+	return nil
+}
+
+// WithNew returns the given sqlite store, if it doesn't exist, it creates it.
+func (db *DB) WithNew(storeName string, _ ...any) database.Filer {
+	if err := db.init(); err != nil {
+		panic(err)
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	d, ok := db.store[storeName]
+	if ok && !d.closed.Load() {
+		return d
+	}
+	if ok {
+		delete(db.store, storeName)
+	}
+	if err := db.initStore(storeName); err != nil {
+		fmt.Println("ERROR: failed to create sqlite store: ", err)
+		return nil
+	}
+	return db.store[storeName]
+}
+
+// Close closes the given store. The connection is released once no stores remain open.
+func (db *DB) Close(storeName string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	st, ok := db.store[storeName]
+	if !ok {
+		return ErrBogusStore
+	}
+	if err := st.Close(); err != nil {
+		return err
+	}
+	delete(db.store, storeName)
+	if len(db.store) == 0 {
+		return db.disconnect()
+	}
+	return nil
+}
+
+func (db *DB) addAllStoresToMeta() {
+	storeNames := make([]string, 0, len(db.store))
+	for name := range db.store {
+		storeNames = append(storeNames, name)
+	}
+	db.meta = db.meta.WithStores(storeNames...)
+}
+
+func (db *DB) syncAll() error {
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	db.addAllStoresToMeta()
+	errs := make([]error, 0, 2)
+	if db.conn != nil {
+		_, err := db.conn.Exec("PRAGMA wal_checkpoint(FULL)")
+		errs = append(errs, err)
+	}
+	errs = append(errs, db.meta.Sync())
+	return compoundErrors(errs)
+}
+
+// SyncAll checkpoints the write-ahead log and syncs the keeper's metadata.
+func (db *DB) SyncAll() error {
+	db.mu.Lock()
+	err := db.syncAll()
+	db.mu.Unlock()
+	return err
+}
+
+func (db *DB) closeAll() error {
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	for name, st := range db.store {
+		_ = st.Close()
+		delete(db.store, name)
+	}
+	return db.disconnect()
+}
+
+// CloseAll closes all sqlite stores and the underlying connection.
+func (db *DB) CloseAll() error {
+	db.mu.Lock()
+	err := db.closeAll()
+	db.mu.Unlock()
+	return err
+}
+
+// SyncAndCloseAll implements the method from Keeper to sync and close all sqlite stores.
+func (db *DB) SyncAndCloseAll() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if len(db.store) < 1 {
+		return ErrNoStores
+	}
+	return compoundErrors([]error{namedErr("sync", db.syncAll()), namedErr("close", db.closeAll())})
+}
+
+// discover opens every table in the sqlite database as a store, caller must hold write lock.
+func (db *DB) discover() ([]string, error) {
+	if _, err := os.Stat(filepath.Join(db.path, dbFile)); errors.Is(err, os.ErrNotExist) {
+		return []string{}, nil
+	}
+	if err := db.open(); err != nil {
+		return nil, err
+	}
+	rows, err := db.conn.Query(
+		"SELECT substr(name, ?) FROM sqlite_master WHERE type = 'table' AND substr(name, 1, ?) = ?",
+		len(tablePrefix)+1, len(tablePrefix), tablePrefix,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	_ = rows.Close()
+
+	stores := make([]string, 0, len(tables))
+	errs := make([]error, 0)
+	for _, name := range tables {
+		if st, ok := db.store[name]; ok && !st.closed.Load() {
+			stores = append(stores, name)
+			continue
+		}
+		delete(db.store, name)
+		if err = db.initStore(name); err != nil {
+			errs = append(errs, namedErr(name, err))
+			continue
+		}
+		stores = append(stores, name)
+	}
+	return stores, compoundErrors(errs)
+}
+
+// Discover will open every existing sqlite store at the path opened by [OpenDB].
+func (db *DB) Discover() ([]string, error) {
+	if err := db.init(); err != nil {
+		return nil, err
+	}
+	db.mu.Lock()
+	stores, err := db.discover()
+	db.mu.Unlock()
+	return stores, err
+}
+
+// Type returns the type of keeper, in this case "sqlite".
+// This is in order to implement [database.Keeper].
+func (db *DB) Type() string {
+	return "sqlite"
+}
@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/models"
+)
+
+// BackupAll creates an archive containing a consistent copy of the sqlite database and the keeper's metadata.
+// The copy is taken with VACUUM INTO, so stores remain open and usable during and after the backup.
+func (db *DB) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	return db.BackupAllContext(context.Background(), archivePath, opts...)
+}
+
+// BackupAllContext is like BackupAll, but writes the archive with opts and abandons the backup once ctx is done.
+func (db *DB) BackupAllContext(ctx context.Context, archivePath string, opts ...backup.Option) (models.Backup, error) {
+	if err := db.init(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	storeNames, err := db.discover()
+	if err != nil {
+		return nil, err
+	}
+	if err = db.syncAll(); err != nil && !errors.Is(err, ErrNoStores) {
+		return nil, err
+	}
+
+	staging, err := os.MkdirTemp("", "sqlite-backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(staging)
+	}()
+
+	metaDat, err := os.ReadFile(filepath.Join(db.path, "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(filepath.Join(staging, "meta.json"), metaDat, 0600); err != nil {
+		return nil, err
+	}
+
+	if db.conn != nil {
+		if _, err = db.conn.ExecContext(ctx, "VACUUM INTO ?", filepath.Join(staging, dbFile)); err != nil {
+			return nil, fmt.Errorf("error copying sqlite database: %w", err)
+		}
+	}
+
+	// stores are tables rather than directories, so the archive can't be checked for them by name.
+	b, err := backup.NewBackuper(backup.FormatFromPath(archivePath), opts...)
+	if err != nil {
+		return nil, err
+	}
+	bu, err := b.Backup(ctx, staging, archivePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(storeNames)
+	bu.Stores = storeNames
+	if db.meta.Backups == nil {
+		db.meta.Backups = make(map[string]any)
+	}
+	db.meta.Backups[bu.FilePath] = bu
+	err = db.meta.Sync()
+	return bu, err
+}
+
+// RestoreAll replaces the sqlite database with the contents of the given archive.
+// A backup of the existing stores is taken first and referenced in any errors.
+func (db *DB) RestoreAll(archivePath string, opts ...backup.Option) error {
+	if err := db.init(); err != nil {
+		return err
+	}
+
+	var preBackupPath string
+
+	db.mu.RLock()
+	hasStores := len(db.store) > 0
+	db.mu.RUnlock()
+
+	if hasStores {
+		preBu, err := db.BackupAll(filepath.Join(os.TempDir(), "pre-restore-"+time.Now().Format(time.RFC3339)+".tar.gz"))
+		if err != nil {
+			return fmt.Errorf("failed to create pre-restore backup: %w", err)
+		}
+		preBackupPath = fmt.Sprintf(" (backup: %s)", preBu.Path())
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for name, st := range db.store {
+		_ = st.Close()
+		delete(db.store, name)
+	}
+	if err := db.disconnect(); err != nil {
+		return fmt.Errorf("failed to close sqlite database%s: %w", preBackupPath, err)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(filepath.Join(db.path, dbFile+suffix)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove existing sqlite database%s: %w", preBackupPath, err)
+		}
+	}
+
+	// release the handle on the current meta.json before it is overwritten by the archive's copy.
+	db.meta.WithWriter(nil)
+	db.initialized.Store(false)
+
+	opts = append([]backup.Option{backup.WithKnown(backup.KnownBackups(db.meta)...)}, opts...)
+	if err := backup.RestoreBackup(archivePath, db.path, opts...); err != nil {
+		return fmt.Errorf("failed to restore archive%s: %w", preBackupPath, err)
+	}
+
+	if err := db._init(); err != nil {
+		return fmt.Errorf("failed to re-init db after restore%s: %w", preBackupPath, err)
+	}
+
+	if _, err := db.discover(); err != nil {
+		return fmt.Errorf("failed during discover call after restore%s: %w", preBackupPath, err)
+	}
+
+	if err := db.meta.Sync(); err != nil {
+		return fmt.Errorf("failed to sync meta after restore%s: %w", preBackupPath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package sqlite
+
+import (
+	"io/fs"
+	"strings"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/iterator"
+)
+
+// Range returns an iterator over the keys from start, inclusive, to end, exclusive, in byte order.
+// Each page of results is a separate query that picks up after the last key of the previous one.
+func (s *Store) Range(start, end []byte, opts database.RangeOptions) database.Iterator {
+	if s.closed.Load() {
+		return iterator.Error(fs.ErrClosed)
+	}
+	return iterator.Paged(func(after []byte, n int) ([][]byte, [][]byte, error) {
+		if s.closed.Load() {
+			return nil, nil, fs.ErrClosed
+		}
+		var (
+			conds []string
+			args  []any
+		)
+		if start != nil {
+			conds, args = append(conds, "key >= ?"), append(args, start)
+		}
+		if end != nil {
+			conds, args = append(conds, "key < ?"), append(args, end)
+		}
+		order := "ASC"
+		if after != nil {
+			if opts.Reverse {
+				conds = append(conds, "key < ?")
+			} else {
+				conds = append(conds, "key > ?")
+			}
+			args = append(args, after)
+		}
+		if opts.Reverse {
+			order = "DESC"
+		}
+		query := "SELECT key, value FROM " + s.table
+		if len(conds) > 0 {
+			query += " WHERE " + strings.Join(conds, " AND ")
+		}
+		query += " ORDER BY key " + order + " LIMIT ?"
+		args = append(args, n)
+
+		rows, err := s.db.Query(query, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		keys := make([][]byte, 0, n)
+		values := make([][]byte, 0, n)
+		for rows.Next() {
+			var k, v []byte
+			if err = rows.Scan(&k, &v); err != nil {
+				return keys, values, err
+			}
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+		return keys, values, rows.Err()
+	}, opts.Limit)
+}
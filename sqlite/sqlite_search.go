@@ -0,0 +1,84 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/tcp-direct/database/iterator"
+	"github.com/tcp-direct/database/kv"
+)
+
+// stream runs the given query and sends each (key, value) row to the returned channel.
+// Once ctx is done the query is interrupted.
+func (s *Store) stream(ctx context.Context, query string, args ...any) (<-chan kv.KeyValue, chan error) {
+	errChan := make(chan error, 1)
+	resChan := make(chan kv.KeyValue, 5)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		defer func(rows *sql.Rows) {
+			_ = rows.Close()
+		}(rows)
+		for rows.Next() {
+			var k, v []byte
+			if err = rows.Scan(&k, &v); err != nil {
+				errChan <- err
+				return
+			}
+			if !kv.Send(ctx, resChan, kv.NewKeyValueFromBytes(k, v)) {
+				errChan <- ctx.Err()
+				return
+			}
+		}
+		if err = rows.Err(); err != nil {
+			errChan <- err
+		}
+	}()
+	return resChan, errChan
+}
+
+// Search will search for a given string within all values inside of a Store.
+// The substring match is performed by sqlite rather than by iterating every key in Go.
+func (s *Store) Search(query string) (<-chan kv.KeyValue, chan error) {
+	return s.SearchContext(context.Background(), query)
+}
+
+// SearchContext is like Search, but stops once ctx is done.
+func (s *Store) SearchContext(ctx context.Context, query string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, "SELECT key, value FROM "+s.table+" WHERE instr(value, ?) > 0 ORDER BY key", []byte(query))
+}
+
+// PrefixScan will scan a Store for all keys that have a matching prefix of the given string
+// and stream the matching key/value pairs, in byte order, to the returned channel.
+// The scan is expressed as a range over the primary key so that sqlite can use its index.
+func (s *Store) PrefixScan(prefix string) (<-chan kv.KeyValue, chan error) {
+	return s.PrefixScanContext(context.Background(), prefix)
+}
+
+// PrefixScanContext is like PrefixScan, but stops once ctx is done.
+func (s *Store) PrefixScanContext(ctx context.Context, prefix string) (<-chan kv.KeyValue, chan error) {
+	start := []byte(prefix)
+	if end := iterator.PrefixEnd(start); end != nil {
+		return s.stream(ctx, "SELECT key, value FROM "+s.table+" WHERE key >= ? AND key < ? ORDER BY key", start, end)
+	}
+	return s.stream(ctx, "SELECT key, value FROM "+s.table+" WHERE key >= ? ORDER BY key", start)
+}
+
+// ValueExists will check for the existence of a Value anywhere within the keyspace;
+// returning the first Key found, true if found || nil and false if not found.
+func (s *Store) ValueExists(value []byte) (key []byte, ok bool) {
+	if value == nil {
+		value = []byte{}
+	}
+	if err := s.db.QueryRow("SELECT key FROM "+s.table+" WHERE value = ? LIMIT 1", value).Scan(&key); err != nil {
+		return nil, false
+	}
+	return key, true
+}
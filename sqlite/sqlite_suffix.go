@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+)
+
+var _ database.KeySearcher = (*Store)(nil)
+
+// SuffixScan will stream every key/value pair whose key ends with suffix, in byte order.
+// The match is performed by sqlite rather than by iterating every key in Go.
+func (s *Store) SuffixScan(suffix string) (<-chan kv.KeyValue, chan error) {
+	return s.SuffixScanContext(context.Background(), suffix)
+}
+
+// SuffixScanContext is like SuffixScan, but stops once ctx is done.
+func (s *Store) SuffixScanContext(ctx context.Context, suffix string) (<-chan kv.KeyValue, chan error) {
+	if suffix == "" {
+		return s.stream(ctx, "SELECT key, value FROM "+s.table+" ORDER BY key")
+	}
+	return s.stream(ctx, "SELECT key, value FROM "+s.table+" WHERE substr(key, -?) = ? ORDER BY key",
+		len(suffix), []byte(suffix))
+}
+
+// KeyContains will stream every key/value pair whose key contains substr, in byte order.
+// The match is performed by sqlite rather than by iterating every key in Go.
+func (s *Store) KeyContains(substr string) (<-chan kv.KeyValue, chan error) {
+	return s.KeyContainsContext(context.Background(), substr)
+}
+
+// KeyContainsContext is like KeyContains, but stops once ctx is done.
+func (s *Store) KeyContainsContext(ctx context.Context, substr string) (<-chan kv.KeyValue, chan error) {
+	return s.stream(ctx, "SELECT key, value FROM "+s.table+" WHERE instr(key, ?) > 0 ORDER BY key", []byte(substr))
+}
@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"testing"
+
+	c "git.tcp.direct/kayos/common/entropy"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/loader"
+	"github.com/tcp-direct/database/memory"
+	"github.com/tcp-direct/database/migrate"
+)
+
+func TestStore_Basic(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	for _, name := range []string{"test", `we"ird store`, "sqlite_master"} {
+		if err := db.Init(name); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+	if err := db.Init("test"); !errors.Is(err, ErrStoreExists) {
+		t.Fatalf("expected ErrStoreExists, got %v", err)
+	}
+	if err := db.Init(""); !errors.Is(err, ErrBadStoreName) {
+		t.Fatalf("expected ErrBadStoreName, got %v", err)
+	}
+	st := db.With(`we"ird store`)
+	if err := st.Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := st.Put([]byte("yeet"), []byte("yeeterson2")); err != nil {
+		t.Fatalf("expected no error on overwrite, got %v", err)
+	}
+	ret, err := st.Get([]byte("yeet"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(ret) != "yeeterson2" {
+		t.Errorf("expected yeeterson2, got %s", ret)
+	}
+	if !st.Has([]byte("yeet")) || st.Len() != 1 || len(st.Keys()) != 1 {
+		t.Errorf("expected exactly one key in store")
+	}
+	if db.With("test").Len() != 0 {
+		t.Errorf("expected stores to be isolated from one another")
+	}
+	if err = st.Delete([]byte("yeet")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !kv.IsNonExistentKey(err) {
+		t.Errorf("expected NonExistentKeyError, got %v", err)
+	}
+	if err = db.Close(`we"ird store`); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err = st.Get([]byte("yeet")); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("expected fs.ErrClosed, got %v", err)
+	}
+}
+
+func TestStore_SearchPushdown(t *testing.T) {
+	db := OpenDB(t.TempDir())
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	st := db.WithNew("test").(database.Store)
+	for i := 0; i < 100; i++ {
+		if err := st.Put([]byte(fmt.Sprintf("key_%03d", i)), []byte(c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if err := st.Put([]byte(c.RandStr(10)), []byte(c.RandStr(5)+"needle"+c.RandStr(5))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = st.Put([]byte{0xff, 0xff}, []byte("needle"))
+
+	count := func(resChan <-chan kv.KeyValue, errChan chan error) int {
+		var last []byte
+		n := 0
+		for res := range resChan {
+			if last != nil && bytes.Compare(last, res.Key.Bytes()) >= 0 {
+				t.Errorf("expected keys in order, got %s after %s", res.Key.String(), last)
+			}
+			last = res.Key.Bytes()
+			n++
+		}
+		for err := range errChan {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return n
+	}
+
+	if n := count(st.PrefixScan("key_0")); n != 100 {
+		t.Errorf("expected 100 prefix scan results, got %d", n)
+	}
+	if n := count(st.PrefixScan("key_05")); n != 10 {
+		t.Errorf("expected 10 prefix scan results, got %d", n)
+	}
+	if n := count(st.PrefixScan(string([]byte{0xff}))); n != 1 {
+		t.Errorf("expected 1 prefix scan result, got %d", n)
+	}
+	if n := count(st.Search("needle")); n != 11 {
+		t.Errorf("expected 11 search results, got %d", n)
+	}
+	if key, ok := st.ValueExists([]byte("needle")); !ok || !bytes.Equal(key, []byte{0xff, 0xff}) {
+		t.Errorf("expected to find exact value, got %v", key)
+	}
+}
+
+func TestDB_BackupRestoreAndLoader(t *testing.T) {
+	path := t.TempDir()
+	db := OpenDB(path)
+	if err := db.WithNew("test").Put([]byte("yeet"), []byte("yeeterson")); err != nil {
+		t.Fatal(err)
+	}
+	bu, err := db.BackupAll(filepath.Join(t.TempDir(), "sqlite.tar.gz"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err = db.With("test").Put([]byte("after"), []byte("backup")); err != nil {
+		t.Fatalf("expected store to be usable after backup, got %v", err)
+	}
+	if err = db.RestoreAll(bu.Path()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if db.With("test").Has([]byte("after")) {
+		t.Error("expected key written after the backup to be gone after restore")
+	}
+	if !db.With("test").Has([]byte("yeet")) {
+		t.Error("expected key written before the backup to exist after restore")
+	}
+	if err = db.SyncAndCloseAll(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	keeper, err := loader.OpenKeeper(path)
+	if err != nil {
+		t.Fatalf("expected loader to open sqlite keeper, got %v", err)
+	}
+	if ret, getErr := keeper.With("test").Get([]byte("yeet")); getErr != nil || string(ret) != "yeeterson" {
+		t.Errorf("expected yeeterson, got %s (err: %v)", ret, getErr)
+	}
+	_ = keeper.CloseAll()
+}
+
+func TestMigrate(t *testing.T) {
+	from := memory.OpenDB("")
+	for i := 0; i < 100; i++ {
+		if err := from.WithNew("test").Put([]byte(c.RandStr(10)), []byte(c.RandStr(10))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	to := OpenDB(t.TempDir())
+	t.Cleanup(func() {
+		_ = to.CloseAll()
+	})
+	m, err := migrate.NewMigrator(from, to)
+	if err != nil {
+		t.Fatalf("error creating migrator: %v", err)
+	}
+	if err = m.Migrate(); err != nil {
+		t.Fatalf("error migrating: %v", err)
+	}
+	if to.With("test").Len() != 100 {
+		t.Fatalf("expected 100 keys after migration, got %d", to.With("test").Len())
+	}
+	for _, key := range from.With("test").Keys() {
+		want, _ := from.With("test").Get(key)
+		got, getErr := to.With("test").Get(key)
+		if getErr != nil || !bytes.Equal(want, got) {
+			t.Errorf("expected %q, got %q (err: %v)", want, got, getErr)
+		}
+	}
+}
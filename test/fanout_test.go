@@ -0,0 +1,186 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/memory"
+)
+
+// brokenKeeper adds a store that can't be searched to the stores of its Keeper.
+type brokenKeeper struct {
+	database.Keeper
+}
+
+func (b brokenKeeper) AllStores() map[string]database.Filer {
+	stores := b.Keeper.AllStores()
+	stores["broken"] = struct{ database.Filer }{}
+	return stores
+}
+
+func collectAll(t *testing.T, search func() (<-chan kv.StoreKeyValue, chan error)) ([]string, error) {
+	t.Helper()
+	resChan, errChan := search()
+	var got []string
+	for keyVal := range resChan {
+		got = append(got, keyVal.Store+"/"+keyVal.Key.String())
+	}
+	slices.Sort(got)
+	return got, <-errChan
+}
+
+func TestSearchAll(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	for _, name := range []string{"users", "orders", "empty"} {
+		db.WithNew(name)
+	}
+	for store, data := range map[string]map[string]string{
+		"users":  {"user/1": "alice", "user/2": "bob", "admin": "carol"},
+		"orders": {"order/1": "bought by alice", "user/x": "not a user"},
+	} {
+		for key, value := range data {
+			if err := db.With(store).Put([]byte(key), []byte(value)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got, err := collectAll(t, func() (<-chan kv.StoreKeyValue, chan error) {
+		return database.SearchAll(db, "alice")
+	})
+	if want := []string{"orders/order/1", "users/user/1"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q (%v)", want, got, err)
+	}
+	got, err = collectAll(t, func() (<-chan kv.StoreKeyValue, chan error) {
+		return database.PrefixScanAllContext(context.Background(), db, "user/", 1)
+	})
+	if want := []string{"orders/user/x", "users/user/1", "users/user/2"}; err != nil || !slices.Equal(got, want) {
+		t.Errorf("expected %q, got %q (%v)", want, got, err)
+	}
+
+	got, err = collectAll(t, func() (<-chan kv.StoreKeyValue, chan error) {
+		return database.PrefixScanAll(brokenKeeper{db}, "user/")
+	})
+	if len(got) != 3 {
+		t.Errorf("expected the other stores to be scanned, got %q", got)
+	}
+	var storeErrs database.StoreErrors
+	if !errors.As(err, &storeErrs) || len(storeErrs) != 1 || !errors.Is(storeErrs["broken"], database.ErrNotSearcher) {
+		t.Errorf("expected ErrNotSearcher for the broken store, got %v", err)
+	}
+	if !errors.Is(err, database.ErrNotSearcher) {
+		t.Errorf("expected errors.Is to reach the store's error, got %v", err)
+	}
+}
+
+func TestSearchAllContext(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	for i := 0; i < 4; i++ {
+		st := db.WithNew(fmt.Sprintf("store%d", i))
+		for j := 0; j < 100; j++ {
+			if err := st.Put([]byte(fmt.Sprintf("key%03d", j)), []byte("value")); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	resChan, errChan := database.SearchAllContext(ctx, db, "value", 2)
+	<-resChan
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		for range resChan {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected result channel to be closed after cancellation")
+	}
+	if err := <-errChan; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// streamingErrStore sends an error part way through its results on an unbuffered channel.
+type streamingErrStore struct {
+	database.Filer
+}
+
+var errMidStream = errors.New("mid-stream failure")
+
+func (streamingErrStore) scan() (<-chan kv.KeyValue, chan error) {
+	resChan := make(chan kv.KeyValue)
+	errChan := make(chan error)
+	go func() {
+		defer func() {
+			close(resChan)
+			close(errChan)
+		}()
+		resChan <- kv.NewKeyValueFromBytes([]byte("before"), []byte("value"))
+		errChan <- errMidStream
+		resChan <- kv.NewKeyValueFromBytes([]byte("after"), []byte("value"))
+	}()
+	return resChan, errChan
+}
+
+func (s streamingErrStore) PrefixScan(string) (<-chan kv.KeyValue, chan error) {
+	return s.scan()
+}
+
+func (s streamingErrStore) Search(string) (<-chan kv.KeyValue, chan error) {
+	return s.scan()
+}
+
+func (streamingErrStore) ValueExists([]byte) ([]byte, bool) {
+	return nil, false
+}
+
+// streamingErrKeeper adds a streamingErrStore to the stores of its Keeper.
+type streamingErrKeeper struct {
+	database.Keeper
+}
+
+func (k streamingErrKeeper) AllStores() map[string]database.Filer {
+	stores := k.Keeper.AllStores()
+	stores["streaming"] = streamingErrStore{}
+	return stores
+}
+
+func TestSearchAll_MidStreamError(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	if err := db.WithNew("users").Put([]byte("user/1"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	type result struct {
+		got []string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		got, err := collectAll(t, func() (<-chan kv.StoreKeyValue, chan error) {
+			return database.SearchAll(streamingErrKeeper{db}, "value")
+		})
+		done <- result{got, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the search to finish when a store sends an error before its results are done")
+	}
+	if want := []string{"streaming/after", "streaming/before", "users/user/1"}; !slices.Equal(res.got, want) {
+		t.Errorf("expected %q, got %q", want, res.got)
+	}
+	var storeErrs database.StoreErrors
+	if !errors.As(res.err, &storeErrs) || !errors.Is(storeErrs["streaming"], errMidStream) {
+		t.Errorf("expected the store's error to be reported, got %v", res.err)
+	}
+}
@@ -2,26 +2,36 @@ package database_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
 	"slices"
 	"testing"
+	"time"
 
 	"git.tcp.direct/kayos/common/entropy"
 
 	"github.com/tcp-direct/database"
 	"github.com/tcp-direct/database/backup"
+	_ "github.com/tcp-direct/database/bbolt"   // register bbolt
 	_ "github.com/tcp-direct/database/bitcask" // register bitcask
+	_ "github.com/tcp-direct/database/fs"      // register fs
 	"github.com/tcp-direct/database/kv"
+	_ "github.com/tcp-direct/database/leveldb" // register leveldb
+	_ "github.com/tcp-direct/database/memory"  // register memory
 	"github.com/tcp-direct/database/models"
 	_ "github.com/tcp-direct/database/pogreb" // register pogreb
+	"github.com/tcp-direct/database/query"
 	"github.com/tcp-direct/database/registry"
+	_ "github.com/tcp-direct/database/sqlite" // register sqlite
 )
 
 func TestAllKeepers(t *testing.T) {
 	keepers := registry.AllKeepers()
-	if len(keepers) != 2 {
-		t.Errorf("expected 2 keepers, got %d", len(keepers))
+	if len(keepers) != 7 {
+		t.Errorf("expected 7 keepers, got %d", len(keepers))
 	}
 	if !slices.Contains(keepers, "bitcask") {
 		t.Error("expected 'bitcask' keeper")
@@ -29,6 +39,21 @@ func TestAllKeepers(t *testing.T) {
 	if !slices.Contains(keepers, "pogreb") {
 		t.Error("expected 'pogreb' keeper")
 	}
+	if !slices.Contains(keepers, "bbolt") {
+		t.Error("expected 'bbolt' keeper")
+	}
+	if !slices.Contains(keepers, "memory") {
+		t.Error("expected 'memory' keeper")
+	}
+	if !slices.Contains(keepers, "sqlite") {
+		t.Error("expected 'sqlite' keeper")
+	}
+	if !slices.Contains(keepers, "leveldb") {
+		t.Error("expected 'leveldb' keeper")
+	}
+	if !slices.Contains(keepers, "fs") {
+		t.Error("expected 'fs' keeper")
+	}
 	t.Logf("keepers: %v", keepers)
 }
 
@@ -187,52 +212,414 @@ func TestImplementationsBackup(t *testing.T) {
 			if err = instance.SyncAll(); err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			var bu models.Backup
+			for _, format := range []backup.Format{backup.FormatTarGz, backup.FormatTarZst, backup.FormatTarXz, backup.FormatTar, backup.FormatZip} {
+				t.Run(string(format), func(t *testing.T) {
+					var bu models.Backup
+					var err error
+
+					newBackup := filepath.Join(t.TempDir(), "backup"+format.Ext())
+
+					t.Logf("creating backup: %v", newBackup)
+
+					if bu, err = instance.BackupAll(newBackup); err != nil {
+						t.Fatalf("expected no error, got %v", err)
+					}
+					if bu == nil {
+						t.Fatalf("expected backup, got nil")
+					}
+
+					t.Logf("backup creation result: (%T) %v", bu, bu)
 
-			newBackup := filepath.Join(t.TempDir(), "backup.tar.gz")
+					t.Logf("verifying backup: %v", bu.Path())
 
-			t.Logf("creating backup: %v", newBackup)
+					if vErr := backup.VerifyBackup(bu.(backup.BackupMetadata)); vErr != nil {
+						t.Fatalf("expected no error, got %v", vErr)
+					}
 
-			if bu, err = instance.BackupAll(newBackup); err != nil {
+					t.Logf("restoring backup: %v", bu.Path())
+					if err = instance.RestoreAll(bu.Path()); err != nil {
+						t.Fatalf("expected no error, got %v", err)
+					}
+					t.Logf("backup restored: %v", bu.Path())
+					t.Run("verify_restored_data", func(t *testing.T) {
+						for storeName, kvs := range garbo {
+							t.Run("verify_"+storeName, func(t *testing.T) {
+								for _, kvTuple := range kvs {
+									// t.Logf("checking key: %s", kvTuple.Key.String())
+									var ret []byte
+									var getErr error
+									if ret, getErr = instance.With(storeName).Get(kvTuple.Key.Bytes()); getErr != nil {
+										t.Fatalf("expected no error, got %v", getErr)
+									}
+									if !bytes.Equal(kvTuple.Value.Bytes(), ret) {
+										t.Errorf("expected %q, got %q", kvTuple.Value.String(), ret)
+									}
+								}
+							})
+						}
+					})
+				})
+			}
+			if err = instance.SyncAndCloseAll(); err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			if bu == nil {
-				t.Fatalf("expected backup, got nil")
+		})
+	}
+}
+
+func TestImplementationsIncrementalBackup(t *testing.T) {
+	for _, name := range registry.AllKeepers() {
+		t.Run(name+"_incremental", func(t *testing.T) {
+			instance, err := registry.GetKeeper(name)(filepath.Join(t.TempDir(), name))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			t.Cleanup(func() {
+				_ = instance.SyncAndCloseAll()
+			})
+			keeper, ok := instance.(backup.IncrementalKeeper)
+			if !ok {
+				t.Fatalf("expected %s keeper to support incremental backups", name)
+			}
+			garbo := insertGarbo(t, instance)
+			if err = instance.SyncAll(); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			full, err := instance.BackupAll(filepath.Join(t.TempDir(), "full.tar.gz"))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
 			}
 
-			t.Logf("backup creation result: (%T) %v", bu, bu)
+			for storeName, kvs := range insertGarbo(t, instance) {
+				garbo[storeName] = kvs
+			}
+			if err = instance.SyncAll(); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			bu, err := backup.Incremental(context.Background(), keeper, filepath.Join(t.TempDir(), "incremental.zip"),
+				full.(backup.BackupMetadata).Checksum.Value)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			inc := bu.(backup.BackupMetadata)
+			if inc.Base != full.(backup.BackupMetadata).Checksum.Value {
+				t.Errorf("expected the incremental backup to record its base, got %q", inc.Base)
+			}
+			t.Logf("full backup: %d bytes, incremental backup: %d bytes", full.(backup.BackupMetadata).Size, inc.Size)
+			if err = backup.VerifyBackup(inc); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if _, err = backup.Incremental(context.Background(), keeper, filepath.Join(t.TempDir(), "nope.zip"), "nope"); !errors.Is(err, backup.ErrBaseNotFound) {
+				t.Errorf("expected ErrBaseNotFound, got %v", err)
+			}
 
-			t.Logf("verifying backup: %v", bu.Path())
+			if err = instance.RestoreAll(inc.Path()); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for storeName, kvs := range garbo {
+				store := instance.With(storeName)
+				if store == nil {
+					t.Fatalf("expected store %s to be restored", storeName)
+				}
+				for _, kvTuple := range kvs {
+					ret, getErr := store.Get(kvTuple.Key.Bytes())
+					if getErr != nil {
+						t.Fatalf("%s: expected no error, got %v", storeName, getErr)
+					}
+					if !bytes.Equal(kvTuple.Value.Bytes(), ret) {
+						t.Errorf("expected %q, got %q", kvTuple.Value.String(), ret)
+					}
+				}
+			}
+		})
+	}
+}
 
-			if vErr := backup.VerifyBackup(bu.(backup.BackupMetadata)); vErr != nil {
-				t.Fatalf("expected no error, got %v", vErr)
+func TestImplementationsEncryptedBackup(t *testing.T) {
+	keys := backup.WithKeys(backup.StaticKeys{Current: "test", Keys: map[string][]byte{"test": bytes.Repeat([]byte{'k'}, backup.KeySize)}})
+	for _, name := range registry.AllKeepers() {
+		t.Run(name+"_encrypted", func(t *testing.T) {
+			instance, err := registry.GetKeeper(name)(filepath.Join(t.TempDir(), name))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			garbo := insertGarbo(t, instance)
+			if err = instance.SyncAll(); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			bu, err := instance.BackupAll(filepath.Join(t.TempDir(), "encrypted.tar.gz"), keys)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
 			}
+			if keyID := bu.(backup.BackupMetadata).KeyID; keyID != "test" {
+				t.Errorf("expected backup to be encrypted with key test, got %q", keyID)
+			}
+			t.Cleanup(func() {
+				_ = instance.SyncAndCloseAll()
+			})
+			if err = instance.RestoreAll(bu.Path(), keys); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			for storeName, kvs := range garbo {
+				for _, kvTuple := range kvs {
+					ret, getErr := instance.With(storeName).Get(kvTuple.Key.Bytes())
+					if getErr != nil {
+						t.Fatalf("%s: expected no error, got %v", storeName, getErr)
+					}
+					if !bytes.Equal(kvTuple.Value.Bytes(), ret) {
+						t.Errorf("expected %q, got %q", kvTuple.Value.String(), ret)
+					}
+				}
+			}
+		})
+	}
+}
 
-			t.Logf("restoring backup: %v", bu.Path())
-			if err = instance.RestoreAll(bu.Path()); err != nil {
+func TestImplementationsRange(t *testing.T) {
+	keys := []string{"", "a", "ab", "abc", "b", "ba", "c", "\xff"}
+	for _, name := range registry.AllKeepers() {
+		t.Run(name+"_range", func(t *testing.T) {
+			instance, err := registry.GetKeeper(name)(filepath.Join(t.TempDir(), name))
+			if err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
-			t.Logf("backup restored: %v", bu.Path())
-			t.Run("verify_restored_data", func(t *testing.T) {
-				for storeName, kvs := range garbo {
-					t.Run("verify_"+storeName, func(t *testing.T) {
-						for _, kvTuple := range kvs {
-							// t.Logf("checking key: %s", kvTuple.Key.String())
-							var ret []byte
-							var getErr error
-							if ret, getErr = instance.With(storeName).Get(kvTuple.Key.Bytes()); getErr != nil {
-								t.Fatalf("expected no error, got %v", getErr)
-							}
-							if !bytes.Equal(kvTuple.Value.Bytes(), ret) {
-								t.Errorf("expected %q, got %q", kvTuple.Value.String(), ret)
-							}
-						}
-					})
+			t.Cleanup(func() {
+				_ = instance.SyncAndCloseAll()
+			})
+			store, ok := instance.WithNew("range").(database.Iterable)
+			if !ok {
+				t.Fatalf("expected %s store to implement Iterable", name)
+			}
+			for _, key := range keys {
+				if key == "" {
+					// not every backend accepts empty keys.
+					continue
+				}
+				if err = store.Put([]byte(key), []byte("v"+key)); err != nil {
+					t.Fatalf("expected no error, got %v", err)
 				}
+			}
+
+			collect := func(start, end []byte, opts database.RangeOptions) []string {
+				t.Helper()
+				it := store.Range(start, end, opts)
+				var got []string
+				for k, v := range database.Seq(it) {
+					if string(v) != "v"+string(k) {
+						t.Errorf("expected value %q for key %q, got %q", "v"+string(k), k, v)
+					}
+					got = append(got, string(k))
+				}
+				if err := it.Err(); err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return got
+			}
+			for _, tc := range []struct {
+				start, end []byte
+				opts       database.RangeOptions
+				want       []string
+			}{
+				{nil, nil, database.RangeOptions{}, keys[1:]},
+				{[]byte("ab"), []byte("ba"), database.RangeOptions{}, []string{"ab", "abc", "b"}},
+				{[]byte("ab"), []byte("ba"), database.RangeOptions{Reverse: true}, []string{"b", "abc", "ab"}},
+				{nil, nil, database.RangeOptions{Reverse: true, Limit: 3}, []string{"\xff", "c", "ba"}},
+				{[]byte("b"), nil, database.RangeOptions{Limit: 2}, []string{"b", "ba"}},
+				{[]byte("d"), []byte("e"), database.RangeOptions{}, nil},
+			} {
+				if got := collect(tc.start, tc.end, tc.opts); !slices.Equal(got, tc.want) {
+					t.Errorf("Range(%q, %q, %+v): expected %q, got %q", tc.start, tc.end, tc.opts, tc.want, got)
+				}
+			}
+
+			if err = instance.Close("range"); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if it := store.Range(nil, nil, database.RangeOptions{}); it.Next() || it.Err() == nil {
+				t.Error("expected error from closed store")
+			}
+		})
+	}
+}
+
+func TestImplementationsContext(t *testing.T) {
+	for _, name := range registry.AllKeepers() {
+		t.Run(name+"_context", func(t *testing.T) {
+			instance, err := registry.GetKeeper(name)(filepath.Join(t.TempDir(), name))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			t.Cleanup(func() {
+				_ = instance.SyncAndCloseAll()
 			})
-			if err = instance.SyncAndCloseAll(); err != nil {
+			filer := instance.WithNew("ctx")
+			store, ok := filer.(database.ContextFiler)
+			if !ok {
+				t.Fatalf("expected %s store to implement ContextFiler", name)
+			}
+			searcher, ok := filer.(database.ContextSearcher)
+			if !ok {
+				t.Fatalf("expected %s store to implement ContextSearcher", name)
+			}
+			for i := 0; i < 100; i++ {
+				if err = store.Put([]byte(fmt.Sprintf("key%03d", i)), []byte("value")); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if v, err := store.GetContext(ctx, []byte("key000")); err != nil || string(v) != "value" {
+				t.Errorf("expected value, got %q (%v)", v, err)
+			}
+			cancel()
+			if _, err = store.GetContext(ctx, []byte("key000")); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			if err = store.PutContext(ctx, []byte("key000"), []byte("changed")); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+
+			scans := map[string]func(ctx context.Context) (<-chan kv.KeyValue, chan error){
+				"prefix": func(ctx context.Context) (<-chan kv.KeyValue, chan error) {
+					return searcher.PrefixScanContext(ctx, "key")
+				},
+				"search": func(ctx context.Context) (<-chan kv.KeyValue, chan error) {
+					return searcher.SearchContext(ctx, "val")
+				},
+			}
+			for scanName, scan := range scans {
+				// stop reading after the first result, the scan must still wind down once cancelled.
+				ctx, cancel := context.WithCancel(context.Background())
+				resChan, errChan := scan(ctx)
+				<-resChan
+				cancel()
+				done := make(chan struct{})
+				go func() {
+					for range resChan {
+					}
+					close(done)
+				}()
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("%s: expected result channel to be closed after cancellation", scanName)
+				}
+				if err := <-errChan; !errors.Is(err, context.Canceled) {
+					t.Errorf("%s: expected context.Canceled, got %v", scanName, err)
+				}
+			}
+
+			ctx, cancel = context.WithCancel(context.Background())
+			cancel()
+			keeper, ok := instance.(database.ContextKeeper)
+			if !ok {
+				t.Fatalf("expected %s keeper to implement ContextKeeper", name)
+			}
+			archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+			if _, err = keeper.BackupAllContext(ctx, archive); !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got %v", err)
+			}
+			if _, err = os.Stat(archive); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected no archive after cancelled backup, got %v", err)
+			}
+		})
+	}
+}
+
+func TestImplementationsQuery(t *testing.T) {
+	for _, name := range registry.AllKeepers() {
+		t.Run(name+"_query", func(t *testing.T) {
+			instance, err := registry.GetKeeper(name)(filepath.Join(t.TempDir(), name))
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			t.Cleanup(func() {
+				_ = instance.SyncAndCloseAll()
+			})
+			filer := instance.WithNew("query")
+			store, ok := filer.(database.Searcher)
+			if !ok {
+				t.Fatalf("expected %s store to implement Searcher", name)
+			}
+			for i := 0; i < 20; i++ {
+				value := fmt.Sprintf(`{"n":%d,"even":%t}`, i, i%2 == 0)
+				if err = filer.Put([]byte(fmt.Sprintf("doc%02d", i)), []byte(value)); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+			if err = filer.Put([]byte("raw"), []byte("not json")); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			resChan, errChan := query.Run(store, "$.even && $.n >= 10", "$.n")
+			var got []string
+			for keyVal := range resChan {
+				got = append(got, keyVal.Key.String()+"="+keyVal.Value.String())
+			}
+			if err = <-errChan; err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			slices.Sort(got)
+			want := []string{`doc10={"$.n":10}`, `doc12={"$.n":12}`, `doc14={"$.n":14}`, `doc16={"$.n":16}`, `doc18={"$.n":18}`}
+			if !slices.Equal(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+
+			groups, err := query.MustCompile("").GroupBy(store, "$.even", "$.n")
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if groups["true"] != (query.Aggregate{Count: 10, Sum: 90}) || groups["false"] != (query.Aggregate{Count: 10, Sum: 100}) {
+				t.Errorf("expected even and odd groups, got %v", groups)
+			}
+		})
+	}
+}
+
+func TestImplementationsKeySearch(t *testing.T) {
+	for _, name := range registry.AllKeepers() {
+		t.Run(name+"_keysearch", func(t *testing.T) {
+			instance, err := registry.GetKeeper(name)(filepath.Join(t.TempDir(), name))
+			if err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
+			t.Cleanup(func() {
+				_ = instance.SyncAndCloseAll()
+			})
+			filer := instance.WithNew("hosts")
+			store, ok := filer.(database.KeySearcher)
+			if !ok {
+				t.Fatalf("expected %s store to implement KeySearcher", name)
+			}
+			for _, host := range []string{"www.example.com", "mail.example.com", "example.org", "mailer.example.org"} {
+				if err = filer.Put([]byte(host), []byte("up")); err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+			}
+
+			collect := func(resChan <-chan kv.KeyValue, errChan chan error) []string {
+				var got []string
+				for keyVal := range resChan {
+					got = append(got, keyVal.Key.String())
+				}
+				if err := <-errChan; err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				slices.Sort(got)
+				return got
+			}
+			if got, want := collect(store.SuffixScan(".example.com")), []string{"mail.example.com", "www.example.com"}; !slices.Equal(got, want) {
+				t.Errorf("suffix: expected %v, got %v", want, got)
+			}
+			if got, want := collect(store.KeyContains("mail")), []string{"mail.example.com", "mailer.example.org"}; !slices.Equal(got, want) {
+				t.Errorf("contains: expected %v, got %v", want, got)
+			}
+			if got := collect(store.SuffixScan("")); len(got) != 4 {
+				t.Errorf("expected an empty suffix to match every key, got %v", got)
+			}
+			if got := collect(store.KeyContains("nothing")); len(got) != 0 {
+				t.Errorf("expected no matches, got %v", got)
+			}
 		})
 	}
 }
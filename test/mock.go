@@ -13,6 +13,7 @@ import (
 	"github.com/tcp-direct/database/metadata"
 	"github.com/tcp-direct/database/registry"
 
+	"github.com/tcp-direct/database/backup"
 	"github.com/tcp-direct/database/models"
 )
 
@@ -262,11 +263,11 @@ func (m *MockKeeper) AllStores() map[string]database.Filer {
 	return stores
 }
 
-func (m *MockKeeper) BackupAll(archivePath string) (models.Backup, error) {
+func (m *MockKeeper) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
 	panic("not implemented")
 }
 
-func (m *MockKeeper) RestoreAll(archivePath string) error {
+func (m *MockKeeper) RestoreAll(archivePath string, opts ...backup.Option) error {
 	panic("not implemented")
 }
 
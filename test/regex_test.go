@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tcp-direct/database"
+)
+
+func TestCompileGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"user/*", []string{"user/", "user/1", "user/1/2"}, []string{"users/1", "xuser/1"}},
+		{"user/?", []string{"user/1", "user/é"}, []string{"user/", "user/10"}},
+		{"[a-c]x", []string{"ax", "cx"}, []string{"dx", "-x"}},
+		{"[!a-c]x", []string{"dx", "-x"}, []string{"ax", "x"}},
+		{"a.b+c", []string{"a.b+c"}, []string{"axbbc"}},
+		{`\*lit\?`, []string{"*lit?"}, []string{"xlitx"}},
+		{"[.]x", []string{".x"}, []string{"ax"}},
+		{"*\n*", []string{"a\nb"}, []string{"ab"}},
+	} {
+		re, err := database.CompileGlob(tc.pattern)
+		if err != nil {
+			t.Fatalf("%q: expected no error, got %v", tc.pattern, err)
+		}
+		for _, m := range tc.matches {
+			if !re.MatchString(m) {
+				t.Errorf("%q: expected %q to match", tc.pattern, m)
+			}
+		}
+		for _, m := range tc.misses {
+			if re.MatchString(m) {
+				t.Errorf("%q: expected %q not to match", tc.pattern, m)
+			}
+		}
+	}
+	for _, bad := range []string{"[", "[]", "[!]", `trailing\`} {
+		if _, err := database.CompileGlob(bad); !errors.Is(err, database.ErrBadGlob) {
+			t.Errorf("%q: expected ErrBadGlob, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package database
+
+import "github.com/tcp-direct/database/kv"
+
+// TextSearcher is a Searcher with a full-text index over its values.
+type TextSearcher interface {
+	Searcher
+	// SearchText should stream the key/value pairs matching the full-text query, best match first.
+	SearchText(query string) (<-chan kv.KeyValue, chan error)
+}
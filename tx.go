@@ -0,0 +1,23 @@
+package database
+
+// Tx is a transaction spanning one or more stores of a [Transactor].
+type Tx interface {
+	// Get should return the value of the key in the given store, including any writes made earlier in the transaction.
+	Get(store string, key []byte) ([]byte, error)
+	// Has should return true if the key exists in the given store, including any writes made earlier in the transaction.
+	Has(store string, key []byte) bool
+	// Put should queue the value to be inserted at the key in the given store when the transaction commits.
+	Put(store string, key []byte, value []byte) error
+	// Delete should queue the key to be deleted from the given store when the transaction commits.
+	Delete(store string, key []byte) error
+}
+
+// Transactor is a Keeper that can run transactions across its stores.
+type Transactor interface {
+	Keeper
+	// Update should run fn in a read-write transaction. Its writes should only take effect if fn returns nil,
+	// in which case either all of them or none of them should be applied.
+	Update(fn func(tx Tx) error) error
+	// View should run fn in a read-only transaction that sees a consistent snapshot of all stores.
+	View(fn func(tx Tx) error) error
+}
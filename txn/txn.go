@@ -0,0 +1,270 @@
+// Package txn adds transactions spanning several stores to any [database.Keeper].
+//
+// Transactions are built on top of the Keeper's Filers rather than any native support in the backend:
+// read-write transactions are serialized with each other and run exclusively of read-only transactions,
+// which gives every transaction a consistent snapshot of all stores. Writes are buffered until the transaction
+// commits, and are then applied through an undo journal in the Keeper's directory so that either all of them
+// take effect or none of them do, even if the process dies part way through a commit.
+//
+// Isolation only holds between transactions, writes made directly to the Keeper's stores are not coordinated
+// with them.
+package txn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/backup"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/models"
+)
+
+// File is the name of the journal file kept in the Keeper's directory while a transaction is being committed.
+const File = "txn.journal"
+
+var (
+	// ErrNoStore is returned when a transaction references a store that isn't open.
+	ErrNoStore = errors.New("store not found")
+	// ErrReadOnly is returned when writing inside of a read-only transaction.
+	ErrReadOnly = errors.New("transaction is read-only")
+	// ErrTxDone is returned when a transaction is used after its function has returned.
+	ErrTxDone = errors.New("transaction has already finished")
+)
+
+var _ database.Transactor = (*Keeper)(nil)
+
+// Keeper wraps a [database.Keeper], adding [Keeper.Update] and [Keeper.View]. It implements [database.Transactor].
+type Keeper struct {
+	database.Keeper
+	journalPath string
+	mu          sync.RWMutex
+}
+
+// Wrap adds transactions to the given Keeper.
+// Any transaction that was interrupted while being committed is rolled back first,
+// so the stores it touched must already be open or discoverable.
+func Wrap(keeper database.Keeper) (*Keeper, error) {
+	k := &Keeper{Keeper: keeper}
+	// in-memory stores don't survive a crash, so there is nothing for a journal on disk to recover.
+	if !database.IsInMemory(keeper) {
+		k.journalPath = filepath.Join(keeper.Path(), File)
+	}
+	if _, err := k.newJournal().Recover(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// InMemory reports whether the underlying Keeper's stores only live in memory, see [database.IsInMemory].
+func (k *Keeper) InMemory() bool {
+	return database.IsInMemory(k.Keeper)
+}
+
+func (k *Keeper) newJournal() *journal.Journal {
+	return journal.New(k.journalPath, &target{keeper: k.Keeper, filers: make(map[string]database.Filer)})
+}
+
+// Update runs fn in a read-write transaction. Writes are only visible to reads within the same transaction
+// until fn returns, at which point they are committed if fn returned nil and discarded otherwise.
+// Update blocks while any other transaction is running.
+func (k *Keeper) Update(fn func(tx database.Tx) error) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	tx := newTx(k.Keeper, true)
+	err := fn(tx)
+	tx.done = true
+	if err != nil {
+		return err
+	}
+	if err = k.newJournal().Apply(tx.ops); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// View runs fn in a read-only transaction. Any number of read-only transactions may run at once,
+// but none of them will run alongside a read-write transaction.
+func (k *Keeper) View(fn func(tx database.Tx) error) error {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	tx := newTx(k.Keeper, false)
+	err := fn(tx)
+	tx.done = true
+	return err
+}
+
+// BackupAll backs up all stores of the underlying Keeper while no read-write transaction is running,
+// so that the backup never contains part of a transaction.
+func (k *Keeper) BackupAll(archivePath string, opts ...backup.Option) (models.Backup, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.Keeper.BackupAll(archivePath, opts...)
+}
+
+// RestoreAll restores all stores of the underlying Keeper while no transaction is running.
+func (k *Keeper) RestoreAll(archivePath string, opts ...backup.Option) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.Keeper.RestoreAll(archivePath, opts...)
+}
+
+// tx implements [database.Tx]. Buffered writes are kept as journal ops with the store name encoded in to the key.
+type tx struct {
+	keeper   database.Keeper
+	writable bool
+	done     bool
+	ops      []journal.Op
+	pending  map[string]int
+}
+
+func newTx(keeper database.Keeper, writable bool) *tx {
+	return &tx{keeper: keeper, writable: writable, pending: make(map[string]int)}
+}
+
+func (t *tx) filer(store string) (database.Filer, error) {
+	if t.done {
+		return nil, ErrTxDone
+	}
+	f := t.keeper.With(store)
+	if f == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	return f, nil
+}
+
+// Get returns the value of the key in the given store, as last written by the transaction if it was.
+func (t *tx) Get(store string, key []byte) ([]byte, error) {
+	f, err := t.filer(store)
+	if err != nil {
+		return nil, err
+	}
+	if i, ok := t.pending[string(encodeKey(store, key))]; ok {
+		if t.ops[i].Delete {
+			return nil, kv.RegularizeKVError(key, nil, nil)
+		}
+		return append([]byte(nil), t.ops[i].Value...), nil
+	}
+	return f.Get(key)
+}
+
+// Has returns true if the key exists in the given store, taking the transaction's writes in to account.
+func (t *tx) Has(store string, key []byte) bool {
+	f, err := t.filer(store)
+	if err != nil {
+		return false
+	}
+	if i, ok := t.pending[string(encodeKey(store, key))]; ok {
+		return !t.ops[i].Delete
+	}
+	return f.Has(key)
+}
+
+func (t *tx) write(store string, op journal.Op) error {
+	if !t.writable {
+		return ErrReadOnly
+	}
+	if _, err := t.filer(store); err != nil {
+		return err
+	}
+	op.Key = encodeKey(store, op.Key)
+	// only the last write to a key matters, so it replaces any earlier one.
+	if i, ok := t.pending[string(op.Key)]; ok {
+		t.ops[i] = op
+		return nil
+	}
+	t.pending[string(op.Key)] = len(t.ops)
+	t.ops = append(t.ops, op)
+	return nil
+}
+
+// Put queues the value to be inserted at the key in the given store.
+func (t *tx) Put(store string, key []byte, value []byte) error {
+	return t.write(store, journal.Op{Key: key, Value: append([]byte(nil), value...)})
+}
+
+// Delete queues the key to be deleted from the given store.
+func (t *tx) Delete(store string, key []byte) error {
+	return t.write(store, journal.Op{Key: key, Delete: true})
+}
+
+// encodeKey prefixes key with the length of the store name and the name itself,
+// which lets a single journal cover writes to several stores.
+func encodeKey(store string, key []byte) []byte {
+	buf := binary.AppendUvarint(nil, uint64(len(store)))
+	buf = append(buf, store...)
+	return append(buf, key...)
+}
+
+func decodeKey(encoded []byte) (string, []byte, error) {
+	l, n := binary.Uvarint(encoded)
+	if n <= 0 || uint64(len(encoded)-n) < l {
+		return "", nil, fmt.Errorf("malformed transaction key: %x", encoded)
+	}
+	return string(encoded[n : n+int(l)]), encoded[n+int(l):], nil
+}
+
+// target routes the journal's reads and writes to the stores named in their keys.
+type target struct {
+	keeper database.Keeper
+	filers map[string]database.Filer
+}
+
+func (t *target) route(encoded []byte) (database.Filer, []byte, error) {
+	store, key, err := decodeKey(encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f, ok := t.filers[store]; ok {
+		return f, key, nil
+	}
+	f := t.keeper.With(store)
+	if f == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNoStore, store)
+	}
+	t.filers[store] = f
+	return f, key, nil
+}
+
+func (t *target) Get(encoded []byte) ([]byte, error) {
+	f, key, err := t.route(encoded)
+	if err != nil {
+		return nil, err
+	}
+	v, err := f.Get(key)
+	if err != nil && !f.Has(key) {
+		return nil, kv.RegularizeKVError(key, nil, nil)
+	}
+	return v, err
+}
+
+func (t *target) Put(encoded []byte, value []byte) error {
+	f, key, err := t.route(encoded)
+	if err != nil {
+		return err
+	}
+	return f.Put(key, value)
+}
+
+func (t *target) Delete(encoded []byte) error {
+	f, key, err := t.route(encoded)
+	if err != nil {
+		return err
+	}
+	return f.Delete(key)
+}
+
+// Sync syncs every store that the journal has touched.
+func (t *target) Sync() error {
+	var errs []error
+	for name, f := range t.filers {
+		if err := f.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,253 @@
+package txn
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/tcp-direct/database"
+	"github.com/tcp-direct/database/bitcask"
+	"github.com/tcp-direct/database/journal"
+	"github.com/tcp-direct/database/kv"
+	"github.com/tcp-direct/database/memory"
+)
+
+var errFail = errors.New("fail")
+
+// failKeeper hands out stores that refuse to write the key "fail".
+type failKeeper struct {
+	database.Keeper
+}
+
+func (f failKeeper) With(name string) database.Filer {
+	if st := f.Keeper.With(name); st != nil {
+		return failFiler{st}
+	}
+	return nil
+}
+
+type failFiler struct {
+	database.Filer
+}
+
+func (f failFiler) Put(key []byte, value []byte) error {
+	if string(key) == "fail" {
+		return errFail
+	}
+	return f.Filer.Put(key, value)
+}
+
+// crashTarget panics after a number of writes to simulate the process dying part way through a commit.
+type crashTarget struct {
+	journal.Target
+	writes int
+}
+
+func (c *crashTarget) Put(key []byte, value []byte) error {
+	if c.writes == 0 {
+		panic("crash")
+	}
+	c.writes--
+	return c.Target.Put(key, value)
+}
+
+func newMemoryKeeper(t *testing.T) (*memory.DB, *Keeper) {
+	t.Helper()
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("pending")
+	db.WithNew("done")
+	k, err := Wrap(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, k
+}
+
+func TestKeeper_Update(t *testing.T) {
+	db, k := newMemoryKeeper(t)
+	if err := db.With("pending").Put([]byte("job"), []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("move", func(t *testing.T) {
+		err := k.Update(func(tx database.Tx) error {
+			v, err := tx.Get("pending", []byte("job"))
+			if err != nil {
+				return err
+			}
+			if err = tx.Delete("pending", []byte("job")); err != nil {
+				return err
+			}
+			if err = tx.Put("done", []byte("job"), v); err != nil {
+				return err
+			}
+			if tx.Has("pending", []byte("job")) || !tx.Has("done", []byte("job")) {
+				t.Error("expected transaction to see its own writes")
+			}
+			if db.With("done").Has([]byte("job")) {
+				t.Error("expected writes to be invisible outside of the transaction before commit")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if db.With("pending").Has([]byte("job")) {
+			t.Error("expected key to be removed from source store")
+		}
+		if v, _ := db.With("done").Get([]byte("job")); string(v) != "data" {
+			t.Errorf("expected key to be moved to destination store, got %s", v)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		err := k.Update(func(tx database.Tx) error {
+			_ = tx.Put("pending", []byte("other"), []byte("value"))
+			return errFail
+		})
+		if !errors.Is(err, errFail) {
+			t.Fatalf("expected errFail, got %v", err)
+		}
+		if db.With("pending").Has([]byte("other")) {
+			t.Error("expected writes of aborted transaction to be discarded")
+		}
+	})
+
+	t.Run("no store", func(t *testing.T) {
+		err := k.Update(func(tx database.Tx) error {
+			return tx.Put("nope", []byte("key"), []byte("value"))
+		})
+		if !errors.Is(err, ErrNoStore) {
+			t.Errorf("expected ErrNoStore, got %v", err)
+		}
+	})
+
+	t.Run("done", func(t *testing.T) {
+		var leaked database.Tx
+		_ = k.Update(func(tx database.Tx) error {
+			leaked = tx
+			return nil
+		})
+		if err := leaked.Put("done", []byte("late"), []byte("value")); !errors.Is(err, ErrTxDone) {
+			t.Errorf("expected ErrTxDone, got %v", err)
+		}
+	})
+}
+
+func TestKeeper_UpdateRollback(t *testing.T) {
+	db := memory.OpenDB(t.TempDir())
+	db.WithNew("a")
+	db.WithNew("b")
+	if err := db.With("a").Put([]byte("key"), []byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	k, err := Wrap(failKeeper{db})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = k.Update(func(tx database.Tx) error {
+		_ = tx.Put("a", []byte("key"), []byte("new"))
+		_ = tx.Put("b", []byte("new"), []byte("value"))
+		return tx.Put("b", []byte("fail"), []byte("value"))
+	})
+	if !errors.Is(err, errFail) {
+		t.Fatalf("expected errFail, got %v", err)
+	}
+	if v, _ := db.With("a").Get([]byte("key")); string(v) != "old" {
+		t.Errorf("expected overwritten value to be rolled back, got %s", v)
+	}
+	if db.With("b").Has([]byte("new")) {
+		t.Error("expected inserted key to be rolled back")
+	}
+}
+
+func TestKeeper_View(t *testing.T) {
+	db, k := newMemoryKeeper(t)
+	if err := db.With("done").Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	err := k.View(func(tx database.Tx) error {
+		if v, err := tx.Get("done", []byte("key")); err != nil || string(v) != "value" {
+			t.Errorf("expected value, got %s (%v)", v, err)
+		}
+		if _, err := tx.Get("done", []byte("missing")); !kv.IsNonExistentKey(err) {
+			t.Errorf("expected non-existent key error, got %v", err)
+		}
+		return tx.Put("done", []byte("key"), []byte("changed"))
+	})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestWrap_Recover(t *testing.T) {
+	path := t.TempDir()
+	db := bitcask.OpenDB(path)
+	for _, name := range []string{"pending", "done"} {
+		if err := db.Init(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.With("pending").Put([]byte("job"), []byte("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash after the delete of a move has been applied, but before the put.
+	crasher := journal.New(filepath.Join(path, File), &crashTarget{
+		Target: &target{keeper: db, filers: make(map[string]database.Filer)},
+	})
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+		_ = crasher.Apply([]journal.Op{
+			{Key: encodeKey("pending", []byte("job")), Delete: true},
+			{Key: encodeKey("done", []byte("job")), Value: []byte("data")},
+		})
+	}()
+	if db.With("pending").Has([]byte("job")) {
+		t.Fatal("expected partially applied transaction before reopening")
+	}
+	if err := db.CloseAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = bitcask.OpenDB(path)
+	if _, err := db.Discover(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = db.CloseAll()
+	})
+	if _, err := Wrap(db); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if v, _ := db.With("pending").Get([]byte("job")); string(v) != "data" {
+		t.Errorf("expected interrupted transaction to be rolled back, got %s", v)
+	}
+	if db.With("done").Has([]byte("job")) {
+		t.Error("expected key from interrupted transaction to not exist")
+	}
+}
+
+func TestWrap_InMemory(t *testing.T) {
+	_, k := newMemoryKeeper(t)
+	if k.journalPath != "" || !k.InMemory() {
+		t.Errorf("expected no journal for in-memory stores, got %q", k.journalPath)
+	}
+	// wrapping a wrapped in-memory keeper must still see that its stores only live in memory.
+	wrapped, err := Wrap(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapped.journalPath != "" {
+		t.Errorf("expected no journal for wrapped in-memory stores, got %q", wrapped.journalPath)
+	}
+	onDisk, err := Wrap(bitcask.OpenDB(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if onDisk.journalPath == "" || onDisk.InMemory() {
+		t.Error("expected a journal for stores on disk")
+	}
+}